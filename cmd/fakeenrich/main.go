@@ -0,0 +1,62 @@
+package main
+
+import (
+	"Effective_Mobile/internal/fakeenrich"
+	"Effective_Mobile/internal/logger"
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// fakeenrich — локальная замена agify, genderize и nationalize для разработки
+// и тестов. Адреса для сервера: ENRICH_AGIFY_URL=http://<addr>/agify/ и т. д.
+func main() {
+	var (
+		addr     = flag.String("addr", "localhost:7100", "listen address")
+		fixtures = flag.String("fixtures", "data/names.csv", "CSV or JSON dataset with name statistics")
+		faults   fakeenrich.Faults
+	)
+	flag.DurationVar(&faults.Latency, "latency", 0, "delay before every response")
+	flag.IntVar(&faults.RateLimitEvery, "rate-limit-every", 0, "answer 429 to every Nth request")
+	flag.IntVar(&faults.ErrorEvery, "error-every", 0, "answer 500 to every Nth request")
+	flag.IntVar(&faults.MalformedEvery, "malformed-every", 0, "answer malformed JSON to every Nth request")
+	flag.DurationVar(&faults.RateLimitReset, "rate-limit-reset", time.Second, "X-Rate-Limit-Reset sent with 429")
+	debug := flag.Bool("debug", false, "log every request")
+	flag.Parse()
+
+	logger.DebugEnabled = *debug
+
+	handler, err := fakeenrich.Load(*fixtures, faults)
+	if err != nil {
+		logger.Error("Failed to load fixtures: %v", err)
+		os.Exit(1)
+	}
+
+	server := &http.Server{Addr: *addr, Handler: handler}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		logger.Info("Fake enrichment server listening on %s (agify %s, genderize %s, nationalize %s)",
+			*addr, fakeenrich.AgifyPath, fakeenrich.GenderizePath, fakeenrich.NationalizePath)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Failed to start server: %v", err)
+			os.Exit(1)
+		}
+	}()
+
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Failed to gracefully shutdown server: %v", err)
+	}
+	logger.Info("Fake enrichment server stopped")
+}
@@ -3,10 +3,22 @@ package main
 import (
 	"Effective_Mobile/internal/config"
 	"Effective_Mobile/internal/httpserver"
+	"Effective_Mobile/internal/httpserver/handlers/dto"
 	"Effective_Mobile/internal/httpserver/routes"
 	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/service/enrichment"
+	"Effective_Mobile/internal/service/importer"
+	"Effective_Mobile/internal/service/refresher"
+	"Effective_Mobile/internal/service/rekey"
+	"Effective_Mobile/internal/service/worker"
+	"Effective_Mobile/internal/storage"
+	"Effective_Mobile/internal/storage/memory"
 	"Effective_Mobile/internal/storage/pg"
+	"Effective_Mobile/lib/normalize"
+	"Effective_Mobile/lib/validate"
 	"context"
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -21,18 +33,63 @@ import (
 func main() {
 	cfg := config.MustLoad()
 	logger.DebugEnabled = cfg.Debug
+	normalize.Transliterate = cfg.Transliterate
+
+	if len(os.Args) > 1 && os.Args[1] == "refresh" {
+		runRefresh(cfg, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		runImport(cfg, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "rekey" {
+		runRekey(cfg, os.Args[2:])
+		return
+	}
 
 	logger.Info("Starting application")
 	logger.Debug("Config loaded: %+v", cfg)
 
-	storage, err := pg.New(&cfg.DsnPG)
-	if err != nil {
-		logger.Error("Failed to initialize storage: %v", err)
+	repo, cache, enricher := mustSetup(cfg)
+
+	queue, ok := repo.(storage.JobQueue)
+	if !ok {
+		logger.Error("Storage %q does not support enrichment jobs", cfg.Storage)
 		os.Exit(1)
 	}
-	logger.Info("Storage initialized")
 
-	router := routes.New(storage)
+	transactor, ok := repo.(storage.Transactor)
+	if !ok {
+		logger.Error("Storage %q does not support transactions", cfg.Storage)
+		os.Exit(1)
+	}
+
+	refreshStore, ok := repo.(storage.RefreshStore)
+	if !ok {
+		logger.Error("Storage %q does not support re-enrichment", cfg.Storage)
+		os.Exit(1)
+	}
+
+	var jobWorker *worker.Worker
+	if cfg.Enrichment.Async {
+		jobWorker = worker.New(queue, repo, enricher, cfg.Worker)
+		jobWorker.Start(context.Background())
+	}
+
+	var scheduler *refresher.Refresher
+	if cfg.Refresh.Enabled {
+		scheduler = refresher.New(repo, refreshStore, enricher, cfg.Refresh)
+		scheduler.Start(context.Background())
+	}
+
+	// ошибки в тегах validate обнаруживаются при старте, а не на первом запросе
+	if err := validate.Register(dto.UserRequest{}); err != nil {
+		logger.Error("Invalid request validation rules: %v", err)
+		os.Exit(1)
+	}
+
+	router := routes.New(repo, transactor, queue, refreshStore, enricher, cfg.Enrichment.Async)
 	server := httpserver.New(cfg.HTTPServer, *router)
 	logger.Info("HTTP server initialized")
 
@@ -41,7 +98,7 @@ func main() {
 
 	go func() {
 		logger.Info("Starting HTTP server...")
-		if err = server.Start(); err != nil {
+		if err := server.Start(); err != nil {
 			logger.Error("Failed to start server: %v", err)
 			os.Exit(1)
 		}
@@ -56,13 +113,229 @@ func main() {
 	defer cancel()
 
 	logger.Info("Stopping HTTP server")
-	if err = server.Shutdown(ctx); err != nil {
+	if err := server.Shutdown(ctx); err != nil {
 		logger.Error("Failed to gracefully shutdown server: %v", err)
 	}
 
-	if err = storage.Close(); err != nil {
+	if jobWorker != nil {
+		logger.Info("Stopping enrichment workers")
+		jobWorker.Stop()
+	}
+
+	if scheduler != nil {
+		logger.Info("Stopping re-enrichment scheduler")
+		scheduler.Stop()
+	}
+
+	if cache != nil {
+		stats := cache.Stats()
+		logger.Info("Enrichment cache: hits=%d misses=%d size=%d", stats.Hits, stats.Misses, stats.Size)
+	}
+
+	if err := repo.Close(); err != nil {
 		logger.Error("Failed to close storage: %v", err)
 	}
 
 	logger.Info("Application stopped")
 }
+
+// runRefresh выполняет один проход повторного обогащения и завершает процесс.
+// Флаги переопределяют параметры REFRESH_*.
+func runRefresh(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("refresh", flag.ExitOnError)
+	fs.IntVar(&cfg.Refresh.Budget, "budget", cfg.Refresh.Budget, "maximum number of people to re-enrich")
+	fs.DurationVar(&cfg.Refresh.MaxAge, "max-age", cfg.Refresh.MaxAge, "re-enrich attributes older than this")
+	fs.DurationVar(&cfg.Refresh.RetryMissing, "retry-missing", cfg.Refresh.RetryMissing,
+		"retry missing and low-confidence attributes fetched earlier than this")
+	fs.DurationVar(&cfg.Refresh.BatchDelay, "batch-delay", cfg.Refresh.BatchDelay, "pause between provider batches")
+	fs.Parse(args)
+
+	logger.Info("Starting re-enrichment")
+
+	repo, _, enricher := mustSetup(cfg)
+
+	store, ok := repo.(storage.RefreshStore)
+	if !ok {
+		logger.Error("Storage %q does not support re-enrichment", cfg.Storage)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	summary, err := refresher.New(repo, store, enricher, cfg.Refresh).RunOnce(ctx)
+	if closeErr := repo.Close(); closeErr != nil {
+		logger.Error("Failed to close storage: %v", closeErr)
+	}
+	if err != nil {
+		logger.Error("Re-enrichment failed: %v", err)
+		os.Exit(1)
+	}
+
+	logger.Info("Re-enrichment done: checked=%d updated=%d changes=%d", summary.Checked, summary.Updated, summary.Changes)
+}
+
+// runImport загружает людей из файла JSON Lines (по умолчанию из stdin) и
+// обогащает их пакетными запросами.
+func runImport(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	file := fs.String("file", "", "JSON Lines file with people, stdin if empty")
+	fs.Parse(args)
+
+	in := os.Stdin
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			logger.Error("Failed to open import file: %v", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	logger.Info("Starting import")
+
+	repo, _, enricher := mustSetup(cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	summary, err := importer.New(repo, enricher).Run(ctx, in)
+	if closeErr := repo.Close(); closeErr != nil {
+		logger.Error("Failed to close storage: %v", closeErr)
+	}
+	if err != nil {
+		logger.Error("Import failed: %v", err)
+		os.Exit(1)
+	}
+
+	logger.Info("Import done: read=%d added=%d duplicates=%d invalid=%d",
+		summary.Read, summary.Added, summary.Duplicates, summary.Invalid)
+}
+
+// runRekey пересчитывает ключи имён по текущим правилам нормализации. Его
+// нужно запустить перед миграцией 013, которая создаёт уникальный индекс по
+// ключам; люди с одинаковыми ключами выводятся в лог, и процесс завершается
+// с ошибкой, пока их не переименуют или не удалят.
+func runRekey(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("rekey", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "report changes and conflicts without writing keys")
+	fs.Parse(args)
+
+	logger.Info("Starting name key recomputation")
+
+	repo, err := newStorage(cfg)
+	if err != nil {
+		logger.Error("Failed to initialize storage: %v", err)
+		os.Exit(1)
+	}
+
+	store, ok := repo.(storage.KeyStore)
+	if !ok {
+		logger.Error("Storage %q does not support key recomputation", cfg.Storage)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	summary, err := rekey.Run(ctx, store, *dryRun)
+	if closeErr := repo.Close(); closeErr != nil {
+		logger.Error("Failed to close storage: %v", closeErr)
+	}
+	if err != nil {
+		logger.Error("Key recomputation failed: %v", err)
+		os.Exit(1)
+	}
+
+	logger.Info("Key recomputation done: checked=%d updated=%d blocked=%d conflicts=%d",
+		summary.Checked, summary.Updated, summary.Blocked, len(summary.Conflicts))
+	for _, c := range summary.Conflicts {
+		logger.Error("Duplicate people %v for %q %q: rename or delete all but one", c.IDs, c.NameKey, c.SurnameKey)
+	}
+	if summary.Blocked > 0 {
+		logger.Error("%d keys were taken by old keys of other people: run rekey again", summary.Blocked)
+	}
+	if len(summary.Conflicts) > 0 || summary.Blocked > 0 {
+		os.Exit(1)
+	}
+}
+
+// mustSetup поднимает хранилище и сервис обогащения, общие для сервера и CLI.
+func mustSetup(cfg *config.Config) (storage.Repository, *enrichment.Cache, *enrichment.Service) {
+	repo, err := newStorage(cfg)
+	if err != nil {
+		logger.Error("Failed to initialize storage: %v", err)
+		os.Exit(1)
+	}
+	logger.Info("Storage initialized")
+
+	cache := newCache(cfg, repo)
+
+	registry, err := newRegistry(cfg)
+	if err != nil {
+		logger.Error("Failed to load enrichment providers: %v", err)
+		os.Exit(1)
+	}
+
+	enricher, err := enrichment.NewFromConfig(cfg.Enrichment, registry, cache, newLimiter(cfg, repo))
+	if err != nil {
+		logger.Error("Failed to initialize enrichment: %v", err)
+		os.Exit(1)
+	}
+	logger.Info("Enrichment service initialized")
+
+	return repo, cache, enricher
+}
+
+func newStorage(cfg *config.Config) (storage.Repository, error) {
+	switch cfg.Storage {
+	case config.StoragePostgres:
+		return pg.New(&cfg.DsnPG)
+	case config.StorageMemory:
+		return memory.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage type %q", cfg.Storage)
+	}
+}
+
+func newRegistry(cfg *config.Config) (*enrichment.Registry, error) {
+	registry := enrichment.DefaultRegistry(enrichment.NewHTTPClient(cfg.Enrichment), cfg.Enrichment)
+	if cfg.Enrichment.OfflineDataset == "" {
+		return registry, nil
+	}
+
+	offline, err := enrichment.LoadOffline(cfg.Enrichment.OfflineDataset)
+	if err != nil {
+		return nil, err
+	}
+	registry.Register(offline)
+	return registry, nil
+}
+
+func newCache(cfg *config.Config, repo storage.Repository) *enrichment.Cache {
+	if !cfg.Enrichment.CacheEnabled {
+		return nil
+	}
+
+	var store enrichment.CacheStore
+	if cfg.Enrichment.CachePersist {
+		if s, ok := repo.(enrichment.CacheStore); ok {
+			store = s
+		} else {
+			logger.Error("Enrichment cache persistence requires PostgreSQL storage, using memory only")
+		}
+	}
+
+	return enrichment.NewCache(cfg.Enrichment.CacheSize, cfg.Enrichment.CacheTTL, store)
+}
+
+// newLimiter хранит суточные квоты в PostgreSQL, чтобы они переживали
+// перезапуск; с хранилищем в памяти счётчики живут до остановки процесса.
+func newLimiter(cfg *config.Config, repo storage.Repository) *enrichment.Limiter {
+	var store enrichment.QuotaStore
+	if s, ok := repo.(enrichment.QuotaStore); ok {
+		store = s
+	}
+	return enrichment.NewLimiter(cfg.Enrichment, store)
+}
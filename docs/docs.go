@@ -22,7 +22,8 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/problem+json"
                 ],
                 "tags": [
                     "people"
@@ -97,24 +98,31 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/dto.ErrorResponse"
+                            "$ref": "#/definitions/dto.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/dto.ErrorResponse"
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
                         }
                     }
                 }
             },
             "post": {
-                "description": "Создаёт нового пользователя и возвращает его ID",
+                "description": "Создаёт нового пользователя и возвращает его ID. Переданные age, gender и\nnationality сохраняются как заданные вручную и не обогащаются.\nПри ENRICH_ASYNC=true человек сохраняется сразу со статусом pending_enrichment\nи ответом 202, а обогащение выполняется в фоне (см. GET /people/{id}/enrichment).",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/problem+json"
                 ],
                 "tags": [
                     "people"
@@ -133,7 +141,13 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "201": {
-                        "description": "Created",
+                        "description": "Человек создан и обогащён",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Response"
+                        }
+                    },
+                    "202": {
+                        "description": "Человек создан, обогащение поставлено в очередь",
                         "schema": {
                             "$ref": "#/definitions/dto.Response"
                         }
@@ -141,26 +155,115 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/dto.ErrorResponse"
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/dto.ErrorResponse"
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
                         }
                     }
                 }
             }
         },
         "/people/{id}": {
+            "get": {
+                "description": "Возвращает человека по ID. Заголовок ETag содержит версию записи для If-Match\nв PUT, PATCH и DELETE.",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "people"
+                ],
+                "summary": "Получить человека",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UserResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Версия записи"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            },
             "put": {
-                "description": "Обновляет данные пользователя по ID",
+                "description": "Обновляет данные пользователя по ID. Переданные age, gender и nationality\nсохраняются как заданные вручную и не перезаписываются обогащением.\nС заголовком If-Match запись обновляется, только если ETag совпадает с текущей\nверсией; новый ETag возвращается в ответе.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/problem+json"
                 ],
                 "tags": [
                     "people"
@@ -174,6 +277,24 @@ const docTemplate = `{
                         "in": "path",
                         "required": true
                     },
+                    {
+                        "type": "string",
+                        "description": "ETag из GET /people/{id}, * или несколько тегов через запятую; слабые теги (W/) не совпадают",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Обогатить заново атрибуты без защиты, даже если имя не изменилось",
+                        "name": "force",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Снять защиту и обогатить заново: all или атрибуты через запятую (age,gender,nationality)",
+                        "name": "unlock",
+                        "in": "query"
+                    },
                     {
                         "description": "Обновлённая информация о пользователе",
                         "name": "user",
@@ -189,29 +310,84 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/dto.Response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия записи"
+                            }
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/dto.ErrorResponse"
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/dto.ErrorResponse"
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
                         }
                     }
                 }
             },
             "delete": {
-                "description": "Удаляет пользователя по ID",
+                "description": "Удаляет пользователя по ID. С заголовком If-Match удаление выполняется,\nтолько если ETag совпадает с текущей версией записи.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/problem+json"
                 ],
                 "tags": [
                     "people"
@@ -224,6 +400,12 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag из GET /people/{id}, * или несколько тегов через запятую; слабые теги (W/) не совпадают",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -236,13 +418,209 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/dto.ErrorResponse"
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/dto.ErrorResponse"
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Обновляет данные пользователя по ID. Переданные age, gender и nationality\nсохраняются как заданные вручную и не перезаписываются обогащением.\nС заголовком If-Match запись обновляется, только если ETag совпадает с текущей\nверсией; новый ETag возвращается в ответе.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "people"
+                ],
+                "summary": "Обновить пользователя",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag из GET /people/{id}, * или несколько тегов через запятую; слабые теги (W/) не совпадают",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Обогатить заново атрибуты без защиты, даже если имя не изменилось",
+                        "name": "force",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Снять защиту и обогатить заново: all или атрибуты через запятую (age,gender,nationality)",
+                        "name": "unlock",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Обновлённая информация о пользователе",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Новая версия записи"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/people/{id}/enrichment": {
+            "get": {
+                "description": "Возвращает статус обогащения человека, историю фоновых задач и изменения атрибутов при повторном обогащении",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "people"
+                ],
+                "summary": "Статус обогащения",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.EnrichmentStatusResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
                         }
                     }
                 }
@@ -250,18 +628,221 @@ const docTemplate = `{
         }
     },
     "definitions": {
-        "dto.ErrorResponse": {
+        "dto.AttributeInfo": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 1250
+                },
+                "countries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.CountryInfo"
+                    }
+                },
+                "country_id": {
+                    "type": "string",
+                    "example": "RU"
+                },
+                "fetched_at": {
+                    "type": "string"
+                },
+                "low_confidence": {
+                    "description": "LowConfidence — ответ провайдера ниже порога достоверности, атрибут не заполнен.",
+                    "type": "boolean"
+                },
+                "probability": {
+                    "type": "number",
+                    "example": 0.99
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "genderize"
+                },
+                "rule": {
+                    "description": "Rule — правило локального провайдера (patronymic, surname).",
+                    "type": "string",
+                    "example": "patronymic"
+                }
+            }
+        },
+        "dto.ChangeResponse": {
+            "type": "object",
+            "properties": {
+                "attribute": {
+                    "type": "string",
+                    "example": "age"
+                },
+                "changed_at": {
+                    "type": "string"
+                },
+                "new_value": {
+                    "type": "string",
+                    "example": "43"
+                },
+                "old_value": {
+                    "type": "string",
+                    "example": "42"
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "agify"
+                }
+            }
+        },
+        "dto.CountryInfo": {
             "type": "object",
             "properties": {
+                "country_id": {
+                    "type": "string",
+                    "example": "RU"
+                },
+                "probability": {
+                    "type": "number",
+                    "example": 0.42
+                }
+            }
+        },
+        "dto.EnrichmentInfo": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "$ref": "#/definitions/dto.AttributeInfo"
+                },
+                "gender": {
+                    "$ref": "#/definitions/dto.AttributeInfo"
+                },
+                "nationality": {
+                    "$ref": "#/definitions/dto.AttributeInfo"
+                }
+            }
+        },
+        "dto.EnrichmentStatusResponse": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ChangeResponse"
+                    }
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "jobs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.JobResponse"
+                    }
+                },
+                "pending": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "age"
+                    ]
+                },
+                "status": {
+                    "type": "string",
+                    "example": "pending_enrichment"
+                }
+            }
+        },
+        "dto.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "age"
+                },
                 "message": {
                     "type": "string",
-                    "example": "error happened"
+                    "example": "must be between 0 and 150"
+                }
+            }
+        },
+        "dto.JobResponse": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "last_error": {
+                    "type": "string",
+                    "example": "pending attributes: age"
+                },
+                "run_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "queued"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code — машиночитаемый код ошибки, например not_found или version_conflict.",
+                    "type": "string",
+                    "example": "not_found"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "person not found"
+                },
+                "errors": {
+                    "description": "Errors — ошибки отдельных полей, если запрос не прошёл проверку.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/people/42"
+                },
+                "request_id": {
+                    "type": "string",
+                    "example": "3f9a1c0d2b7e4a61"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Not Found"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "/problems/not_found"
                 }
             }
         },
         "dto.Response": {
             "type": "object",
             "properties": {
+                "enrichment": {
+                    "description": "Enrichment — статус обогащения по атрибутам: succeeded, failed, skipped или low_confidence.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
                 "id": {
                     "type": "integer",
                     "example": 1
@@ -274,17 +855,48 @@ const docTemplate = `{
         },
         "dto.UserRequest": {
             "type": "object",
+            "required": [
+                "name",
+                "surname"
+            ],
             "properties": {
+                "age": {
+                    "description": "Age, Gender и Nationality задаются вручную и защищаются от повторного\nобогащения; снять защиту можно параметром unlock.",
+                    "type": "integer",
+                    "maximum": 150,
+                    "minimum": 0,
+                    "example": 30
+                },
+                "country_hint": {
+                    "description": "CountryHint — страна, для которой запрашиваются возраст и пол.",
+                    "type": "string",
+                    "example": "RU"
+                },
+                "gender": {
+                    "type": "string",
+                    "enum": [
+                        "male",
+                        "female"
+                    ],
+                    "example": "male"
+                },
                 "name": {
                     "type": "string",
+                    "maxLength": 100,
                     "example": "Dmitriy"
                 },
+                "nationality": {
+                    "type": "string",
+                    "example": "RU"
+                },
                 "patronymic": {
                     "type": "string",
+                    "maxLength": 100,
                     "example": "Sergeevich"
                 },
                 "surname": {
                     "type": "string",
+                    "maxLength": 100,
                     "example": "Ivanov"
                 }
             }
@@ -296,6 +908,17 @@ const docTemplate = `{
                     "type": "integer",
                     "example": 30
                 },
+                "country_hint": {
+                    "type": "string",
+                    "example": "RU"
+                },
+                "enrichment": {
+                    "$ref": "#/definitions/dto.EnrichmentInfo"
+                },
+                "enrichment_status": {
+                    "type": "string",
+                    "example": "complete"
+                },
                 "gender": {
                     "type": "string",
                     "example": "male"
@@ -304,6 +927,15 @@ const docTemplate = `{
                     "type": "integer",
                     "example": 1
                 },
+                "locked_attributes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "gender"
+                    ]
+                },
                 "name": {
                     "type": "string",
                     "example": "Dmitriy"
@@ -316,9 +948,23 @@ const docTemplate = `{
                     "type": "string",
                     "example": "Sergeevich"
                 },
+                "pending_enrichment": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "gender"
+                    ]
+                },
                 "surname": {
                     "type": "string",
                     "example": "Ivanov"
+                },
+                "version": {
+                    "description": "Version — версия записи, та же, что в ETag.",
+                    "type": "integer",
+                    "example": 3
                 }
             }
         }
@@ -2,7 +2,9 @@ package config
 
 import (
 	"Effective_Mobile/internal/logger"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v6"
@@ -10,25 +12,174 @@ import (
 )
 
 type Config struct {
+	Storage    string     `env:"STORAGE_TYPE" envDefault:"postgres"`
 	DsnPG      DsnPG      `envPrefix:"DSN_"`
 	HTTPServer HTTPServer `envPrefix:"HTTP_"`
-	Debug      bool       `env:"DEBUG"`
+	Enrichment Enrichment `envPrefix:"ENRICH_"`
+	Worker     Worker     `envPrefix:"WORKER_"`
+	Refresh    Refresh    `envPrefix:"REFRESH_"`
+	// Transliterate — переводить кириллицу в латиницу в ключах поиска и запросах обогащения.
+	Transliterate bool `env:"NORMALIZE_TRANSLITERATE" envDefault:"true"`
+	Debug         bool `env:"DEBUG"`
 }
 
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
 type DsnPG struct {
-	Port     int    `env:"PORT" env-default:"5432"`
-	User     string `env:"USER" env-default:"admin"`
-	Password string `env:"PASSWORD" env-default:"adm_123"`
-	Name     string `env:"NAME" env-default:"myapp"`
-	Host     string `env:"HOST" env-default:"localhost"`
+	Port     int    `env:"PORT" envDefault:"5432"`
+	User     string `env:"USER" envDefault:"admin"`
+	Password string `env:"PASSWORD" envDefault:"adm_123"`
+	Name     string `env:"NAME" envDefault:"myapp"`
+	Host     string `env:"HOST" envDefault:"localhost"`
+	// TxIsolation — уровень изоляции транзакций: read_committed, repeatable_read или serializable.
+	TxIsolation string `env:"TX_ISOLATION" envDefault:"serializable"`
+	// TxRetries — повторы транзакции при конфликте сериализации.
+	TxRetries int `env:"TX_RETRIES" envDefault:"3"`
+}
+
+// String скрывает пароль, чтобы он не попал в лог вместе с конфигурацией.
+func (d DsnPG) String() string {
+	return fmt.Sprintf("{Port:%d User:%s Password:%s Name:%s Host:%s TxIsolation:%s TxRetries:%d}",
+		d.Port, d.User, redact(d.Password), d.Name, d.Host, d.TxIsolation, d.TxRetries)
 }
 
 type HTTPServer struct {
-	Address     string        `env:"ADDR" env-default:"localhost:8080"`
-	Timeout     time.Duration `env:"TIMEOUT" env-default:"4s"`
-	IdleTimeout time.Duration `env:"IDLE_TIMEOUT" env-default:"60s"`
-	User        string        `env:"USER" env-required:"true"`
-	Password    string        `env:"HTTP_SERVER_PASSWORD" env-required:"true"`
+	Address     string        `env:"ADDR" envDefault:"localhost:8080"`
+	ReadTimeout time.Duration `env:"READ_TIMEOUT" envDefault:"4s"`
+	// WriteTimeout — время на обработку запроса и запись ответа; должно быть
+	// больше ENRICH_TIMEOUT, иначе синхронное обогащение не успеет ответить.
+	WriteTimeout time.Duration `env:"WRITE_TIMEOUT" envDefault:"10s"`
+	IdleTimeout  time.Duration `env:"IDLE_TIMEOUT" envDefault:"60s"`
+	User         string        `env:"USER" env-required:"true"`
+	Password     string        `env:"HTTP_SERVER_PASSWORD" env-required:"true"`
+	// MaxBodySize — предельный размер тела запроса в байтах, больше — 413.
+	MaxBodySize int64 `env:"MAX_BODY_SIZE" envDefault:"65536"`
+}
+
+// String скрывает пароль, чтобы он не попал в лог вместе с конфигурацией.
+func (h HTTPServer) String() string {
+	return fmt.Sprintf("{Address:%s ReadTimeout:%s WriteTimeout:%s IdleTimeout:%s User:%s Password:%s MaxBodySize:%d}",
+		h.Address, h.ReadTimeout, h.WriteTimeout, h.IdleTimeout, h.User, redact(h.Password), h.MaxBodySize)
+}
+
+type Enrichment struct {
+	// Timeout — общий дедлайн обогащения одного запроса, включая повторы и
+	// ожидание лимитов провайдеров.
+	Timeout             time.Duration `env:"TIMEOUT" envDefault:"5s"`
+	RequestTimeout      time.Duration `env:"REQUEST_TIMEOUT" envDefault:"3s"`
+	MaxIdleConns        int           `env:"MAX_IDLE_CONNS" envDefault:"30"`
+	MaxIdleConnsPerHost int           `env:"MAX_IDLE_CONNS_PER_HOST" envDefault:"10"`
+	MaxConnsPerHost     int           `env:"MAX_CONNS_PER_HOST" envDefault:"10"`
+	IdleConnTimeout     time.Duration `env:"IDLE_CONN_TIMEOUT" envDefault:"90s"`
+
+	AgeProviders         []string `env:"AGE_PROVIDERS" envSeparator:"," envDefault:"agify"`
+	GenderProviders      []string `env:"GENDER_PROVIDERS" envSeparator:"," envDefault:"patronymic,genderize"`
+	NationalityProviders []string `env:"NATIONALITY_PROVIDERS" envSeparator:"," envDefault:"nationalize"`
+	// OfflineDataset — CSV или JSON со статистикой имён для провайдера offline.
+	OfflineDataset string `env:"OFFLINE_DATASET"`
+
+	Agify       Provider `envPrefix:"AGIFY_"`
+	Genderize   Provider `envPrefix:"GENDERIZE_"`
+	Nationalize Provider `envPrefix:"NATIONALIZE_"`
+
+	CacheEnabled bool          `env:"CACHE_ENABLED" envDefault:"true"`
+	CacheSize    int           `env:"CACHE_SIZE" envDefault:"10000"`
+	CacheTTL     time.Duration `env:"CACHE_TTL" envDefault:"24h"`
+	CachePersist bool          `env:"CACHE_PERSIST" envDefault:"false"`
+
+	Partial bool `env:"PARTIAL" envDefault:"true"`
+	// Localize — без подсказки страны запрашивать возраст и пол для страны,
+	// определённой по национальности.
+	Localize bool `env:"LOCALIZE" envDefault:"true"`
+
+	// Пороги достоверности: ответы ниже порога не записываются в атрибут.
+	AgeMinCount               int     `env:"AGE_MIN_COUNT" envDefault:"1"`
+	GenderMinProbability      float64 `env:"GENDER_MIN_PROBABILITY" envDefault:"0.6"`
+	GenderMinCount            int     `env:"GENDER_MIN_COUNT" envDefault:"1"`
+	NationalityMinProbability float64 `env:"NATIONALITY_MIN_PROBABILITY" envDefault:"0"`
+	NationalityMinCount       int     `env:"NATIONALITY_MIN_COUNT" envDefault:"1"`
+	// Async — сохранять человека сразу и обогащать в фоне через очередь задач.
+	Async bool `env:"ASYNC" envDefault:"false"`
+
+	RetryMax         int           `env:"RETRY_MAX" envDefault:"3"`
+	RetryBaseDelay   time.Duration `env:"RETRY_BASE_DELAY" envDefault:"200ms"`
+	RetryMaxDelay    time.Duration `env:"RETRY_MAX_DELAY" envDefault:"5s"`
+	BreakerThreshold int           `env:"BREAKER_THRESHOLD" envDefault:"5"`
+	BreakerCooldown  time.Duration `env:"BREAKER_COOLDOWN" envDefault:"30s"`
+
+	// RateLimitPolicy — поведение при исчерпании лимитов: queue, degrade или reject.
+	RateLimitPolicy  string        `env:"RATE_LIMIT_POLICY" envDefault:"queue"`
+	RateLimitMaxWait time.Duration `env:"RATE_LIMIT_MAX_WAIT" envDefault:"2s"`
+}
+
+// Provider — настройки HTTP-провайдера обогащения.
+type Provider struct {
+	// URL — базовый адрес API, например http://localhost:7100/agify/ для fakeenrich.
+	// Пустое значение — публичный адрес провайдера.
+	URL     string `env:"URL"`
+	Enabled bool   `env:"ENABLED" envDefault:"true"`
+	// Timeout — таймаут запроса к провайдеру; 0 — ENRICH_REQUEST_TIMEOUT.
+	Timeout time.Duration `env:"TIMEOUT"`
+	// APIKey — ключ платного тарифа. APIKeyFile — файл с ключом (Docker secrets),
+	// читается при загрузке конфигурации и имеет приоритет.
+	APIKey     string `env:"API_KEY"`
+	APIKeyFile string `env:"API_KEY_FILE"`
+	// RateLimit — запросов в секунду (0 — без ограничения), RateBurst — запас токенов.
+	RateLimit float64 `env:"RATE_LIMIT" envDefault:"0"`
+	RateBurst int     `env:"RATE_BURST" envDefault:"1"`
+	// DailyQuota — имён в сутки (UTC), 0 — без ограничения.
+	DailyQuota int `env:"DAILY_QUOTA" envDefault:"0"`
+}
+
+// String скрывает ключ, чтобы он не попал в лог вместе с конфигурацией.
+func (p Provider) String() string {
+	return fmt.Sprintf("{URL:%s Enabled:%t Timeout:%s APIKey:%s APIKeyFile:%s RateLimit:%g RateBurst:%d DailyQuota:%d}",
+		p.URL, p.Enabled, p.Timeout, redact(p.APIKey), p.APIKeyFile, p.RateLimit, p.RateBurst, p.DailyQuota)
+}
+
+// redact заменяет непустой секрет на REDACTED.
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "REDACTED"
+}
+
+func (p *Provider) loadKey() error {
+	if p.APIKeyFile == "" {
+		return nil
+	}
+	key, err := os.ReadFile(p.APIKeyFile)
+	if err != nil {
+		return err
+	}
+	p.APIKey = strings.TrimSpace(string(key))
+	return nil
+}
+
+type Worker struct {
+	Count        int           `env:"COUNT" envDefault:"2"`
+	BatchSize    int           `env:"BATCH_SIZE" envDefault:"10"`
+	PollInterval time.Duration `env:"POLL_INTERVAL" envDefault:"1s"`
+	MaxAttempts  int           `env:"MAX_ATTEMPTS" envDefault:"5"`
+	RetryDelay   time.Duration `env:"RETRY_DELAY" envDefault:"30s"`
+	Lease        time.Duration `env:"LEASE" envDefault:"5m"`
+}
+
+// Refresh — повторное обогащение пустых, недостоверных и устаревших атрибутов.
+type Refresh struct {
+	Enabled  bool          `env:"ENABLED" envDefault:"false"`
+	Interval time.Duration `env:"INTERVAL" envDefault:"1h"`
+	// MaxAge — возраст атрибута, после которого он запрашивается заново.
+	MaxAge time.Duration `env:"MAX_AGE" envDefault:"720h"`
+	// RetryMissing — пауза перед повторным запросом пустых и недостоверных атрибутов.
+	RetryMissing time.Duration `env:"RETRY_MISSING" envDefault:"24h"`
+	// Budget — максимум людей за один проход.
+	Budget     int           `env:"BUDGET" envDefault:"100"`
+	BatchDelay time.Duration `env:"BATCH_DELAY" envDefault:"1s"`
 }
 
 func MustLoad() *Config {
@@ -46,6 +197,22 @@ func MustLoad() *Config {
 		os.Exit(1)
 	}
 
+	for name, p := range map[string]*Provider{
+		"agify":       &cfg.Enrichment.Agify,
+		"genderize":   &cfg.Enrichment.Genderize,
+		"nationalize": &cfg.Enrichment.Nationalize,
+	} {
+		if err := p.loadKey(); err != nil {
+			logger.Error("%s: failed to read %s API key: %v", op, name, err)
+			os.Exit(1)
+		}
+	}
+
+	if err := cfg.validate(); err != nil {
+		logger.Error("%s: invalid configuration: %v", op, err)
+		os.Exit(1)
+	}
+
 	logger.DebugEnabled = cfg.Debug
 	if cfg.Debug {
 		logger.Info("%s: debug mode enabled", op)
@@ -54,3 +221,21 @@ func MustLoad() *Config {
 	logger.Info("%s: configuration loaded successfully", op)
 	return &cfg
 }
+
+// validate проверяет согласованность таймаутов: обогащение в обработчике
+// должно укладываться в WriteTimeout с запасом на работу с БД и запись ответа.
+func (c *Config) validate() error {
+	const op = "config.validate"
+
+	write, enrich := c.HTTPServer.WriteTimeout, c.Enrichment.Timeout
+	if write <= 0 {
+		return nil
+	}
+	if enrich <= 0 {
+		return fmt.Errorf("%s: ENRICH_TIMEOUT must be set when HTTP_WRITE_TIMEOUT is %s", op, write)
+	}
+	if enrich >= write {
+		return fmt.Errorf("%s: ENRICH_TIMEOUT (%s) must be shorter than HTTP_WRITE_TIMEOUT (%s)", op, enrich, write)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateTimeouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		write   time.Duration
+		enrich  time.Duration
+		wantErr bool
+	}{
+		{"enrichment fits", 10 * time.Second, 5 * time.Second, false},
+		{"no write timeout", 0, 0, false},
+		{"enrichment without deadline", 10 * time.Second, 0, true},
+		{"enrichment as long as write", 10 * time.Second, 10 * time.Second, true},
+		{"enrichment longer than write", 5 * time.Second, 10 * time.Second, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			cfg.HTTPServer.WriteTimeout = tt.write
+			cfg.Enrichment.Timeout = tt.enrich
+
+			if err := cfg.validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("validate: got %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStringRedactsSecrets(t *testing.T) {
+	var cfg Config
+	cfg.DsnPG = DsnPG{User: "admin", Password: "pg-secret", Host: "db"}
+	cfg.HTTPServer = HTTPServer{Address: ":8080", User: "api", Password: "http-secret"}
+	cfg.Enrichment.Agify = Provider{APIKey: "agify-secret"}
+
+	// так конфигурация пишется в лог при запуске
+	logged := fmt.Sprintf("%+v", &cfg)
+	for _, secret := range []string{"pg-secret", "http-secret", "agify-secret"} {
+		if strings.Contains(logged, secret) {
+			t.Errorf("config log contains %q: %s", secret, logged)
+		}
+	}
+	for _, field := range []string{"User:admin", "Host:db", "Address::8080", "User:api", "Password:REDACTED", "APIKey:REDACTED"} {
+		if !strings.Contains(logged, field) {
+			t.Errorf("config log misses %s: %s", field, logged)
+		}
+	}
+
+	if s := (DsnPG{}).String(); !strings.Contains(s, "Password: ") {
+		t.Errorf("empty password shown as %s, want it empty", s)
+	}
+}
@@ -0,0 +1,235 @@
+package fakeenrich
+
+import (
+	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/service/enrichment"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Пути API на fake-сервере. Один сервер отвечает за все три провайдера.
+const (
+	AgifyPath       = "/agify/"
+	GenderizePath   = "/genderize/"
+	NationalizePath = "/nationalize/"
+)
+
+// Faults — сбои, которые сервер подмешивает в ответы. Поля *Every срабатывают
+// на каждом N-м запросе (0 — никогда), поэтому поведение детерминировано.
+type Faults struct {
+	Latency        time.Duration
+	RateLimitEvery int
+	ErrorEvery     int
+	MalformedEvery int
+	// RateLimitReset — значение X-Rate-Limit-Reset в ответах 429.
+	RateLimitReset time.Duration
+}
+
+// Server имитирует agify, genderize и nationalize, отвечая по набору данных
+// в формате провайдера offline. Реализует http.Handler, поэтому годится для
+// httptest.NewServer.
+type Server struct {
+	fixtures *enrichment.Offline
+	mux      *http.ServeMux
+
+	mu       sync.Mutex
+	faults   Faults
+	requests int
+}
+
+type ageResponse struct {
+	Count     int    `json:"count"`
+	Name      string `json:"name"`
+	Age       *int   `json:"age"`
+	CountryID string `json:"country_id,omitempty"`
+}
+
+type genderResponse struct {
+	Count       int     `json:"count"`
+	Name        string  `json:"name"`
+	Gender      *string `json:"gender"`
+	Probability float64 `json:"probability"`
+	CountryID   string  `json:"country_id,omitempty"`
+}
+
+type nationalityResponse struct {
+	Count   int             `json:"count"`
+	Name    string          `json:"name"`
+	Country []model.Country `json:"country"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// New создаёт сервер. Имена, которых нет в fixtures, получают ответ с count 0,
+// как у настоящих API.
+func New(fixtures *enrichment.Offline, faults Faults) *Server {
+	s := &Server{fixtures: fixtures, faults: faults, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc(AgifyPath, s.handle(func(ctx context.Context, q enrichment.Query) (any, error) {
+		res, err := s.fixtures.Age(ctx, q)
+		if errors.Is(err, enrichment.ErrNoMatch) {
+			return ageResponse{Name: q.Name, CountryID: q.CountryID}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return ageResponse{Count: res.Count, Name: q.Name, Age: res.Age, CountryID: q.CountryID}, nil
+	}))
+	s.mux.HandleFunc(GenderizePath, s.handle(func(ctx context.Context, q enrichment.Query) (any, error) {
+		res, err := s.fixtures.Gender(ctx, q)
+		if errors.Is(err, enrichment.ErrNoMatch) {
+			return genderResponse{Name: q.Name, CountryID: q.CountryID}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return genderResponse{Count: res.Count, Name: q.Name, Gender: res.Gender,
+			Probability: res.Probability, CountryID: q.CountryID}, nil
+	}))
+	s.mux.HandleFunc(NationalizePath, s.handle(func(ctx context.Context, q enrichment.Query) (any, error) {
+		res, err := s.fixtures.Nationality(ctx, q)
+		if errors.Is(err, enrichment.ErrNoMatch) {
+			return nationalityResponse{Name: q.Name, Country: []model.Country{}}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nationalityResponse{Count: res.Count, Name: q.Name, Country: res.Countries}, nil
+	}))
+
+	return s
+}
+
+// Load читает набор данных (CSV или JSON, как для ENRICH_OFFLINE_DATASET).
+func Load(path string, faults Faults) (*Server, error) {
+	fixtures, err := enrichment.LoadOffline(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(fixtures, faults), nil
+}
+
+// Configure направляет HTTP-провайдеров cfg на fake-сервер по адресу baseURL,
+// например httptest.Server.URL.
+func Configure(cfg *config.Enrichment, baseURL string) {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	cfg.Agify.URL = baseURL + AgifyPath
+	cfg.Genderize.URL = baseURL + GenderizePath
+	cfg.Nationalize.URL = baseURL + NationalizePath
+}
+
+// SetFaults меняет сбои на лету и сбрасывает счётчик запросов.
+func (s *Server) SetFaults(faults Faults) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = faults
+	s.requests = 0
+}
+
+// Requests возвращает число запросов с момента запуска или последнего SetFaults.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handle(answer func(context.Context, enrichment.Query) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "fakeenrich.handle"
+
+		logger.Debug("%s: %s %s", op, r.Method, r.URL.String())
+
+		faults, n := s.next()
+		if faults.Latency > 0 {
+			timer := time.NewTimer(faults.Latency)
+			select {
+			case <-r.Context().Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+
+		switch {
+		case every(n, faults.RateLimitEvery):
+			w.Header().Set("X-Rate-Limit-Remaining", "0")
+			w.Header().Set("X-Rate-Limit-Reset", strconv.Itoa(int(faults.RateLimitReset.Seconds())))
+			writeJSON(w, http.StatusTooManyRequests, errorResponse{Error: "Request limit reached"})
+			return
+		case every(n, faults.ErrorEvery):
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "Internal server error"})
+			return
+		case every(n, faults.MalformedEvery):
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"count":`))
+			return
+		}
+
+		query := r.URL.Query()
+		countryID := strings.ToUpper(query.Get("country_id"))
+		names, batch := query["name[]"], true
+		if len(names) == 0 {
+			names, batch = query["name"], false
+		}
+		if len(names) == 0 || names[0] == "" {
+			writeJSON(w, http.StatusUnprocessableEntity, errorResponse{Error: "Missing 'name' parameter"})
+			return
+		}
+		if len(names) > enrichment.MaxBatchSize {
+			writeJSON(w, http.StatusUnprocessableEntity, errorResponse{Error: "Invalid 'name' parameter"})
+			return
+		}
+
+		results := make([]any, len(names))
+		for i, name := range names {
+			res, err := answer(r.Context(), enrichment.Query{Name: name, CountryID: countryID})
+			if err != nil {
+				logger.Error("%s: %v", op, err)
+				writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+				return
+			}
+			results[i] = res
+		}
+
+		if batch {
+			writeJSON(w, http.StatusOK, results)
+			return
+		}
+		writeJSON(w, http.StatusOK, results[0])
+	}
+}
+
+func (s *Server) next() (Faults, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	return s.faults, s.requests
+}
+
+func every(n, period int) bool {
+	return period > 0 && n%period == 0
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
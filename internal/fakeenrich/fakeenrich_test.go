@@ -0,0 +1,103 @@
+package fakeenrich
+
+import (
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/service/enrichment"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newServer(faults Faults) *Server {
+	age := 47
+	return New(enrichment.NewOffline([]enrichment.DatasetEntry{
+		{Name: "ivan", Count: 100, Gender: "male", GenderProbability: 0.99, Age: &age,
+			Countries: []model.Country{{CountryID: "RU", Probability: 0.8}}},
+	}), faults)
+}
+
+func get(t *testing.T, s *Server, target string, v any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if v != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("decode %s: %v", rec.Body, err)
+		}
+	}
+	return rec
+}
+
+func TestAnswersLikeProviders(t *testing.T) {
+	s := newServer(Faults{})
+
+	var age ageResponse
+	get(t, s, AgifyPath+"?name=Ivan&country_id=ru", &age)
+	if age.Count != 100 || age.Age == nil || *age.Age != 47 || age.CountryID != "RU" {
+		t.Errorf("agify: got %+v", age)
+	}
+
+	var genders []genderResponse
+	get(t, s, GenderizePath+"?name[]=ivan&name[]=zzz", &genders)
+	if len(genders) != 2 || genders[0].Gender == nil || *genders[0].Gender != "male" {
+		t.Fatalf("genderize batch: got %+v", genders)
+	}
+	// неизвестное имя — count 0, как у настоящего API
+	if genders[1].Count != 0 || genders[1].Gender != nil || genders[1].Name != "zzz" {
+		t.Errorf("genderize miss: got %+v", genders[1])
+	}
+
+	var nationality nationalityResponse
+	get(t, s, NationalizePath+"?name=zzz", &nationality)
+	if nationality.Count != 0 || nationality.Country == nil || len(nationality.Country) != 0 {
+		t.Errorf("nationalize miss: got %+v, want an empty country list", nationality)
+	}
+}
+
+func TestRejectsBadNames(t *testing.T) {
+	s := newServer(Faults{})
+
+	if rec := get(t, s, AgifyPath, nil); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("missing name: got %d, want 422", rec.Code)
+	}
+
+	target := AgifyPath + "?"
+	for i := 0; i <= enrichment.MaxBatchSize; i++ {
+		target += "name[]=ivan&"
+	}
+	if rec := get(t, s, target, nil); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("%d names: got %d, want 422", enrichment.MaxBatchSize+1, rec.Code)
+	}
+}
+
+func TestFaultsEveryNthRequest(t *testing.T) {
+	s := newServer(Faults{ErrorEvery: 2, RateLimitEvery: 3})
+
+	// на шестом запросе срабатывают оба сбоя, 429 проверяется первым
+	want := []int{http.StatusOK, http.StatusInternalServerError, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusOK, http.StatusTooManyRequests}
+	for i, status := range want {
+		rec := get(t, s, AgifyPath+"?name=ivan", nil)
+		if rec.Code != status {
+			t.Errorf("request %d: got %d, want %d", i+1, rec.Code, status)
+		}
+		if status == http.StatusTooManyRequests && rec.Header().Get("X-Rate-Limit-Remaining") != "0" {
+			t.Errorf("request %d: 429 without X-Rate-Limit-Remaining: 0", i+1)
+		}
+	}
+	if s.Requests() != len(want) {
+		t.Errorf("got %d requests, want %d", s.Requests(), len(want))
+	}
+
+	s.SetFaults(Faults{MalformedEvery: 1})
+	if s.Requests() != 0 {
+		t.Errorf("SetFaults kept %d requests", s.Requests())
+	}
+	var age ageResponse
+	rec := get(t, s, AgifyPath+"?name=ivan", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &age); err == nil {
+		t.Errorf("malformed fault returned valid JSON: %s", rec.Body)
+	}
+}
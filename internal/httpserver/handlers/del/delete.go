@@ -3,25 +3,35 @@ package del
 import (
 	"Effective_Mobile/internal/httpserver/handlers"
 	"Effective_Mobile/internal/httpserver/handlers/dto"
+	"Effective_Mobile/internal/httpserver/handlers/get"
 	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/storage"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 )
 
 type Deleter interface {
-	Delete(id int) error
+	get.Getter
+	Delete(ctx context.Context, id, version int) error
 }
 
 // @Summary Удалить пользователя
-// @Description Удаляет пользователя по ID
+// @Description Удаляет пользователя по ID. С заголовком If-Match удаление выполняется,
+// @Description только если ETag совпадает с текущей версией записи.
 // @Tags people
 // @Accept json
-// @Produce json
+// @Produce json,application/problem+json
 // @Param id path int true "ID пользователя"
+// @Param If-Match header string false "ETag из GET /people/{id}, * или несколько тегов через запятую; слабые теги (W/) не совпадают"
 // @Success 200 {object} dto.Response
-// @Failure 400 {object} dto.ErrorResponse
-// @Failure 500 {object} dto.ErrorResponse
+// @Failure 400 {object} dto.Problem
+// @Failure 404 {object} dto.Problem
+// @Failure 412 {object} dto.Problem
+// @Failure 500 {object} dto.Problem
+// @Failure 504 {object} dto.Problem
 // @Router /people/{id} [delete]
 func New(deleter Deleter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -31,22 +41,36 @@ func New(deleter Deleter) http.HandlerFunc {
 
 		if http.MethodDelete != r.Method {
 			logger.Error("%s: method not allowed: %s", op, r.Method)
-			handlers.WriteError(w, http.StatusMethodNotAllowed, fmt.Sprintf("%s: %s", op, handlers.MethodNotAllowed))
+			handlers.RespondError(w, r, op, handlers.MethodNotAllowed)
 			return
 		}
 
 		id, err := handlers.GetID(r.URL.Path)
 		if err != nil {
 			logger.Error("%s: failed to extract ID from URL: %v", op, err)
-			handlers.WriteError(w, http.StatusBadRequest, fmt.Sprintf("%s: %s", op, err.Error()))
+			handlers.RespondError(w, r, op, err)
 			return
 		}
 		logger.Debug("%s: extracted id: %d", op, id)
 
-		err = deleter.Delete(id)
+		versions, err := handlers.IfMatch(r)
+		if err != nil {
+			logger.Error("%s: invalid If-Match: %v", op, err)
+			handlers.RespondError(w, r, op, err)
+			return
+		}
+
+		version, err := expectedVersion(r.Context(), deleter, id, versions)
+		if err != nil {
+			logger.Error("%s: precondition failed for user %d: %v", op, id, err)
+			handlers.RespondError(w, r, op, err)
+			return
+		}
+
+		err = deleter.Delete(r.Context(), id, version)
 		if err != nil {
 			logger.Error("%s: failed to delete user with id %d: %v", op, id, err)
-			handlers.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("%s: %s", op, err.Error()))
+			handlers.RespondError(w, r, op, err)
 			return
 		}
 		logger.Info("%s: successfully deleted user with id %d", op, id)
@@ -58,7 +82,7 @@ func New(deleter Deleter) http.HandlerFunc {
 		responseJson, err := json.Marshal(&response)
 		if err != nil {
 			logger.Error("%s: failed to marshal response: %v", op, err)
-			handlers.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("%s: %s", op, err.Error()))
+			handlers.RespondError(w, r, op, err)
 			return
 		}
 
@@ -68,3 +92,29 @@ func New(deleter Deleter) http.HandlerFunc {
 		logger.Debug("%s: response written: %s", op, string(responseJson))
 	}
 }
+
+// expectedVersion выбирает версию для условного удаления. Delete сверяет одну
+// версию атомарно, поэтому при нескольких тегах в If-Match сначала читается
+// текущая версия записи, а затем удаляется именно она.
+func expectedVersion(ctx context.Context, getter get.Getter, id int, versions []int) (int, error) {
+	const op = "httpserver.handlers.del.expectedVersion"
+
+	switch len(versions) {
+	case 0:
+		return 0, nil
+	case 1:
+		return versions[0], nil
+	}
+
+	users, err := getter.List(ctx, &storage.ListParam{User: model.User{ID: id}})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(users) == 0 {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if !handlers.MatchVersion(versions, users[0].Version) {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrVersionConflict)
+	}
+	return users[0].Version, nil
+}
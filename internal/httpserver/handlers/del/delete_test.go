@@ -0,0 +1,50 @@
+package del
+
+import (
+	"Effective_Mobile/internal/httpserver/handlers"
+	"Effective_Mobile/internal/httpserver/handlers/handlertest"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/storage/memory"
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestDeleteIfMatch(t *testing.T) {
+	repo := memory.New()
+	ctx := context.Background()
+	id, err := repo.Add(ctx, model.User{Name: "Ivan", Surname: "Smith", NameKey: "ivan", SurnameKey: "smith"})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	age := 40
+	if err = repo.Update(ctx, id, &model.User{Age: &age}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	h, path := New(repo), "/people/"+strconv.Itoa(id)
+
+	tests := []struct {
+		name    string
+		ifMatch string
+		status  int
+		code    string
+	}{
+		{"stale version", `"1"`, http.StatusPreconditionFailed, handlers.CodeVersionConflict},
+		{"weak tag", `W/"2"`, http.StatusPreconditionFailed, handlers.CodeVersionConflict},
+		{"unquoted", `2`, http.StatusBadRequest, handlers.CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := handlertest.Do(t, h, http.MethodDelete, path, "", "If-Match", tt.ifMatch)
+			handlertest.AssertProblem(t, rec, tt.status, tt.code)
+		})
+	}
+	handlertest.Stored(t, repo, id)
+
+	rec := handlertest.Do(t, h, http.MethodDelete, path, "", "If-Match", `"1", "2"`)
+	handlertest.AssertStatus(t, rec, http.StatusOK, nil)
+
+	rec = handlertest.Do(t, h, http.MethodDelete, path, "")
+	handlertest.AssertProblem(t, rec, http.StatusNotFound, handlers.CodeNotFound)
+}
@@ -0,0 +1,29 @@
+package dto
+
+import "time"
+
+type EnrichmentStatusResponse struct {
+	ID      int               `json:"id" example:"1"`
+	Status  string            `json:"status" example:"pending_enrichment"`
+	Pending []string          `json:"pending,omitempty" example:"age"`
+	Jobs    []*JobResponse    `json:"jobs"`
+	Changes []*ChangeResponse `json:"changes"`
+}
+
+type JobResponse struct {
+	ID        int64     `json:"id" example:"1"`
+	Status    string    `json:"status" example:"queued"`
+	Attempts  int       `json:"attempts" example:"1"`
+	LastError *string   `json:"last_error,omitempty" example:"pending attributes: age"`
+	RunAt     time.Time `json:"run_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ChangeResponse — изменение атрибута при повторном обогащении.
+type ChangeResponse struct {
+	Attribute string    `json:"attribute" example:"age"`
+	OldValue  *string   `json:"old_value,omitempty" example:"42"`
+	NewValue  *string   `json:"new_value,omitempty" example:"43"`
+	Provider  string    `json:"provider,omitempty" example:"agify"`
+	ChangedAt time.Time `json:"changed_at"`
+}
@@ -3,8 +3,25 @@ package dto
 type Response struct {
 	ID      int    `json:"id" example:"1"`
 	Message string `json:"message" example:"user added"`
+	// Enrichment — статус обогащения по атрибутам: succeeded, failed, skipped или low_confidence.
+	Enrichment map[string]string `json:"enrichment,omitempty"`
 }
 
-type ErrorResponse struct {
-	Error string `json:"message" example:"error happened"`
+// Problem — ответ с ошибкой в формате RFC 7807 (application/problem+json).
+type Problem struct {
+	Type     string `json:"type" example:"/problems/not_found"`
+	Title    string `json:"title" example:"Not Found"`
+	Status   int    `json:"status" example:"404"`
+	Detail   string `json:"detail,omitempty" example:"person not found"`
+	Instance string `json:"instance,omitempty" example:"/people/42"`
+	// Code — машиночитаемый код ошибки, например not_found или version_conflict.
+	Code      string `json:"code" example:"not_found"`
+	RequestID string `json:"request_id,omitempty" example:"3f9a1c0d2b7e4a61"`
+	// Errors — ошибки отдельных полей, если запрос не прошёл проверку.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field" example:"age"`
+	Message string `json:"message" example:"must be between 0 and 150"`
 }
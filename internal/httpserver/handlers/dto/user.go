@@ -1,17 +1,59 @@
 package dto
 
+import "time"
+
+// UserRequest проверяется по тегам validate (см. lib/validate) одинаково в POST,
+// PUT и PATCH; в PUT и PATCH обязательных полей нет.
 type UserRequest struct {
-	Name       string  `json:"name" example:"Dmitriy"`
-	Surname    string  `json:"surname" example:"Ivanov"`
-	Patronymic *string `json:"patronymic,omitempty" example:"Sergeevich"`
+	Name       string  `json:"name" example:"Dmitriy" validate:"required,max=100,name"`
+	Surname    string  `json:"surname" example:"Ivanov" validate:"required,max=100,name"`
+	Patronymic *string `json:"patronymic,omitempty" example:"Sergeevich" validate:"max=100,name"`
+	// CountryHint — страна, для которой запрашиваются возраст и пол.
+	CountryHint *string `json:"country_hint,omitempty" example:"RU" validate:"country"`
+	// Age, Gender и Nationality задаются вручную и защищаются от повторного
+	// обогащения; снять защиту можно параметром unlock.
+	Age         *int    `json:"age,omitempty" example:"30" validate:"min=0,max=150"`
+	Gender      *string `json:"gender,omitempty" example:"male" validate:"nonempty,oneof=male female"`
+	Nationality *string `json:"nationality,omitempty" example:"RU" validate:"nonempty,country"`
 }
 
 type UserResponse struct {
-	ID          int     `json:"id" example:"1"`
-	Name        string  `json:"name" example:"Dmitriy"`
-	Surname     string  `json:"surname" example:"Ivanov"`
-	Patronymic  *string `json:"patronymic,omitempty" example:"Sergeevich"`
-	Age         *int    `json:"age,omitempty" example:"30"`
-	Gender      *string `json:"gender,omitempty" example:"male"`
-	Nationality *string `json:"nationality,omitempty" example:"RU"`
+	ID                int             `json:"id" example:"1"`
+	Name              string          `json:"name" example:"Dmitriy"`
+	Surname           string          `json:"surname" example:"Ivanov"`
+	Patronymic        *string         `json:"patronymic,omitempty" example:"Sergeevich"`
+	Age               *int            `json:"age,omitempty" example:"30"`
+	Gender            *string         `json:"gender,omitempty" example:"male"`
+	Nationality       *string         `json:"nationality,omitempty" example:"RU"`
+	CountryHint       *string         `json:"country_hint,omitempty" example:"RU"`
+	PendingEnrichment []string        `json:"pending_enrichment,omitempty" example:"gender"`
+	LockedAttributes  []string        `json:"locked_attributes,omitempty" example:"gender"`
+	EnrichmentStatus  string          `json:"enrichment_status,omitempty" example:"complete"`
+	Enrichment        *EnrichmentInfo `json:"enrichment,omitempty"`
+	// Version — версия записи, та же, что в ETag.
+	Version int `json:"version" example:"3"`
+}
+
+type EnrichmentInfo struct {
+	Age         *AttributeInfo `json:"age,omitempty"`
+	Gender      *AttributeInfo `json:"gender,omitempty"`
+	Nationality *AttributeInfo `json:"nationality,omitempty"`
+}
+
+type AttributeInfo struct {
+	Provider    string        `json:"provider" example:"genderize"`
+	Probability *float64      `json:"probability,omitempty" example:"0.99"`
+	Count       int           `json:"count" example:"1250"`
+	Countries   []CountryInfo `json:"countries,omitempty"`
+	CountryID   string        `json:"country_id,omitempty" example:"RU"`
+	// Rule — правило локального провайдера (patronymic, surname).
+	Rule      string    `json:"rule,omitempty" example:"patronymic"`
+	FetchedAt time.Time `json:"fetched_at"`
+	// LowConfidence — ответ провайдера ниже порога достоверности, атрибут не заполнен.
+	LowConfidence bool `json:"low_confidence,omitempty"`
+}
+
+type CountryInfo struct {
+	CountryID   string  `json:"country_id" example:"RU"`
+	Probability float64 `json:"probability" example:"0.42"`
 }
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"Effective_Mobile/internal/storage"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var InvalidIfMatch = errors.New("invalid If-Match header")
+
+// ETag возвращает сильный ETag для версии записи.
+func ETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// SetETag выставляет ETag, если версия известна.
+func SetETag(w http.ResponseWriter, version int) {
+	if version > 0 {
+		w.Header().Set("ETag", ETag(version))
+	}
+}
+
+// IfMatch разбирает If-Match и возвращает версии из сильных тегов. nil —
+// заголовка нет или он равен "*": подойдёт любая существующая версия. По
+// RFC 9110 If-Match сравнивает теги строго, поэтому слабые теги (W/"3") и
+// теги, которые сервер не выдавал, не совпадают ни с одной версией; если
+// других нет, сразу возвращается ErrVersionConflict.
+func IfMatch(r *http.Request) ([]int, error) {
+	const op = "httpserver.handlers.ifMatch"
+
+	header := strings.TrimSpace(strings.Join(r.Header.Values("If-Match"), ","))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	tags, err := parseETags(header)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, Invalid(InvalidIfMatch, "If-Match", err.Error()))
+	}
+
+	versions := make([]int, 0, len(tags))
+	for _, tag := range tags {
+		if tag.weak {
+			continue
+		}
+		if version, err := strconv.Atoi(tag.opaque); err == nil && version > 0 && strconv.Itoa(version) == tag.opaque {
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrVersionConflict)
+	}
+	return versions, nil
+}
+
+// MatchVersion сообщает, удовлетворяет ли версия условию из IfMatch.
+func MatchVersion(versions []int, version int) bool {
+	return versions == nil || slices.Contains(versions, version)
+}
+
+type etag struct {
+	weak   bool
+	opaque string
+}
+
+// parseETags разбирает список тегов через запятую: "1", W/"2".
+func parseETags(header string) ([]etag, error) {
+	var tags []etag
+	for rest := header; ; {
+		rest = strings.TrimLeft(rest, ", \t")
+		if rest == "" {
+			break
+		}
+
+		var tag etag
+		if strings.HasPrefix(rest, "W/") {
+			tag.weak = true
+			rest = rest[2:]
+		}
+		if !strings.HasPrefix(rest, `"`) {
+			return nil, errors.New("must be * or a list of quoted entity tags")
+		}
+		end := strings.IndexByte(rest[1:], '"')
+		if end < 0 {
+			return nil, errors.New("has an unterminated entity tag")
+		}
+		tag.opaque = rest[1 : end+1]
+		tags = append(tags, tag)
+
+		rest = strings.TrimLeft(rest[end+2:], " \t")
+		if rest == "" {
+			break
+		}
+		if rest[0] != ',' {
+			return nil, errors.New("must be * or a list of quoted entity tags")
+		}
+		rest = rest[1:]
+	}
+	if len(tags) == 0 {
+		return nil, errors.New("must be * or a list of quoted entity tags")
+	}
+	return tags, nil
+}
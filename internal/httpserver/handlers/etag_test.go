@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"Effective_Mobile/internal/storage"
+	"errors"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   []string
+		versions []int
+		err      error
+	}{
+		{"no header", nil, nil, nil},
+		{"any version", []string{"*"}, nil, nil},
+		{"one tag", []string{`"3"`}, []int{3}, nil},
+		{"tag list", []string{`"9", "2"`}, []int{9, 2}, nil},
+		{"several headers", []string{`"9"`, `"2"`}, []int{9, 2}, nil},
+		{"weak tag skipped", []string{`W/"3", "4"`}, []int{4}, nil},
+		{"only weak tags", []string{`W/"3"`}, nil, storage.ErrVersionConflict},
+		{"foreign tag", []string{`"abc"`}, nil, storage.ErrVersionConflict},
+		{"leading zero", []string{`"03"`}, nil, storage.ErrVersionConflict},
+		{"unquoted", []string{`3`}, nil, InvalidIfMatch},
+		{"unterminated", []string{`"3`}, nil, InvalidIfMatch},
+		{"no comma", []string{`"3" "4"`}, nil, InvalidIfMatch},
+		{"only commas", []string{`, ,`}, nil, InvalidIfMatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PATCH", "/people/1", nil)
+			for _, h := range tt.header {
+				r.Header.Add("If-Match", h)
+			}
+
+			versions, err := IfMatch(r)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if !slices.Equal(versions, tt.versions) || (versions == nil) != (tt.versions == nil) {
+				t.Fatalf("got versions %#v, want %#v", versions, tt.versions)
+			}
+		})
+	}
+}
+
+func TestMatchVersion(t *testing.T) {
+	if !MatchVersion(nil, 7) {
+		t.Error("no If-Match did not match")
+	}
+	if !MatchVersion([]int{2, 7}, 7) || MatchVersion([]int{2}, 7) {
+		t.Error("version list compared wrongly")
+	}
+}
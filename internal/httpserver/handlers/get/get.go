@@ -5,7 +5,9 @@ import (
 	"Effective_Mobile/internal/httpserver/handlers/dto"
 	"Effective_Mobile/internal/logger"
 	"Effective_Mobile/internal/model"
-	"Effective_Mobile/internal/storage/pg"
+	"Effective_Mobile/internal/storage"
+	"Effective_Mobile/lib/normalize"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -14,14 +16,14 @@ import (
 )
 
 type Getter interface {
-	List(params *pg.ListParam) ([]*model.User, error)
+	List(ctx context.Context, params *storage.ListParam) ([]*model.User, error)
 }
 
 // @Summary Получить список людей
 // @Description Возвращает список людей с поддержкой фильтрации по полям и пагинации
 // @Tags people
 // @Accept json
-// @Produce json
+// @Produce json,application/problem+json
 // @Param id query int false "ID пользователя"
 // @Param name query string false "Имя"
 // @Param surname query string false "Фамилия"
@@ -32,8 +34,9 @@ type Getter interface {
 // @Param limit query int false "Максимальное количество записей"
 // @Param offset query int false "Смещение (offset) для пагинации"
 // @Success 200 {array} dto.UserResponse
-// @Failure 400 {object} dto.ErrorResponse
-// @Failure 500 {object} dto.ErrorResponse
+// @Failure 400 {object} dto.Problem
+// @Failure 500 {object} dto.Problem
+// @Failure 504 {object} dto.Problem
 // @Router /people [get]
 func New(getter Getter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -43,7 +46,7 @@ func New(getter Getter) http.HandlerFunc {
 
 		if http.MethodGet != r.Method {
 			logger.Error("%s: method not allowed: %s", op, r.Method)
-			handlers.WriteError(w, http.StatusMethodNotAllowed, fmt.Sprintf("%s: %s", op, handlers.MethodNotAllowed))
+			handlers.RespondError(w, r, op, handlers.MethodNotAllowed)
 			return
 		}
 
@@ -52,15 +55,15 @@ func New(getter Getter) http.HandlerFunc {
 		params, err := getParams(rows)
 		if err != nil {
 			logger.Error("%s: invalid query params: %v", op, err)
-			handlers.WriteError(w, http.StatusBadRequest, fmt.Sprintf("%s: %s", op, err.Error()))
+			handlers.RespondError(w, r, op, err)
 			return
 		}
 		logger.Debug("%s: parsed params: %+v", op, params)
 
-		users, err := getter.List(params)
+		users, err := getter.List(r.Context(), params)
 		if err != nil {
 			logger.Error("%s: failed to list users: %v", op, err)
-			handlers.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("%s: %s", op, err.Error()))
+			handlers.RespondError(w, r, op, err)
 			return
 		}
 		logger.Info("%s: successfully retrieved %d users", op, len(users))
@@ -74,7 +77,7 @@ func New(getter Getter) http.HandlerFunc {
 		response, err := json.Marshal(dtoUsers)
 		if err != nil {
 			logger.Error("%s: failed to marshal users: %v", op, err)
-			handlers.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("%s: %s", op, err.Error()))
+			handlers.RespondError(w, r, op, err)
 			return
 		}
 		logger.Debug("%s: response payload: %s", op, response)
@@ -85,17 +88,75 @@ func New(getter Getter) http.HandlerFunc {
 	}
 }
 
-func getParams(rows url.Values) (*pg.ListParam, error) {
+// @Summary Получить человека
+// @Description Возвращает человека по ID. Заголовок ETag содержит версию записи для If-Match
+// @Description в PUT, PATCH и DELETE.
+// @Tags people
+// @Produce json,application/problem+json
+// @Param id path int true "ID пользователя"
+// @Success 200 {object} dto.UserResponse
+// @Header 200 {string} ETag "Версия записи"
+// @Failure 400 {object} dto.Problem
+// @Failure 404 {object} dto.Problem
+// @Failure 500 {object} dto.Problem
+// @Failure 504 {object} dto.Problem
+// @Router /people/{id} [get]
+func NewByID(getter Getter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "httpserver.handlers.get.newByID"
+
+		logger.Debug("%s: incoming %s request on %s", op, r.Method, r.URL.Path)
+
+		if http.MethodGet != r.Method {
+			logger.Error("%s: method not allowed: %s", op, r.Method)
+			handlers.RespondError(w, r, op, handlers.MethodNotAllowed)
+			return
+		}
+
+		id, err := handlers.GetID(r.URL.Path)
+		if err != nil {
+			logger.Error("%s: failed to extract ID from URL: %v", op, err)
+			handlers.RespondError(w, r, op, err)
+			return
+		}
+
+		users, err := getter.List(r.Context(), &storage.ListParam{User: model.User{ID: id}})
+		if err != nil {
+			logger.Error("%s: failed to get user %d: %v", op, id, err)
+			handlers.RespondError(w, r, op, err)
+			return
+		}
+		if len(users) == 0 {
+			logger.Debug("%s: user %d not found", op, id)
+			handlers.RespondError(w, r, op, handlers.UserNotFound)
+			return
+		}
+
+		response, err := json.Marshal(toDTO(users[0]))
+		if err != nil {
+			logger.Error("%s: failed to marshal user: %v", op, err)
+			handlers.RespondError(w, r, op, err)
+			return
+		}
+
+		handlers.SetETag(w, users[0].Version)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(response)
+	}
+}
+
+func getParams(rows url.Values) (*storage.ListParam, error) {
 	const op = "httpserver.handlers.get.getParams"
 
-	params := &pg.ListParam{}
+	params := &storage.ListParam{}
 	user := &model.User{}
 
 	if limitStr := rows.Get("limit"); limitStr != "" {
 		limit, err := strconv.Atoi(limitStr)
 		if err != nil {
 			logger.Error("%s: invalid limit: %v", op, err)
-			return nil, fmt.Errorf("%s: %w", op, err)
+			return nil, fmt.Errorf("%s: %w", op, handlers.Invalid(handlers.InvalidQuery, "limit", "must be an integer"))
 		}
 		params.Limit = limit
 		logger.Debug("%s: parsed limit: %d", op, limit)
@@ -105,7 +166,7 @@ func getParams(rows url.Values) (*pg.ListParam, error) {
 		offset, err := strconv.Atoi(offsetStr)
 		if err != nil {
 			logger.Error("%s: invalid offset: %v", op, err)
-			return nil, fmt.Errorf("%s: %w", op, err)
+			return nil, fmt.Errorf("%s: %w", op, handlers.Invalid(handlers.InvalidQuery, "offset", "must be an integer"))
 		}
 		params.Offset = offset
 		logger.Debug("%s: parsed offset: %d", op, offset)
@@ -115,23 +176,24 @@ func getParams(rows url.Values) (*pg.ListParam, error) {
 		ID, err := strconv.Atoi(IDStr)
 		if err != nil {
 			logger.Error("%s: invalid id: %v", op, err)
-			return nil, fmt.Errorf("%s: %w", op, err)
+			return nil, fmt.Errorf("%s: %w", op, handlers.Invalid(handlers.InvalidQuery, "id", "must be an integer"))
 		}
 		user.ID = ID
 		logger.Debug("%s: parsed id: %d", op, ID)
 	}
 
 	if name := rows.Get("name"); name != "" {
-		user.Name = name
+		user.NameKey = normalize.Key(name)
 		logger.Debug("%s: parsed name: %s", op, name)
 	}
 
 	if surname := rows.Get("surname"); surname != "" {
-		user.Surname = surname
+		user.SurnameKey = normalize.Key(surname)
 		logger.Debug("%s: parsed surname: %s", op, surname)
 	}
 
 	if patronymic := rows.Get("patronymic"); patronymic != "" {
+		patronymic = normalize.Clean(patronymic)
 		user.Patronymic = &patronymic
 		logger.Debug("%s: parsed patronymic: %s", op, patronymic)
 	}
@@ -140,7 +202,7 @@ func getParams(rows url.Values) (*pg.ListParam, error) {
 		age, err := strconv.Atoi(ageStr)
 		if err != nil {
 			logger.Error("%s: invalid age: %v", op, err)
-			return nil, fmt.Errorf("%s: %w", op, err)
+			return nil, fmt.Errorf("%s: %w", op, handlers.Invalid(handlers.InvalidQuery, "age", "must be an integer"))
 		}
 		user.Age = &age
 		logger.Debug("%s: parsed age: %d", op, age)
@@ -161,14 +223,59 @@ func getParams(rows url.Values) (*pg.ListParam, error) {
 	return params, nil
 }
 
+// toDTO возвращает человека для ответа; имена показываются с заглавной буквы
+// (см. normalize.Display).
 func toDTO(user *model.User) *dto.UserResponse {
+	var patronymic *string
+	if user.Patronymic != nil {
+		p := normalize.Display(*user.Patronymic)
+		patronymic = &p
+	}
+
 	return &dto.UserResponse{
-		ID:          user.ID,
-		Name:        user.Name,
-		Surname:     user.Surname,
-		Patronymic:  user.Patronymic,
-		Age:         user.Age,
-		Gender:      user.Gender,
-		Nationality: user.Nationality,
+		ID:                user.ID,
+		Name:              normalize.Display(user.Name),
+		Surname:           normalize.Display(user.Surname),
+		Patronymic:        patronymic,
+		Age:               user.Age,
+		Gender:            user.Gender,
+		Nationality:       user.Nationality,
+		CountryHint:       user.CountryHint,
+		PendingEnrichment: user.PendingEnrichment,
+		LockedAttributes:  user.Locked,
+		EnrichmentStatus:  user.EnrichmentStatus,
+		Enrichment:        enrichmentToDTO(user.Enrichment),
+		Version:           user.Version,
+	}
+}
+
+func enrichmentToDTO(e *model.Enrichment) *dto.EnrichmentInfo {
+	if e == nil {
+		return nil
+	}
+	return &dto.EnrichmentInfo{
+		Age:         attributionToDTO(e.Age),
+		Gender:      attributionToDTO(e.Gender),
+		Nationality: attributionToDTO(e.Nationality),
+	}
+}
+
+func attributionToDTO(a *model.Attribution) *dto.AttributeInfo {
+	if a == nil {
+		return nil
+	}
+
+	info := &dto.AttributeInfo{
+		Provider:      a.Provider,
+		Probability:   a.Probability,
+		Count:         a.Count,
+		CountryID:     a.CountryID,
+		Rule:          a.Rule,
+		FetchedAt:     a.FetchedAt,
+		LowConfidence: a.LowConfidence,
+	}
+	for _, c := range a.Countries {
+		info.Countries = append(info.Countries, dto.CountryInfo{CountryID: c.CountryID, Probability: c.Probability})
 	}
+	return info
 }
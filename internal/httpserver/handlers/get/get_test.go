@@ -0,0 +1,107 @@
+package get
+
+import (
+	"Effective_Mobile/internal/httpserver/handlers"
+	"Effective_Mobile/internal/httpserver/handlers/dto"
+	"Effective_Mobile/internal/httpserver/handlers/handlertest"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/storage/memory"
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func add(t *testing.T, repo *memory.Storage, user model.User) int {
+	t.Helper()
+
+	id, err := repo.Add(context.Background(), user)
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	return id
+}
+
+func TestGetByIDShowsProvenance(t *testing.T) {
+	repo := memory.New()
+	fetched := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	gender, nationality := "male", "RU"
+	probability, top := 0.99, 0.8
+	id := add(t, repo, model.User{
+		Name: "Ivan", Surname: "Smith", NameKey: "ivan", SurnameKey: "smith",
+		Age: handlertest.IntPtr(47), Gender: &gender, Nationality: &nationality,
+		Enrichment: &model.Enrichment{
+			Age:    &model.Attribution{Provider: "agify", Count: 120, CountryID: "RU", FetchedAt: fetched},
+			Gender: &model.Attribution{Provider: "genderize", Probability: &probability, Count: 300, FetchedAt: fetched},
+			Nationality: &model.Attribution{Provider: "nationalize", Probability: &top, Count: 90, FetchedAt: fetched,
+				Countries: []model.Country{{CountryID: "RU", Probability: 0.8}, {CountryID: "UA", Probability: 0.1}}},
+		},
+	})
+
+	rec := handlertest.Do(t, NewByID(repo), http.MethodGet, "/people/"+strconv.Itoa(id), "")
+	var user dto.UserResponse
+	handlertest.AssertStatus(t, rec, http.StatusOK, &user)
+	if etag := rec.Header().Get("ETag"); etag != `"1"` || user.Version != 1 {
+		t.Errorf("got ETag %s, version %d, want 1", etag, user.Version)
+	}
+
+	e := user.Enrichment
+	if e == nil || e.Age == nil || e.Gender == nil || e.Nationality == nil {
+		t.Fatalf("got enrichment %+v, want all three attributes", e)
+	}
+	if e.Age.Provider != "agify" || e.Age.Count != 120 || e.Age.CountryID != "RU" || !e.Age.FetchedAt.Equal(fetched) {
+		t.Errorf("age: got %+v", e.Age)
+	}
+	if e.Gender.Provider != "genderize" || e.Gender.Probability == nil || *e.Gender.Probability != 0.99 ||
+		e.Gender.Count != 300 {
+		t.Errorf("gender: got %+v", e.Gender)
+	}
+	want := []dto.CountryInfo{{CountryID: "RU", Probability: 0.8}, {CountryID: "UA", Probability: 0.1}}
+	if len(e.Nationality.Countries) != 2 || e.Nationality.Countries[0] != want[0] || e.Nationality.Countries[1] != want[1] {
+		t.Errorf("nationality: got countries %+v, want the full distribution %+v", e.Nationality.Countries, want)
+	}
+}
+
+func TestGetByIDNotFound(t *testing.T) {
+	rec := handlertest.Do(t, NewByID(memory.New()), http.MethodGet, "/people/7", "")
+	problem := handlertest.AssertProblem(t, rec, http.StatusNotFound, handlers.CodeNotFound)
+	if problem.Instance != "/people/7" {
+		t.Errorf("got instance %q, want /people/7", problem.Instance)
+	}
+}
+
+func TestGetListFilters(t *testing.T) {
+	repo := memory.New()
+	add(t, repo, model.User{Name: "Ivan", Surname: "Smith", NameKey: "ivan", SurnameKey: "smith"})
+	anna := add(t, repo, model.User{Name: "Anna", Surname: "Smith", NameKey: "anna", SurnameKey: "smith"})
+
+	var users []dto.UserResponse
+	handlertest.AssertStatus(t, handlertest.Do(t, New(repo), http.MethodGet, "/people?name=ANNA", ""),
+		http.StatusOK, &users)
+	if len(users) != 1 || users[0].ID != anna {
+		t.Fatalf("got %+v, want only Anna", users)
+	}
+
+	rec := handlertest.Do(t, New(repo), http.MethodGet, "/people?limit=x", "")
+	handlertest.AssertProblem(t, rec, http.StatusBadRequest, handlers.CodeInvalidRequest)
+}
+
+func TestGetShowsDisplayNames(t *testing.T) {
+	repo := memory.New()
+	patronymic := "ivanovna"
+	id := add(t, repo, model.User{Name: "анна-мария", Surname: "McDonald", Patronymic: &patronymic,
+		NameKey: "anna-mariya", SurnameKey: "mcdonald"})
+
+	var user dto.UserResponse
+	handlertest.AssertStatus(t, handlertest.Do(t, NewByID(repo), http.MethodGet, "/people/"+strconv.Itoa(id), ""),
+		http.StatusOK, &user)
+	if user.Name != "Анна-Мария" || user.Surname != "McDonald" || *user.Patronymic != "Ivanovna" {
+		t.Errorf("got %q %q %q, want title-cased parts with inner capitals kept", user.Name, user.Surname, *user.Patronymic)
+	}
+
+	// хранится имя как введено
+	if stored := handlertest.Stored(t, repo, id); stored.Name != "анна-мария" || *stored.Patronymic != "ivanovna" {
+		t.Errorf("display casing leaked into storage: %q %q", stored.Name, *stored.Patronymic)
+	}
+}
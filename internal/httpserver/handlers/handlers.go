@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/lib/normalize"
 	"errors"
 	"fmt"
 	"strconv"
@@ -11,12 +13,48 @@ import (
 var (
 	InvalidPath      = errors.New("invalid path")
 	InvalidUserID    = errors.New("invalid user id")
+	InvalidBody      = errors.New("invalid request body")
+	BodyTooLarge     = errors.New("request body too large")
+	InvalidQuery     = errors.New("invalid query parameter")
 	UserNotFound     = errors.New("user not found")
+	RouteNotFound    = errors.New("route not found")
 	MethodNotAllowed = errors.New("method not allowed")
 )
 
+// NormalizeUser чистит пробелы и Unicode в имени, фамилии и отчестве и
+// заполняет ключи поиска. Регистр сохраняется как введён ("McDonald",
+// "d'Artagnan"), сравнение идёт по ключам. Пустые поля (не обновляемые в PUT)
+// не меняются.
+func NormalizeUser(user *model.User) {
+	if user.Name != "" {
+		user.Name = normalize.Clean(user.Name)
+		user.NameKey = normalize.Key(user.Name)
+	}
+	if user.Surname != "" {
+		user.Surname = normalize.Clean(user.Surname)
+		user.SurnameKey = normalize.Key(user.Surname)
+	}
+	if user.Patronymic != nil {
+		patronymic := normalize.Clean(*user.Patronymic)
+		user.Patronymic = &patronymic
+	}
+}
+
+// CountryCode приводит подсказку страны к коду ISO 3166-1 alpha-2 в верхнем
+// регистре. Пустая строка означает отсутствие подсказки.
+func CountryCode(country *string) *string {
+	if country == nil {
+		return nil
+	}
+	code := strings.ToUpper(strings.TrimSpace(*country))
+	if code == "" {
+		return nil
+	}
+	return &code
+}
+
 func GetID(path string) (int, error) {
-	const op = "httpserver.handlers.del.getID"
+	const op = "httpserver.handlers.getID"
 
 	parts := strings.Split(path, "/")
 	logger.Debug("%s: split path '%s' into %v", op, path, parts)
@@ -29,7 +67,7 @@ func GetID(path string) (int, error) {
 	id, err := strconv.Atoi(parts[2])
 	if err != nil {
 		logger.Error("%s: failed to convert id '%s': %v", op, parts[2], err)
-		return -1, fmt.Errorf("%s: %w", op, InvalidUserID)
+		return -1, fmt.Errorf("%s: %w", op, Invalid(InvalidUserID, "id", "must be an integer"))
 	}
 
 	logger.Debug("%s: extracted id: %d", op, id)
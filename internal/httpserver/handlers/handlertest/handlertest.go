@@ -0,0 +1,197 @@
+// Package handlertest собирает обогащение поверх fakeenrich и проверяет ответы
+// обработчиков в тестах пакетов handlers/*.
+package handlertest
+
+import (
+	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/fakeenrich"
+	"Effective_Mobile/internal/httpserver/handlers"
+	"Effective_Mobile/internal/httpserver/handlers/dto"
+	"Effective_Mobile/internal/httpserver/middleware/requestid"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/service/enrichment"
+	"Effective_Mobile/internal/storage"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/caarlos0/env/v6"
+)
+
+// Fixtures — ответы fake-сервера: Иван и Анна из России, Джон из США.
+var Fixtures = []enrichment.DatasetEntry{
+	{Name: "ivan", Count: 100, Gender: "male", GenderProbability: 0.99, Age: IntPtr(47),
+		Countries: []model.Country{{CountryID: "RU", Probability: 0.8}}},
+	{Name: "anna", Count: 100, Gender: "female", GenderProbability: 0.98, Age: IntPtr(35),
+		Countries: []model.Country{{CountryID: "RU", Probability: 0.6}}},
+	{Name: "john", Count: 100, Gender: "male", GenderProbability: 0.97, Age: IntPtr(52),
+		Countries: []model.Country{{CountryID: "US", Probability: 0.5}}},
+}
+
+type Options struct {
+	// Configure меняет настройки обогащения после подключения к fake-серверу.
+	Configure func(cfg *config.Enrichment)
+	Faults    fakeenrich.Faults
+	// Local — дополнительные провайдеры в реестре, например offline.
+	Local []enrichment.Provider
+}
+
+// Enrichment — сервис обогащения, который ходит в fake-сервер.
+type Enrichment struct {
+	*enrichment.Service
+	Fake   *fakeenrich.Server
+	Config config.Enrichment
+}
+
+// NewEnrichment запускает fake-сервер на время теста. Настройки берутся по
+// умолчанию, но без кэша и повторов, чтобы число запросов было предсказуемым.
+func NewEnrichment(t *testing.T, opts Options) *Enrichment {
+	t.Helper()
+
+	var cfg config.Enrichment
+	if err := env.Parse(&cfg, env.Options{Prefix: "ENRICH_"}); err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	cfg.CacheEnabled = false
+	cfg.RetryMax = 0
+
+	fake := fakeenrich.New(enrichment.NewOffline(Fixtures), opts.Faults)
+	upstream := httptest.NewServer(fake)
+	t.Cleanup(upstream.Close)
+
+	fakeenrich.Configure(&cfg, upstream.URL)
+	if opts.Configure != nil {
+		opts.Configure(&cfg)
+	}
+
+	registry := enrichment.DefaultRegistry(enrichment.NewHTTPClient(cfg), cfg)
+	for _, p := range opts.Local {
+		registry.Register(p)
+	}
+
+	var cache *enrichment.Cache
+	if cfg.CacheEnabled {
+		cache = enrichment.NewCache(cfg.CacheSize, cfg.CacheTTL, nil)
+	}
+
+	service, err := enrichment.NewFromConfig(cfg, registry, cache, enrichment.NewLimiter(cfg, nil))
+	if err != nil {
+		t.Fatalf("enrichment: %v", err)
+	}
+	return &Enrichment{Service: service, Fake: fake, Config: cfg}
+}
+
+// Do выполняет запрос к обработчику h за middleware requestid. header —
+// пары имя, значение.
+func Do(t *testing.T, h http.HandlerFunc, method, target, body string, header ...string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return Serve(h, NewRequest(method, target, body, header...))
+}
+
+// NewRequest создаёт запрос с телом JSON; header — пары имя, значение.
+func NewRequest(method, target, body string, header ...string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Add(header[i], header[i+1])
+	}
+	return req
+}
+
+// Serve передаёт req обработчику h за middleware requestid.
+func Serve(h http.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	requestid.Middleware(h)(rec, req)
+	return rec
+}
+
+// Decode разбирает JSON из тела ответа в v.
+func Decode(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+}
+
+// AssertStatus проверяет код ответа и разбирает тело в v, если v не nil.
+func AssertStatus(t *testing.T, rec *httptest.ResponseRecorder, status int, v any) {
+	t.Helper()
+
+	if rec.Code != status {
+		t.Fatalf("got status %d, want %d: %s", rec.Code, status, rec.Body)
+	}
+	if v != nil {
+		Decode(t, rec, v)
+	}
+}
+
+// AssertProblem проверяет ответ application/problem+json с кодом ошибки code
+// и идентификатором запроса из заголовка X-Request-ID.
+func AssertProblem(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) dto.Problem {
+	t.Helper()
+
+	if rec.Code != status {
+		t.Fatalf("got status %d, want %d: %s", rec.Code, status, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("got Content-Type %q, want application/problem+json", ct)
+	}
+
+	var problem dto.Problem
+	Decode(t, rec, &problem)
+	if problem.Code != code || problem.Status != status || problem.Type != handlers.ProblemTypeBase+code {
+		t.Fatalf("got problem %+v, want code %s and status %d", problem, code, status)
+	}
+	if id := rec.Header().Get(requestid.Header); problem.RequestID == "" || problem.RequestID != id {
+		t.Fatalf("problem request_id %q does not match %s header %q", problem.RequestID, requestid.Header, id)
+	}
+	return problem
+}
+
+// Stored читает сохранённого человека в обход обработчиков.
+func Stored(t *testing.T, repo storage.Repository, id int) *model.User {
+	t.Helper()
+
+	users, err := repo.List(context.Background(), &storage.ListParam{User: model.User{ID: id}})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(users) != 1 {
+		t.Fatalf("person %d not found", id)
+	}
+	return users[0]
+}
+
+// AssertAttributes проверяет обогащённые возраст, пол и национальность.
+func AssertAttributes(t *testing.T, user *model.User, age int, gender, nationality string) {
+	t.Helper()
+
+	if user.Age == nil || *user.Age != age {
+		t.Errorf("got age %v, want %d", Value(user.Age), age)
+	}
+	if user.Gender == nil || *user.Gender != gender {
+		t.Errorf("got gender %v, want %s", Value(user.Gender), gender)
+	}
+	if user.Nationality == nil || *user.Nationality != nationality {
+		t.Errorf("got nationality %v, want %s", Value(user.Nationality), nationality)
+	}
+}
+
+func IntPtr(v int) *int {
+	return &v
+}
+
+// Value показывает значение указателя в сообщениях об ошибке.
+func Value[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
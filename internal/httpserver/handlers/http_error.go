@@ -2,12 +2,132 @@ package handlers
 
 import (
 	"Effective_Mobile/internal/httpserver/handlers/dto"
+	"Effective_Mobile/internal/httpserver/middleware/requestid"
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/service/enrichment"
+	"Effective_Mobile/internal/storage"
+	"context"
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 )
 
-func WriteError(w http.ResponseWriter, status int, msg string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(dto.ErrorResponse{Error: msg})
+// StatusClientClosedRequest — нестандартный код (nginx) для запросов, прерванных клиентом.
+const StatusClientClosedRequest = 499
+
+// ProblemTypeBase — префикс поля type в ответах с ошибкой, дальше идёт код ошибки.
+const ProblemTypeBase = "/problems/"
+
+// Коды ошибок в dto.Problem. Клиенты опираются на них, а не на текст
+// сообщения, поэтому менять существующие коды нельзя.
+const (
+	CodeInvalidRequest      = "invalid_request"
+	CodeNotFound            = "not_found"
+	CodeMethodNotAllowed    = "method_not_allowed"
+	CodeBodyTooLarge        = "body_too_large"
+	CodeAlreadyExists       = "already_exists"
+	CodeConflict            = "conflict"
+	CodeVersionConflict     = "version_conflict"
+	CodeNothingToUpdate     = "nothing_to_update"
+	CodeProviderBudget      = "provider_budget_exhausted"
+	CodeProviderUnavailable = "provider_unavailable"
+	CodeProviderFailed      = "provider_failed"
+	CodeTimeout             = "timeout"
+	CodeCanceled            = "canceled"
+	CodeInternal            = "internal_error"
+)
+
+// errorMapping переводит ошибки слоёв хранения и обогащения в ответы HTTP.
+// detail уходит клиенту вместо текста ошибки, который остаётся в логах.
+// Порядок важен: ошибка провайдера может оборачивать исчерпанный бюджет или
+// истёкший дедлайн, и тогда побеждает более точная строка.
+var errorMapping = []struct {
+	err    error
+	status int
+	code   string
+	detail string
+}{
+	{InvalidPath, http.StatusBadRequest, CodeInvalidRequest, "invalid path"},
+	{InvalidUserID, http.StatusBadRequest, CodeInvalidRequest, "person id must be an integer"},
+	{InvalidBody, http.StatusBadRequest, CodeInvalidRequest, "request body is invalid"},
+	{BodyTooLarge, http.StatusRequestEntityTooLarge, CodeBodyTooLarge, "request body is too large"},
+	{InvalidQuery, http.StatusBadRequest, CodeInvalidRequest, "query parameters are invalid"},
+	{InvalidIfMatch, http.StatusBadRequest, CodeInvalidRequest, "If-Match must be * or a list of entity tags"},
+	{UserNotFound, http.StatusNotFound, CodeNotFound, "person not found"},
+	{RouteNotFound, http.StatusNotFound, CodeNotFound, "no such endpoint"},
+	{storage.ErrUserNotFound, http.StatusNotFound, CodeNotFound, "person not found"},
+	{MethodNotAllowed, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method is not allowed for this endpoint"},
+	{storage.ErrUserExists, http.StatusConflict, CodeAlreadyExists, "a person with this name and surname already exists"},
+	{storage.ErrTxConflict, http.StatusConflict, CodeConflict, "the person was modified concurrently, retry the request"},
+	{storage.ErrVersionConflict, http.StatusPreconditionFailed, CodeVersionConflict, "the person was modified since it was read"},
+	{storage.ErrNothingUpdate, http.StatusUnprocessableEntity, CodeNothingToUpdate, "request contains nothing to update"},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, CodeTimeout, "request timed out"},
+	{context.Canceled, StatusClientClosedRequest, CodeCanceled, "request was canceled"},
+	{enrichment.ErrBudgetExhausted, http.StatusServiceUnavailable, CodeProviderBudget, "enrichment provider request limit reached, retry later"},
+	{enrichment.ErrCircuitOpen, http.StatusServiceUnavailable, CodeProviderUnavailable, "enrichment provider is temporarily unavailable"},
+	{enrichment.ErrUpstream, http.StatusBadGateway, CodeProviderFailed, "enrichment provider returned an error"},
+}
+
+// Classify возвращает код ответа, код ошибки и безопасное для клиента описание.
+// Всё, что не описано в errorMapping, считается внутренней ошибкой.
+func Classify(err error) (int, string, string) {
+	for _, m := range errorMapping {
+		if errors.Is(err, m.err) {
+			return m.status, m.code, m.detail
+		}
+	}
+	return http.StatusInternalServerError, CodeInternal, "internal server error"
+}
+
+// RespondError пишет ответ application/problem+json для ошибки err, возникшей
+// в op. Текст err со всей цепочкой вызовов попадает только в лог.
+func RespondError(w http.ResponseWriter, r *http.Request, op string, err error) {
+	status, code, detail := Classify(err)
+	id := requestid.FromContext(r.Context())
+
+	if status >= http.StatusInternalServerError {
+		logger.Error("%s: request %s failed with %d: %v", op, id, status, err)
+	} else {
+		logger.Info("%s: request %s rejected with %d: %v", op, id, status, err)
+	}
+
+	problem := dto.Problem{
+		Type:      ProblemTypeBase + code,
+		Title:     statusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: id,
+	}
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		problem.Errors = validationErr.Fields
+	}
+
+	SetRetryAfter(w, err)
+	WriteProblem(w, problem)
+}
+
+func WriteProblem(w http.ResponseWriter, problem dto.Problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	_ = json.NewEncoder(w).Encode(problem)
+}
+
+// SetRetryAfter выставляет Retry-After, если запрос отклонён лимитом провайдера.
+func SetRetryAfter(w http.ResponseWriter, err error) {
+	var budgetErr *enrichment.BudgetError
+	if errors.As(err, &budgetErr) && budgetErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(budgetErr.RetryAfter.Seconds()))))
+	}
+}
+
+func statusText(status int) string {
+	if status == StatusClientClosedRequest {
+		return "Client Closed Request"
+	}
+	return http.StatusText(status)
 }
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"Effective_Mobile/internal/service/enrichment"
+	"Effective_Mobile/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	// обёртка, как у ошибок из слоёв хранения и обогащения
+	wrap := func(err error) error { return fmt.Errorf("storage.pg.update: %w", err) }
+	budget := &enrichment.BudgetError{Provider: "agify", RetryAfter: time.Second}
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"invalid id", wrap(InvalidUserID), http.StatusBadRequest, CodeInvalidRequest},
+		{"field errors", Invalid(InvalidBody, "age", "must be between 0 and 150"), http.StatusBadRequest, CodeInvalidRequest},
+		{"invalid If-Match", Invalid(InvalidIfMatch, "If-Match", "x"), http.StatusBadRequest, CodeInvalidRequest},
+		{"body too large", BodyTooLarge, http.StatusRequestEntityTooLarge, CodeBodyTooLarge},
+		{"person not found", wrap(storage.ErrUserNotFound), http.StatusNotFound, CodeNotFound},
+		{"unknown route", RouteNotFound, http.StatusNotFound, CodeNotFound},
+		{"method", MethodNotAllowed, http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+		{"duplicate", wrap(storage.ErrUserExists), http.StatusConflict, CodeAlreadyExists},
+		{"transaction conflict", wrap(storage.ErrTxConflict), http.StatusConflict, CodeConflict},
+		{"stale version", wrap(storage.ErrVersionConflict), http.StatusPreconditionFailed, CodeVersionConflict},
+		{"nothing to update", wrap(storage.ErrNothingUpdate), http.StatusUnprocessableEntity, CodeNothingToUpdate},
+		{"deadline", wrap(context.DeadlineExceeded), http.StatusGatewayTimeout, CodeTimeout},
+		{"canceled", wrap(context.Canceled), StatusClientClosedRequest, CodeCanceled},
+		{"budget", budget, http.StatusServiceUnavailable, CodeProviderBudget},
+		{"breaker", enrichment.ErrCircuitOpen, http.StatusServiceUnavailable, CodeProviderUnavailable},
+		{"provider error", fmt.Errorf("%w: %w", enrichment.ErrUpstream, errors.New("502")), http.StatusBadGateway, CodeProviderFailed},
+		// ошибка провайдера с более точной причиной
+		{"provider timeout", fmt.Errorf("%w: %w", enrichment.ErrUpstream, context.DeadlineExceeded), http.StatusGatewayTimeout, CodeTimeout},
+		{"provider budget", fmt.Errorf("%w: %w", enrichment.ErrUpstream, budget), http.StatusServiceUnavailable, CodeProviderBudget},
+		{"unknown", errors.New("pq: connection refused"), http.StatusInternalServerError, CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code, detail := Classify(tt.err)
+			if status != tt.status || code != tt.code {
+				t.Fatalf("got %d %s, want %d %s", status, code, tt.status, tt.code)
+			}
+			if detail == "" || detail == tt.err.Error() {
+				t.Fatalf("got detail %q, want a safe description instead of the error text", detail)
+			}
+		})
+	}
+}
+
+func TestSetRetryAfter(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"rounded up", &enrichment.BudgetError{RetryAfter: 1200 * time.Millisecond}, "2"},
+		{"wrapped", fmt.Errorf("enrich: %w", &enrichment.BudgetError{RetryAfter: 3 * time.Second}), "3"},
+		{"no delay", &enrichment.BudgetError{}, ""},
+		{"other error", storage.ErrTxConflict, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			SetRetryAfter(rec, tt.err)
+			if got := rec.Header().Get("Retry-After"); got != tt.want {
+				t.Fatalf("got Retry-After %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"Effective_Mobile/internal/httpserver/handlers/dto"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/service/enrichment"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ApplyOverrides переносит заданные вручную атрибуты в пользователя с
+// источником manual и возвращает их список для блокировки. Значения уже
+// проверены в DecodeBody.
+func ApplyOverrides(user *model.User, req dto.UserRequest) []string {
+	if user.Enrichment == nil {
+		user.Enrichment = &model.Enrichment{}
+	}
+	manual := func() *model.Attribution {
+		return &model.Attribution{Provider: model.ProviderManual, FetchedAt: time.Now().UTC()}
+	}
+
+	var locked []string
+
+	if req.Age != nil {
+		user.Age = req.Age
+		user.Enrichment.Age = manual()
+		locked = append(locked, enrichment.AttrAge)
+	}
+
+	if req.Gender != nil {
+		user.Gender = req.Gender
+		user.Enrichment.Gender = manual()
+		locked = append(locked, enrichment.AttrGender)
+	}
+
+	if req.Nationality != nil {
+		user.Nationality = CountryCode(req.Nationality)
+		user.Enrichment.Nationality = manual()
+		locked = append(locked, enrichment.AttrNationality)
+	}
+
+	if len(locked) == 0 {
+		user.Enrichment = nil
+	}
+	return locked
+}
+
+// Force читает параметр запроса force: атрибуты без защиты обогащаются заново,
+// даже если имя и подсказка страны не изменились. Защиту force не снимает.
+func Force(r *http.Request) (bool, error) {
+	const op = "httpserver.handlers.force"
+
+	v := r.URL.Query().Get("force")
+	if v == "" {
+		return false, nil
+	}
+
+	force, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, Invalid(InvalidQuery, "force", "must be a boolean"))
+	}
+	return force, nil
+}
+
+// Unlock читает параметр запроса unlock: атрибуты через запятую или all. С
+// перечисленных атрибутов снимается защита, и они обогащаются заново.
+func Unlock(r *http.Request) ([]string, error) {
+	const op = "httpserver.handlers.unlock"
+
+	v := strings.TrimSpace(r.URL.Query().Get("unlock"))
+	if v == "" {
+		return nil, nil
+	}
+
+	all := []string{enrichment.AttrAge, enrichment.AttrGender, enrichment.AttrNationality}
+	if v == "all" {
+		return all, nil
+	}
+
+	var unlock []string
+	for _, attr := range strings.Split(v, ",") {
+		attr = strings.TrimSpace(attr)
+		if !slices.Contains(all, attr) {
+			return nil, fmt.Errorf("%s: %w", op, Invalid(InvalidQuery, "unlock",
+				"must be all or a comma-separated list of age, gender, nationality"))
+		}
+		if !slices.Contains(unlock, attr) {
+			unlock = append(unlock, attr)
+		}
+	}
+	return unlock, nil
+}
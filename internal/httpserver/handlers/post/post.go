@@ -6,26 +6,40 @@ import (
 	"Effective_Mobile/internal/logger"
 	"Effective_Mobile/internal/model"
 	"Effective_Mobile/internal/service/enrichment"
+	"Effective_Mobile/internal/storage"
+	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
+	"slices"
 )
 
-type Poster interface {
-	Add(user model.User) (int, error)
+type Enricher interface {
+	Enrich(ctx context.Context, user *model.User) (enrichment.Report, error)
 }
 
+// New создаёт обработчик POST /people. При async человек сохраняется сразу,
+// а обогащение выполняется в фоне: запись и задача в очереди создаются в одной
+// транзакции, поэтому человек не остаётся в pending_enrichment без задачи.
 // @Summary Добавить нового пользователя
-// @Description Создаёт нового пользователя и возвращает его ID
+// @Description Создаёт нового пользователя и возвращает его ID. Переданные age, gender и
+// @Description nationality сохраняются как заданные вручную и не обогащаются.
+// @Description При ENRICH_ASYNC=true человек сохраняется сразу со статусом pending_enrichment
+// @Description и ответом 202, а обогащение выполняется в фоне (см. GET /people/{id}/enrichment).
 // @Tags people
 // @Accept json
-// @Produce json
+// @Produce json,application/problem+json
 // @Param user body dto.UserRequest true "Информация о пользователе"
-// @Success 201 {object} dto.Response
-// @Failure 400 {object} dto.ErrorResponse
-// @Failure 500 {object} dto.ErrorResponse
+// @Success 201 {object} dto.Response "Человек создан и обогащён"
+// @Success 202 {object} dto.Response "Человек создан, обогащение поставлено в очередь"
+// @Failure 400 {object} dto.Problem
+// @Failure 409 {object} dto.Problem
+// @Failure 413 {object} dto.Problem
+// @Failure 500 {object} dto.Problem
+// @Failure 502 {object} dto.Problem
+// @Failure 503 {object} dto.Problem
+// @Failure 504 {object} dto.Problem
 // @Router /people [post]
-func New(poster Poster) http.HandlerFunc {
+func New(tx storage.Transactor, enricher Enricher, async bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "httpserver.handlers.post.new"
 
@@ -33,7 +47,7 @@ func New(poster Poster) http.HandlerFunc {
 
 		if http.MethodPost != r.Method {
 			logger.Error("%s: method not allowed: %s", op, r.Method)
-			http.Error(w, fmt.Sprintf("%s: %s", op, handlers.MethodNotAllowed), http.StatusMethodNotAllowed)
+			handlers.RespondError(w, r, op, handlers.MethodNotAllowed)
 			return
 		}
 
@@ -49,51 +63,90 @@ func New(poster Poster) http.HandlerFunc {
 
 		var req dto.UserRequest
 
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if err := handlers.DecodeBody(r, &req, handlers.Full); err != nil {
 			logger.Error("%s: failed to decode request body: %v", op, err)
-			handlers.WriteError(w, http.StatusBadRequest, fmt.Sprintf("%s: %s", op, err.Error()))
+			handlers.RespondError(w, r, op, err)
 			return
 		}
 
 		user := model.User{
-			Name:       req.Name,
-			Surname:    req.Surname,
-			Patronymic: req.Patronymic,
+			Name:        req.Name,
+			Surname:     req.Surname,
+			Patronymic:  req.Patronymic,
+			CountryHint: handlers.CountryCode(req.CountryHint),
 		}
+		handlers.NormalizeUser(&user)
+
+		locked := handlers.ApplyOverrides(&user, req)
+		user.Locked = locked
 
 		logger.Debug("%s: decoded user: %+v", op, user)
 
-		err := enrichment.Enrich(&user)
-		if err != nil {
-			logger.Error("%s: enrichment failed: %v", op, err)
-			handlers.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("%s: %s", op, err.Error()))
-			return
+		var (
+			report enrichment.Report
+			err    error
+		)
+		if !async {
+			report, err = enricher.Enrich(r.Context(), &user)
+			if err != nil {
+				logger.Error("%s: enrichment failed: %v", op, err)
+				handlers.RespondError(w, r, op, err)
+				return
+			}
+
+			logger.Debug("%s: enriched user: %+v", op, user)
+		} else {
+			user.EnrichmentStatus = model.EnrichmentPending
+			user.PendingEnrichment = make([]string, 0, 3)
+			for _, attr := range []string{enrichment.AttrAge, enrichment.AttrGender, enrichment.AttrNationality} {
+				if !slices.Contains(locked, attr) {
+					user.PendingEnrichment = append(user.PendingEnrichment, attr)
+				}
+			}
 		}
 
-		logger.Debug("%s: enriched user: %+v", op, user)
-
-		id, err := poster.Add(user)
+		var id int
+		err = tx.WithTx(r.Context(), func(repo storage.Repo) error {
+			id, err = repo.Add(r.Context(), user)
+			if err != nil {
+				logger.Error("%s: failed to add user: %v", op, err)
+				return err
+			}
+			if async {
+				if err = repo.Enqueue(r.Context(), id); err != nil {
+					logger.Error("%s: failed to enqueue enrichment for user %d: %v", op, id, err)
+					return err
+				}
+			}
+			return nil
+		})
 		if err != nil {
-			logger.Error("%s: failed to add user: %v", op, err)
-			handlers.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("%s: %s", op, err.Error()))
+			handlers.RespondError(w, r, op, err)
 			return
 		}
 		logger.Info("%s: user added with id %d", op, id)
 
+		status := http.StatusCreated
 		response := dto.Response{
-			ID:      id,
-			Message: "user added",
+			ID:         id,
+			Message:    "user added",
+			Enrichment: report.Strings(),
+		}
+
+		if async {
+			status = http.StatusAccepted
+			response.Message = "user added, enrichment pending"
 		}
 
 		responseJson, err := json.Marshal(&response)
 		if err != nil {
 			logger.Error("%s: failed to marshal response: %v", op, err)
-			handlers.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("%s: %s", op, err.Error()))
+			handlers.RespondError(w, r, op, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
+		w.WriteHeader(status)
 		w.Write(responseJson)
 		logger.Debug("%s: response written: %s", op, string(responseJson))
 	}
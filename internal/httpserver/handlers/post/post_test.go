@@ -0,0 +1,291 @@
+package post
+
+import (
+	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/fakeenrich"
+	"Effective_Mobile/internal/httpserver/handlers"
+	"Effective_Mobile/internal/httpserver/handlers/dto"
+	"Effective_Mobile/internal/httpserver/handlers/handlertest"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/service/enrichment"
+	"Effective_Mobile/internal/storage"
+	"Effective_Mobile/internal/storage/memory"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func create(t *testing.T, h http.HandlerFunc, body string, status int) dto.Response {
+	t.Helper()
+
+	var resp dto.Response
+	handlertest.AssertStatus(t, handlertest.Do(t, h, http.MethodPost, "/people", body), status, &resp)
+	return resp
+}
+
+func TestPostEnrichesPerson(t *testing.T) {
+	repo := memory.New()
+	h := New(repo, handlertest.NewEnrichment(t, handlertest.Options{}), false)
+
+	resp := create(t, h, `{"name":"Ivan","surname":"Smith"}`, http.StatusCreated)
+	for _, attr := range []string{enrichment.AttrAge, enrichment.AttrGender, enrichment.AttrNationality} {
+		if resp.Enrichment[attr] != string(enrichment.StatusSucceeded) {
+			t.Errorf("enrichment of %s: got %q, want succeeded", attr, resp.Enrichment[attr])
+		}
+	}
+
+	user := handlertest.Stored(t, repo, resp.ID)
+	handlertest.AssertAttributes(t, user, 47, "male", "RU")
+	if user.Version != 1 {
+		t.Errorf("got version %d, want 1", user.Version)
+	}
+}
+
+func TestPostDuplicateConflict(t *testing.T) {
+	repo := memory.New()
+	h := New(repo, handlertest.NewEnrichment(t, handlertest.Options{}), false)
+
+	create(t, h, `{"name":"Ivan","surname":"Smith"}`, http.StatusCreated)
+	// ключи имени и фамилии не зависят от регистра
+	rec := handlertest.Do(t, h, http.MethodPost, "/people", `{"name":"ivan","surname":"SMITH"}`)
+	problem := handlertest.AssertProblem(t, rec, http.StatusConflict, handlers.CodeAlreadyExists)
+	if problem.Instance != "/people" {
+		t.Errorf("got instance %q, want /people", problem.Instance)
+	}
+}
+
+func TestPostValidation(t *testing.T) {
+	repo := memory.New()
+	enricher := handlertest.NewEnrichment(t, handlertest.Options{})
+	h := New(repo, enricher, false)
+
+	tests := []struct {
+		name   string
+		body   string
+		fields []string
+	}{
+		{"field errors", `{"name":"","surname":"Smith","age":200}`, []string{"name", "age"}},
+		{"bad country", `{"name":"Ivan","surname":"Smith","country_hint":"Russia"}`, []string{"country_hint"}},
+		{"digits in name", `{"name":"Iv4n","surname":"Smith"}`, []string{"name"}},
+		{"unknown field", `{"name":"Ivan","surname":"Smith","email":"x"}`, nil},
+		{"trailing data", `{"name":"Ivan","surname":"Smith"} {}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := handlertest.Do(t, h, http.MethodPost, "/people", tt.body)
+			problem := handlertest.AssertProblem(t, rec, http.StatusBadRequest, handlers.CodeInvalidRequest)
+
+			fields := make(map[string]bool, len(problem.Errors))
+			for _, e := range problem.Errors {
+				fields[e.Field] = true
+			}
+			for _, f := range tt.fields {
+				if !fields[f] {
+					t.Errorf("got field errors %+v, want %s", problem.Errors, f)
+				}
+			}
+		})
+	}
+
+	if n := enricher.Fake.Requests(); n != 0 {
+		t.Errorf("invalid requests made %d provider requests, want 0", n)
+	}
+	if users, _ := repo.List(context.Background(), &storage.ListParam{}); len(users) != 0 {
+		t.Errorf("invalid requests stored %d people", len(users))
+	}
+}
+
+func TestPostStopsWhenClientGoesAway(t *testing.T) {
+	repo := memory.New()
+	enricher := handlertest.NewEnrichment(t, handlertest.Options{Faults: fakeenrich.Faults{Latency: 5 * time.Second}})
+	h := New(repo, enricher, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer time.AfterFunc(50*time.Millisecond, cancel).Stop()
+	req := handlertest.NewRequest(http.MethodPost, "/people", `{"name":"Ivan","surname":"Smith"}`).WithContext(ctx)
+
+	started := time.Now()
+	rec := handlertest.Serve(h, req)
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("handler returned after %s, want it to stop with the request context", elapsed)
+	}
+	handlertest.AssertProblem(t, rec, handlers.StatusClientClosedRequest, handlers.CodeCanceled)
+
+	users, err := repo.List(context.Background(), &storage.ListParam{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(users) != 0 {
+		t.Errorf("canceled request stored %d people", len(users))
+	}
+}
+
+func TestPostUsesCache(t *testing.T) {
+	repo := memory.New()
+	enricher := handlertest.NewEnrichment(t, handlertest.Options{Configure: func(cfg *config.Enrichment) {
+		cfg.CacheEnabled = true
+	}})
+	h := New(repo, enricher, false)
+
+	create(t, h, `{"name":"Ivan","surname":"Smith"}`, http.StatusCreated)
+	requests := enricher.Fake.Requests()
+	if requests == 0 {
+		t.Fatal("first POST made no provider requests")
+	}
+
+	resp := create(t, h, `{"name":"ivan","surname":"Jones"}`, http.StatusCreated)
+	if n := enricher.Fake.Requests(); n != requests {
+		t.Errorf("same first name made %d more provider requests, want 0", n-requests)
+	}
+	handlertest.AssertAttributes(t, handlertest.Stored(t, repo, resp.ID), 47, "male", "RU")
+}
+
+func TestPostProviderFailureOpensBreaker(t *testing.T) {
+	repo := memory.New()
+	enricher := handlertest.NewEnrichment(t, handlertest.Options{
+		Faults: fakeenrich.Faults{ErrorEvery: 1},
+		Configure: func(cfg *config.Enrichment) {
+			cfg.Partial = false
+			cfg.BreakerThreshold = 1
+		},
+	})
+	h := New(repo, enricher, false)
+
+	rec := handlertest.Do(t, h, http.MethodPost, "/people", `{"name":"Ivan","surname":"Smith"}`)
+	handlertest.AssertProblem(t, rec, http.StatusBadGateway, handlers.CodeProviderFailed)
+
+	requests := enricher.Fake.Requests()
+	rec = handlertest.Do(t, h, http.MethodPost, "/people", `{"name":"Ivan","surname":"Smith"}`)
+	handlertest.AssertProblem(t, rec, http.StatusServiceUnavailable, handlers.CodeProviderUnavailable)
+	if n := enricher.Fake.Requests(); n != requests {
+		t.Errorf("open breaker let %d requests through", n-requests)
+	}
+
+	users, err := repo.List(context.Background(), &storage.ListParam{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(users) != 0 {
+		t.Errorf("failed enrichment stored %d people", len(users))
+	}
+}
+
+func TestPostAsyncEnqueuesJob(t *testing.T) {
+	repo := memory.New()
+	enricher := handlertest.NewEnrichment(t, handlertest.Options{})
+	h := New(repo, enricher, true)
+
+	resp := create(t, h, `{"name":"Ivan","surname":"Smith","age":30}`, http.StatusAccepted)
+
+	jobs, err := repo.Jobs(context.Background(), resp.ID)
+	if err != nil {
+		t.Fatalf("Jobs: %v", err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("got %d jobs, want 1", len(jobs))
+	}
+	if n := enricher.Fake.Requests(); n != 0 {
+		t.Errorf("async POST made %d provider requests, want 0", n)
+	}
+
+	user := handlertest.Stored(t, repo, resp.ID)
+	if user.EnrichmentStatus != model.EnrichmentPending {
+		t.Errorf("got enrichment status %q, want %q", user.EnrichmentStatus, model.EnrichmentPending)
+	}
+	if strings.Join(user.PendingEnrichment, ",") != "gender,nationality" {
+		t.Errorf("got pending %v, want gender and nationality: age was set manually", user.PendingEnrichment)
+	}
+}
+
+func TestPostStoresCountryHint(t *testing.T) {
+	repo := memory.New()
+	enricher := handlertest.NewEnrichment(t, handlertest.Options{})
+	h := New(repo, enricher, false)
+
+	resp := create(t, h, `{"name":"Ivan","surname":"Smith","country_hint":"us"}`, http.StatusCreated)
+
+	user := handlertest.Stored(t, repo, resp.ID)
+	if user.CountryHint == nil || *user.CountryHint != "US" {
+		t.Errorf("got country hint %v, want US", handlertest.Value(user.CountryHint))
+	}
+	if a := user.Enrichment.Age; a == nil || a.CountryID != "US" {
+		t.Errorf("got age provenance %+v, want the answer localized to US", a)
+	}
+}
+
+func TestPostFallsBackToNextProvider(t *testing.T) {
+	// offline знает только Анну, поэтому возраст Ивана берётся из agify
+	offline := enrichment.NewOffline([]enrichment.DatasetEntry{
+		{Name: "anna", Count: 10, Age: handlertest.IntPtr(29)},
+	})
+	repo := memory.New()
+	enricher := handlertest.NewEnrichment(t, handlertest.Options{
+		Local: []enrichment.Provider{offline},
+		Configure: func(cfg *config.Enrichment) {
+			cfg.AgeProviders = []string{"offline", "agify"}
+		},
+	})
+	h := New(repo, enricher, false)
+
+	ivan := create(t, h, `{"name":"Ivan","surname":"Smith"}`, http.StatusCreated)
+	anna := create(t, h, `{"name":"Anna","surname":"Smith"}`, http.StatusCreated)
+
+	user := handlertest.Stored(t, repo, ivan.ID)
+	handlertest.AssertAttributes(t, user, 47, "male", "RU")
+	if src := user.Enrichment.Age.Provider; src != "agify" {
+		t.Errorf("Ivan's age came from %q, want agify", src)
+	}
+
+	user = handlertest.Stored(t, repo, anna.ID)
+	handlertest.AssertAttributes(t, user, 29, "female", "RU")
+	if src := user.Enrichment.Age.Provider; src != "offline" {
+		t.Errorf("Anna's age came from %q, want offline", src)
+	}
+}
+
+func TestPostStrictOfflineMissIsLowConfidence(t *testing.T) {
+	offline := enrichment.NewOffline([]enrichment.DatasetEntry{
+		{Name: "anna", Count: 10, Age: handlertest.IntPtr(29)},
+	})
+	repo := memory.New()
+	enricher := handlertest.NewEnrichment(t, handlertest.Options{
+		Local: []enrichment.Provider{offline},
+		Configure: func(cfg *config.Enrichment) {
+			cfg.Partial = false
+			cfg.AgeProviders = []string{"offline"}
+		},
+	})
+	h := New(repo, enricher, false)
+
+	resp := create(t, h, `{"name":"Ivan","surname":"Smith"}`, http.StatusCreated)
+	if resp.Enrichment[enrichment.AttrAge] != string(enrichment.StatusLowConfidence) {
+		t.Errorf("got age %q, want low_confidence", resp.Enrichment[enrichment.AttrAge])
+	}
+	if resp.Enrichment[enrichment.AttrGender] != string(enrichment.StatusSucceeded) {
+		t.Errorf("got gender %q, want the miss not to cancel other providers", resp.Enrichment[enrichment.AttrGender])
+	}
+
+	user := handlertest.Stored(t, repo, resp.ID)
+	if user.Age != nil || user.EnrichmentStatus != model.EnrichmentComplete {
+		t.Errorf("got age %v, status %q", handlertest.Value(user.Age), user.EnrichmentStatus)
+	}
+}
+
+func TestPostRateLimitRejects(t *testing.T) {
+	repo := memory.New()
+	enricher := handlertest.NewEnrichment(t, handlertest.Options{Configure: func(cfg *config.Enrichment) {
+		cfg.RateLimitPolicy = enrichment.PolicyReject
+		cfg.Agify.DailyQuota = 1
+	}})
+	h := New(repo, enricher, false)
+
+	create(t, h, `{"name":"Ivan","surname":"Smith"}`, http.StatusCreated)
+
+	rec := handlertest.Do(t, h, http.MethodPost, "/people", `{"name":"Anna","surname":"Smith"}`)
+	handlertest.AssertProblem(t, rec, http.StatusServiceUnavailable, handlers.CodeProviderBudget)
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("no Retry-After on an exhausted daily quota")
+	}
+}
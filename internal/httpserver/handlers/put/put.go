@@ -4,39 +4,59 @@ import (
 	"Effective_Mobile/internal/httpserver/handlers"
 	"Effective_Mobile/internal/httpserver/handlers/dto"
 	"Effective_Mobile/internal/httpserver/handlers/get"
+	"Effective_Mobile/internal/httpserver/handlers/post"
 	"Effective_Mobile/internal/logger"
 	"Effective_Mobile/internal/model"
 	"Effective_Mobile/internal/service/enrichment"
-	"Effective_Mobile/internal/storage/pg"
+	"Effective_Mobile/internal/storage"
+	"Effective_Mobile/lib/normalize"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 )
 
-type Putter interface {
-	Update(id int, user *model.User) error
-}
-
+// New создаёт обработчик PUT и PATCH /people/{id}. Обогащение выполняется вне
+// транзакции; в транзакции запись перечитывается, и если за это время
+// изменились имя, подсказка страны или защита атрибутов, запрос завершается
+// конфликтом 409, а не записывает устаревшие ответы провайдеров.
 // @Summary Обновить пользователя
-// @Description Обновляет данные пользователя по ID
+// @Description Обновляет данные пользователя по ID. Переданные age, gender и nationality
+// @Description сохраняются как заданные вручную и не перезаписываются обогащением.
+// @Description С заголовком If-Match запись обновляется, только если ETag совпадает с текущей
+// @Description версией; новый ETag возвращается в ответе.
 // @Tags people
 // @Accept json
-// @Produce json
+// @Produce json,application/problem+json
 // @Param id path int true "ID пользователя"
+// @Param If-Match header string false "ETag из GET /people/{id}, * или несколько тегов через запятую; слабые теги (W/) не совпадают"
+// @Param force query bool false "Обогатить заново атрибуты без защиты, даже если имя не изменилось"
+// @Param unlock query string false "Снять защиту и обогатить заново: all или атрибуты через запятую (age,gender,nationality)"
 // @Param user body dto.UserRequest true "Обновлённая информация о пользователе"
 // @Success 200 {object} dto.Response
-// @Failure 400 {object} dto.ErrorResponse
-// @Failure 500 {object} dto.ErrorResponse
+// @Header 200 {string} ETag "Новая версия записи"
+// @Failure 400 {object} dto.Problem
+// @Failure 404 {object} dto.Problem
+// @Failure 409 {object} dto.Problem
+// @Failure 412 {object} dto.Problem
+// @Failure 422 {object} dto.Problem
+// @Failure 413 {object} dto.Problem
+// @Failure 500 {object} dto.Problem
+// @Failure 502 {object} dto.Problem
+// @Failure 503 {object} dto.Problem
+// @Failure 504 {object} dto.Problem
 // @Router /people/{id} [put]
-func New(putter Putter, getter get.Getter) http.HandlerFunc {
+// @Router /people/{id} [patch]
+func New(repo get.Getter, tx storage.Transactor, enricher post.Enricher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "httpserver.handlers.put.new"
 
 		logger.Debug("%s: incoming %s request on %s", op, r.Method, r.URL.Path)
 
-		if http.MethodPut != r.Method {
+		if http.MethodPut != r.Method && http.MethodPatch != r.Method {
 			logger.Error("%s: method not allowed: %s", op, r.Method)
-			handlers.WriteError(w, http.StatusMethodNotAllowed, fmt.Sprintf("%s: %s", op, handlers.MethodNotAllowed))
+			handlers.RespondError(w, r, op, handlers.MethodNotAllowed)
 			return
 		}
 
@@ -53,67 +73,151 @@ func New(putter Putter, getter get.Getter) http.HandlerFunc {
 		id, err := handlers.GetID(r.URL.Path)
 		if err != nil {
 			logger.Error("%s: failed to parse id: %v", op, err)
-			handlers.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("%s: %s", op, err.Error()))
+			handlers.RespondError(w, r, op, err)
 			return
 		}
 		logger.Debug("%s: extracted id: %d", op, id)
 
+		versions, err := handlers.IfMatch(r)
+		if err != nil {
+			logger.Error("%s: invalid If-Match: %v", op, err)
+			handlers.RespondError(w, r, op, err)
+			return
+		}
+
 		var req dto.UserRequest
 
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if err := handlers.DecodeBody(r, &req, handlers.Partial); err != nil {
 			logger.Error("%s: failed to decode request body: %v", op, err)
-			handlers.WriteError(w, http.StatusBadRequest, fmt.Sprintf("%s: %s", op, err.Error()))
+			handlers.RespondError(w, r, op, err)
 			return
 		}
 
-		user := model.User{
-			Name:       req.Name,
-			Surname:    req.Surname,
-			Patronymic: req.Patronymic,
+		base := model.User{
+			Name:        req.Name,
+			Surname:     req.Surname,
+			Patronymic:  req.Patronymic,
+			CountryHint: handlers.CountryCode(req.CountryHint),
 		}
+		handlers.NormalizeUser(&base)
+
+		logger.Debug("%s: decoded user: %+v", op, base)
+
+		manual := handlers.ApplyOverrides(&base, req)
 
-		logger.Debug("%s: decoded user: %+v", op, user)
+		force, err := handlers.Force(r)
+		if err != nil {
+			logger.Error("%s: invalid force parameter: %v", op, err)
+			handlers.RespondError(w, r, op, err)
+			return
+		}
+
+		unlock, err := handlers.Unlock(r)
+		if err != nil {
+			logger.Error("%s: invalid unlock parameter: %v", op, err)
+			handlers.RespondError(w, r, op, err)
+			return
+		}
+		for _, attr := range unlock {
+			if slices.Contains(manual, attr) {
+				err = fmt.Errorf("%s: %w", op, handlers.Invalid(handlers.InvalidQuery, "unlock", attr+" is set in the request body"))
+				logger.Error("%s: invalid unlock parameter: %v", op, err)
+				handlers.RespondError(w, r, op, err)
+				return
+			}
+		}
 
-		ok, err := existName(id, user.Name, getter)
+		current, err := loadUser(r.Context(), id, repo)
 		if err != nil {
-			logger.Error("%s: failed to check existing name: %v", op, err)
-			handlers.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("%s: %s", op, err.Error()))
+			logger.Error("%s: failed to load existing user: %v", op, err)
+			handlers.RespondError(w, r, op, err)
+			return
+		}
+		// не тратим запросы к провайдерам, если запись уже изменилась
+		if !handlers.MatchVersion(versions, current.Version) {
+			logger.Info("%s: user %d is at version %d, If-Match expects %v", op, id, current.Version, versions)
+			handlers.RespondError(w, r, op, storage.ErrVersionConflict)
 			return
 		}
 
-		if !ok {
-			logger.Debug("%s: name changed, enriching...", op)
-			err = enrichment.Enrich(&user)
+		// защита снимается только с атрибутов из unlock
+		locked := slices.DeleteFunc(lockedUnion(current.Locked, manual), func(attr string) bool {
+			return slices.Contains(unlock, attr)
+		})
+
+		user := base
+		var report enrichment.Report
+		if force || len(unlock) > 0 || changed(current, &user) {
+			logger.Debug("%s: identity or country hint changed (force: %t, unlock: %v), enriching...", op, force, unlock)
+			fillIdentity(&user, current, locked)
+			user.Locked = locked
+			report, err = enricher.Enrich(r.Context(), &user)
 			if err != nil {
 				logger.Error("%s: enrichment failed: %v", op, err)
-				handlers.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("%s: %s", op, err.Error()))
+				handlers.RespondError(w, r, op, err)
 				return
 			}
 			logger.Debug("%s: enriched user: %+v", op, user)
 		} else {
-			logger.Debug("%s: name unchanged, enrichment skipped", op)
+			logger.Debug("%s: identity and country hint unchanged, enrichment skipped", op)
+		}
+
+		user.Locked = nil
+		if len(manual) > 0 || len(unlock) > 0 {
+			user.Locked = locked
 		}
 
-		err = putter.Update(id, &user)
+		// обогащение идёт вне транзакции: в ней только проверяется, что запись
+		// не изменилась так, что ответы провайдеров к ней уже не относятся
+		var updated int
+		err = tx.WithTx(r.Context(), func(repo storage.Repo) error {
+			fresh, err := loadUser(r.Context(), id, repo)
+			if err != nil {
+				logger.Error("%s: failed to reload user: %v", op, err)
+				return err
+			}
+			if !handlers.MatchVersion(versions, fresh.Version) {
+				logger.Info("%s: user %d is at version %d, If-Match expects %v", op, id, fresh.Version, versions)
+				return storage.ErrVersionConflict
+			}
+			if !sameIdentity(current, fresh) {
+				logger.Info("%s: identity of user %d changed concurrently", op, id)
+				return storage.ErrTxConflict
+			}
+
+			// Update меняет версию в переданной записи, поэтому при повторе
+			// транзакции нужна свежая копия
+			update := user.Clone()
+			if versions != nil {
+				update.Version = fresh.Version
+			}
+			if err := repo.Update(r.Context(), id, &update); err != nil {
+				logger.Error("%s: failed to update user: %v", op, err)
+				return err
+			}
+			updated = update.Version
+			return nil
+		})
 		if err != nil {
-			logger.Error("%s: failed to update user: %v", op, err)
-			handlers.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("%s: %s", op, err.Error()))
+			handlers.RespondError(w, r, op, err)
 			return
 		}
 		logger.Info("%s: user %d updated", op, id)
 
 		response := dto.Response{
-			ID:      id,
-			Message: "user updated",
+			ID:         id,
+			Message:    "user updated",
+			Enrichment: report.Strings(),
 		}
 
 		responseJson, err := json.Marshal(&response)
 		if err != nil {
 			logger.Error("%s: failed to marshal response: %v", op, err)
-			handlers.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("%s: %s", op, err.Error()))
+			handlers.RespondError(w, r, op, err)
 			return
 		}
 
+		handlers.SetETag(w, updated)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		w.Write(responseJson)
@@ -121,22 +225,103 @@ func New(putter Putter, getter get.Getter) http.HandlerFunc {
 	}
 }
 
-func existName(id int, name string, getter get.Getter) (bool, error) {
-	const op = "httpserver.handlers.put.existName"
+func loadUser(ctx context.Context, id int, getter get.Getter) (*model.User, error) {
+	const op = "httpserver.handlers.put.loadUser"
 
-	users, err := getter.List(&pg.ListParam{User: model.User{ID: id}})
+	users, err := getter.List(ctx, &storage.ListParam{User: model.User{ID: id}})
 	if err != nil {
 		logger.Error("%s: failed to get user by id %d: %v", op, id, err)
-		return false, fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	if len(users) == 0 {
 		logger.Error("%s: user with id %d not found", op, id)
-		return false, fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
 	}
 
-	match := users[0].Name == name
-	logger.Debug("%s: existing name: %s, incoming name: %s, match: %t", op, users[0].Name, name, match)
+	return users[0], nil
+}
+
+// changed сообщает, отличаются ли имя, фамилия, отчество или подсказка страны
+// от сохранённых: только в этом случае нужно повторное обогащение. Фамилия и
+// отчество влияют на пол, определённый правилами patronymic.
+func changed(current, user *model.User) bool {
+	const op = "httpserver.handlers.put.changed"
+
+	if user.NameKey != "" && user.NameKey != current.NameKey {
+		logger.Debug("%s: name changed from %s to %s", op, current.Name, user.Name)
+		return true
+	}
+
+	if user.SurnameKey != "" && user.SurnameKey != current.SurnameKey {
+		logger.Debug("%s: surname changed from %s to %s", op, current.Surname, user.Surname)
+		return true
+	}
+
+	if user.Patronymic != nil && (current.Patronymic == nil || normalize.Key(*current.Patronymic) != normalize.Key(*user.Patronymic)) {
+		logger.Debug("%s: patronymic changed to %s", op, *user.Patronymic)
+		return true
+	}
+
+	if user.CountryHint != nil && (current.CountryHint == nil || *current.CountryHint != *user.CountryHint) {
+		logger.Debug("%s: country hint changed to %s", op, *user.CountryHint)
+		return true
+	}
+
+	return false
+}
+
+// fillIdentity дополняет запрос сохранёнными данными, нужными для обогащения:
+// имя, фамилию, отчество, подсказку страны и заданную вручную национальность.
+func fillIdentity(user, current *model.User, locked []string) {
+	if user.Name == "" {
+		user.Name, user.NameKey = current.Name, current.NameKey
+	}
+	if user.Surname == "" {
+		user.Surname, user.SurnameKey = current.Surname, current.SurnameKey
+	}
+	if user.Patronymic == nil {
+		user.Patronymic = current.Patronymic
+	}
+	if user.CountryHint == nil {
+		user.CountryHint = current.CountryHint
+	}
+	if user.Nationality == nil && slices.Contains(locked, enrichment.AttrNationality) {
+		user.Nationality = current.Nationality
+	}
+}
+
+// lockedUnion объединяет защищённые атрибуты; результат не nil, чтобы
+// Update записал и пустой список.
+// sameIdentity сообщает, совпадают ли у a и b данные, от которых зависит
+// обогащение: ключи имени и фамилии, отчество, подсказка страны, защищённые
+// атрибуты и заданная вручную национальность.
+func sameIdentity(a, b *model.User) bool {
+	if a.NameKey != b.NameKey || a.SurnameKey != b.SurnameKey {
+		return false
+	}
+	if patronymicKey(a) != patronymicKey(b) || deref(a.CountryHint) != deref(b.CountryHint) {
+		return false
+	}
+	if !slices.Equal(lockedUnion(a.Locked, nil), lockedUnion(b.Locked, nil)) {
+		return false
+	}
+	return !slices.Contains(a.Locked, enrichment.AttrNationality) || deref(a.Nationality) == deref(b.Nationality)
+}
+
+func patronymicKey(user *model.User) string {
+	return normalize.Key(deref(user.Patronymic))
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
 
-	return match, nil
+func lockedUnion(current, manual []string) []string {
+	locked := append(append([]string{}, current...), manual...)
+	slices.Sort(locked)
+	return slices.Compact(locked)
 }
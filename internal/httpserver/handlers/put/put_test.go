@@ -0,0 +1,279 @@
+package put
+
+import (
+	"Effective_Mobile/internal/httpserver/handlers"
+	"Effective_Mobile/internal/httpserver/handlers/dto"
+	"Effective_Mobile/internal/httpserver/handlers/handlertest"
+	"Effective_Mobile/internal/httpserver/handlers/post"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/service/enrichment"
+	"Effective_Mobile/internal/storage"
+	"Effective_Mobile/internal/storage/memory"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// create сохраняет человека через POST /people и возвращает путь к нему.
+func create(t *testing.T, repo *memory.Storage, enricher post.Enricher, body string) string {
+	t.Helper()
+
+	var resp dto.Response
+	rec := handlertest.Do(t, post.New(repo, enricher, false), http.MethodPost, "/people", body)
+	handlertest.AssertStatus(t, rec, http.StatusCreated, &resp)
+	return "/people/" + strconv.Itoa(resp.ID)
+}
+
+// patch выполняет PATCH и проверяет, что он прошёл.
+func patch(t *testing.T, h http.HandlerFunc, target, body string, header ...string) dto.Response {
+	t.Helper()
+
+	var resp dto.Response
+	handlertest.AssertStatus(t, handlertest.Do(t, h, http.MethodPatch, target, body, header...), http.StatusOK, &resp)
+	return resp
+}
+
+func TestPatchReEnrichesOnNameChange(t *testing.T) {
+	repo := memory.New()
+	enricher := handlertest.NewEnrichment(t, handlertest.Options{})
+	path := create(t, repo, enricher, `{"name":"Ivan","surname":"Smith"}`)
+	h := New(repo, repo, enricher)
+
+	rec := handlertest.Do(t, h, http.MethodPatch, path, `{"name":"Anna"}`, "If-Match", `"1"`)
+	handlertest.AssertStatus(t, rec, http.StatusOK, nil)
+	if etag := rec.Header().Get("ETag"); etag != `"2"` {
+		t.Errorf("got ETag %s, want \"2\"", etag)
+	}
+
+	user := handlertest.Stored(t, repo, 1)
+	if user.Name != "Anna" || user.Surname != "Smith" {
+		t.Errorf("got name %q %q, want Anna Smith", user.Name, user.Surname)
+	}
+	handlertest.AssertAttributes(t, user, 35, "female", "RU")
+}
+
+func TestPatchWithoutChangesSkipsEnrichment(t *testing.T) {
+	repo := memory.New()
+	enricher := handlertest.NewEnrichment(t, handlertest.Options{})
+	path := create(t, repo, enricher, `{"name":"Ivan","surname":"Smith"}`)
+	requests := enricher.Fake.Requests()
+
+	patch(t, New(repo, repo, enricher), path, `{"age":50}`)
+	if n := enricher.Fake.Requests(); n != requests {
+		t.Errorf("PATCH of a manual age made %d provider requests, want 0", n-requests)
+	}
+
+	user := handlertest.Stored(t, repo, 1)
+	if user.Age == nil || *user.Age != 50 {
+		t.Errorf("got age %v, want 50", handlertest.Value(user.Age))
+	}
+	if !slices.Equal(user.Locked, []string{enrichment.AttrAge}) {
+		t.Errorf("got locked %v, want age", user.Locked)
+	}
+}
+
+func TestPatchForceKeepsLocks(t *testing.T) {
+	repo := memory.New()
+	enricher := handlertest.NewEnrichment(t, handlertest.Options{})
+	path := create(t, repo, enricher, `{"name":"Ivan","surname":"Smith"}`)
+	h := New(repo, repo, enricher)
+	patch(t, h, path, `{"age":50}`)
+	requests := enricher.Fake.Requests()
+
+	// имя не менялось, но force обогащает заново всё, кроме защищённого возраста
+	resp := patch(t, h, path+"?force=true", `{}`)
+	if n := enricher.Fake.Requests() - requests; n != 2 {
+		t.Errorf("force made %d provider requests, want gender and nationality", n)
+	}
+	want := map[string]string{
+		enrichment.AttrAge:         string(enrichment.StatusLocked),
+		enrichment.AttrGender:      string(enrichment.StatusSucceeded),
+		enrichment.AttrNationality: string(enrichment.StatusSucceeded),
+	}
+	for attr, status := range want {
+		if resp.Enrichment[attr] != status {
+			t.Errorf("%s: got %q, want %q", attr, resp.Enrichment[attr], status)
+		}
+	}
+
+	user := handlertest.Stored(t, repo, 1)
+	handlertest.AssertAttributes(t, user, 50, "male", "RU")
+	if !slices.Equal(user.Locked, []string{enrichment.AttrAge}) {
+		t.Errorf("got locked %v, want age kept", user.Locked)
+	}
+}
+
+func TestPatchUnlock(t *testing.T) {
+	repo := memory.New()
+	enricher := handlertest.NewEnrichment(t, handlertest.Options{})
+	path := create(t, repo, enricher, `{"name":"Ivan","surname":"Smith"}`)
+	h := New(repo, repo, enricher)
+	patch(t, h, path, `{"age":50,"gender":"female"}`)
+
+	resp := patch(t, h, path+"?unlock=gender", `{}`)
+	if resp.Enrichment[enrichment.AttrGender] != string(enrichment.StatusSucceeded) ||
+		resp.Enrichment[enrichment.AttrAge] != string(enrichment.StatusLocked) {
+		t.Errorf("got enrichment %v, want gender enriched and age locked", resp.Enrichment)
+	}
+	user := handlertest.Stored(t, repo, 1)
+	handlertest.AssertAttributes(t, user, 50, "male", "RU")
+	if !slices.Equal(user.Locked, []string{enrichment.AttrAge}) {
+		t.Errorf("got locked %v, want only age", user.Locked)
+	}
+	if user.Enrichment.Gender == nil || user.Enrichment.Gender.Provider == model.ProviderManual {
+		t.Errorf("got gender provenance %+v, want the provider", user.Enrichment.Gender)
+	}
+
+	patch(t, h, path+"?unlock=all", `{}`)
+	user = handlertest.Stored(t, repo, 1)
+	handlertest.AssertAttributes(t, user, 47, "male", "RU")
+	if len(user.Locked) != 0 {
+		t.Errorf("got locked %v after unlock=all", user.Locked)
+	}
+}
+
+func TestPatchInvalidQuery(t *testing.T) {
+	repo := memory.New()
+	enricher := handlertest.NewEnrichment(t, handlertest.Options{})
+	path := create(t, repo, enricher, `{"name":"Ivan","surname":"Smith"}`)
+	h := New(repo, repo, enricher)
+	requests := enricher.Fake.Requests()
+
+	tests := []struct {
+		name  string
+		query string
+		body  string
+	}{
+		{"unknown attribute", "?unlock=height", `{}`},
+		{"all in a list", "?unlock=age,all", `{}`},
+		{"unlocking a manual value", "?unlock=age", `{"age":30}`},
+		{"force not a boolean", "?force=maybe", `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := handlertest.Do(t, h, http.MethodPatch, path+tt.query, tt.body)
+			handlertest.AssertProblem(t, rec, http.StatusBadRequest, handlers.CodeInvalidRequest)
+		})
+	}
+	if n := enricher.Fake.Requests(); n != requests {
+		t.Errorf("invalid requests made %d provider requests, want 0", n-requests)
+	}
+}
+
+// racingTx при первой попытке транзакции применяет change к хранилищу в обход
+// неё, как параллельный запрос, и заставляет хранилище повторить транзакцию.
+type racingTx struct {
+	*memory.Storage
+	id       int
+	change   model.User
+	attempts int
+}
+
+func (r *racingTx) WithTx(ctx context.Context, fn func(tx storage.Repo) error) error {
+	return r.Storage.WithTx(ctx, func(tx storage.Repo) error {
+		r.attempts++
+		if r.attempts == 1 {
+			change := r.change.Clone()
+			if err := r.Storage.Update(ctx, r.id, &change); err != nil {
+				return err
+			}
+		}
+		return fn(tx)
+	})
+}
+
+func TestPatchRetriesTransaction(t *testing.T) {
+	repo := memory.New()
+	enricher := handlertest.NewEnrichment(t, handlertest.Options{})
+	path := create(t, repo, enricher, `{"name":"Ivan","surname":"Smith"}`)
+
+	// параллельно меняется атрибут, от которого обогащение не зависит
+	racing := &racingTx{Storage: repo, id: 1, change: model.User{Age: handlertest.IntPtr(48)}}
+	patch(t, New(repo, racing, enricher), path, `{"name":"Anna"}`)
+	if racing.attempts != 2 {
+		t.Fatalf("transaction ran %d times, want 2", racing.attempts)
+	}
+
+	user := handlertest.Stored(t, repo, 1)
+	if user.Name != "Anna" {
+		t.Errorf("got name %q, want Anna", user.Name)
+	}
+	handlertest.AssertAttributes(t, user, 35, "female", "RU")
+	if user.Version != 3 {
+		t.Errorf("got version %d, want 3", user.Version)
+	}
+}
+
+func TestPatchConflictsOnConcurrentIdentityChange(t *testing.T) {
+	repo := memory.New()
+	enricher := handlertest.NewEnrichment(t, handlertest.Options{})
+	path := create(t, repo, enricher, `{"name":"Ivan","surname":"Smith"}`)
+
+	// пол Анны определён без отчества, а параллельный запрос его задал
+	patronymic := "Petrovich"
+	racing := &racingTx{Storage: repo, id: 1, change: model.User{Patronymic: &patronymic}}
+	rec := handlertest.Do(t, New(repo, racing, enricher), http.MethodPatch, path, `{"name":"Anna"}`)
+	handlertest.AssertProblem(t, rec, http.StatusConflict, handlers.CodeConflict)
+
+	user := handlertest.Stored(t, repo, 1)
+	if user.Name != "Ivan" || user.Patronymic == nil || *user.Patronymic != patronymic {
+		t.Errorf("got %q %v, want only the concurrent change", user.Name, handlertest.Value(user.Patronymic))
+	}
+	handlertest.AssertAttributes(t, user, 47, "male", "RU")
+}
+
+func TestPatchIfMatch(t *testing.T) {
+	repo := memory.New()
+	enricher := handlertest.NewEnrichment(t, handlertest.Options{})
+	path := create(t, repo, enricher, `{"name":"Ivan","surname":"Smith"}`)
+	h := New(repo, repo, enricher)
+
+	rec := handlertest.Do(t, h, http.MethodPatch, path, `{"age":40}`, "If-Match", `"1"`)
+	handlertest.AssertStatus(t, rec, http.StatusOK, nil)
+	if etag := rec.Header().Get("ETag"); etag != `"2"` {
+		t.Fatalf("got ETag %s, want \"2\"", etag)
+	}
+
+	tests := []struct {
+		name    string
+		ifMatch string
+		status  int
+		code    string
+	}{
+		{"stale version", `"1"`, http.StatusPreconditionFailed, handlers.CodeVersionConflict},
+		{"weak tag", `W/"2"`, http.StatusPreconditionFailed, handlers.CodeVersionConflict},
+		{"foreign tag", `"abc"`, http.StatusPreconditionFailed, handlers.CodeVersionConflict},
+		{"unquoted", `2`, http.StatusBadRequest, handlers.CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := handlertest.Do(t, h, http.MethodPatch, path, `{"age":41}`, "If-Match", tt.ifMatch)
+			handlertest.AssertProblem(t, rec, tt.status, tt.code)
+		})
+	}
+	if age := handlertest.Stored(t, repo, 1).Age; age == nil || *age != 40 {
+		t.Fatalf("failed preconditions changed the age to %v", handlertest.Value(age))
+	}
+
+	rec = handlertest.Do(t, h, http.MethodPatch, path, `{"age":42}`, "If-Match", `"9", "2"`)
+	handlertest.AssertStatus(t, rec, http.StatusOK, nil)
+	if etag := rec.Header().Get("ETag"); etag != `"3"` {
+		t.Fatalf("PATCH with a tag list: got ETag %s, want \"3\"", etag)
+	}
+	patch(t, h, path, `{"age":43}`, "If-Match", "*")
+}
+
+func TestPatchIfMatchSkipsEnrichmentOnStaleVersion(t *testing.T) {
+	repo := memory.New()
+	enricher := handlertest.NewEnrichment(t, handlertest.Options{})
+	path := create(t, repo, enricher, `{"name":"Ivan","surname":"Smith"}`)
+	requests := enricher.Fake.Requests()
+
+	rec := handlertest.Do(t, New(repo, repo, enricher), http.MethodPatch, path, `{"name":"Anna"}`, "If-Match", `"7"`)
+	handlertest.AssertProblem(t, rec, http.StatusPreconditionFailed, handlers.CodeVersionConflict)
+	if n := enricher.Fake.Requests(); n != requests {
+		t.Errorf("stale PATCH made %d provider requests, want 0", n-requests)
+	}
+}
@@ -0,0 +1,120 @@
+package status
+
+import (
+	"Effective_Mobile/internal/httpserver/handlers"
+	"Effective_Mobile/internal/httpserver/handlers/dto"
+	"Effective_Mobile/internal/httpserver/handlers/get"
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/storage"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type JobLister interface {
+	Jobs(ctx context.Context, personID int) ([]*model.EnrichmentJob, error)
+}
+
+type ChangeLister interface {
+	Changes(ctx context.Context, personID int) ([]model.EnrichmentChange, error)
+}
+
+// @Summary Статус обогащения
+// @Description Возвращает статус обогащения человека, историю фоновых задач и изменения атрибутов при повторном обогащении
+// @Tags people
+// @Produce json,application/problem+json
+// @Param id path int true "ID пользователя"
+// @Success 200 {object} dto.EnrichmentStatusResponse
+// @Failure 400 {object} dto.Problem
+// @Failure 404 {object} dto.Problem
+// @Failure 500 {object} dto.Problem
+// @Failure 504 {object} dto.Problem
+// @Router /people/{id}/enrichment [get]
+func New(getter get.Getter, jobs JobLister, changes ChangeLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "httpserver.handlers.status.new"
+
+		logger.Debug("%s: incoming %s request on %s", op, r.Method, r.URL.Path)
+
+		if http.MethodGet != r.Method {
+			logger.Error("%s: method not allowed: %s", op, r.Method)
+			handlers.RespondError(w, r, op, handlers.MethodNotAllowed)
+			return
+		}
+
+		id, err := handlers.GetID(strings.TrimSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/enrichment"))
+		if err != nil {
+			logger.Error("%s: failed to extract ID from URL: %v", op, err)
+			handlers.RespondError(w, r, op, err)
+			return
+		}
+
+		users, err := getter.List(r.Context(), &storage.ListParam{User: model.User{ID: id}})
+		if err != nil {
+			logger.Error("%s: failed to get user %d: %v", op, id, err)
+			handlers.RespondError(w, r, op, err)
+			return
+		}
+		if len(users) == 0 {
+			logger.Error("%s: user %d not found", op, id)
+			handlers.RespondError(w, r, op, handlers.UserNotFound)
+			return
+		}
+
+		userJobs, err := jobs.Jobs(r.Context(), id)
+		if err != nil {
+			logger.Error("%s: failed to list jobs for user %d: %v", op, id, err)
+			handlers.RespondError(w, r, op, err)
+			return
+		}
+
+		userChanges, err := changes.Changes(r.Context(), id)
+		if err != nil {
+			logger.Error("%s: failed to list changes for user %d: %v", op, id, err)
+			handlers.RespondError(w, r, op, err)
+			return
+		}
+
+		response := dto.EnrichmentStatusResponse{
+			ID:      id,
+			Status:  users[0].EnrichmentStatus,
+			Pending: users[0].PendingEnrichment,
+			Jobs:    make([]*dto.JobResponse, len(userJobs)),
+			Changes: make([]*dto.ChangeResponse, len(userChanges)),
+		}
+		for i, job := range userJobs {
+			response.Jobs[i] = &dto.JobResponse{
+				ID:        job.ID,
+				Status:    job.Status,
+				Attempts:  job.Attempts,
+				LastError: job.LastError,
+				RunAt:     job.RunAt,
+				UpdatedAt: job.UpdatedAt,
+			}
+		}
+
+		for i, change := range userChanges {
+			response.Changes[i] = &dto.ChangeResponse{
+				Attribute: change.Attribute,
+				OldValue:  change.OldValue,
+				NewValue:  change.NewValue,
+				Provider:  change.Provider,
+				ChangedAt: change.ChangedAt,
+			}
+		}
+
+		responseJson, err := json.Marshal(&response)
+		if err != nil {
+			logger.Error("%s: failed to marshal response: %v", op, err)
+			handlers.RespondError(w, r, op, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(responseJson)
+		logger.Debug("%s: response written: %s", op, string(responseJson))
+	}
+}
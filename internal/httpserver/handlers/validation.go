@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"Effective_Mobile/internal/httpserver/handlers/dto"
+	"Effective_Mobile/lib/validate"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ValidationError — запрос отклонён из-за значений отдельных полей. Kind —
+// одна из ошибок InvalidBody, InvalidQuery, InvalidOverride и т. п., по ней
+// выбирается код ответа.
+type ValidationError struct {
+	Kind   error
+	Fields []dto.FieldError
+}
+
+// Invalid сообщает о недопустимом значении поля field.
+func Invalid(kind error, field, message string) *ValidationError {
+	return &ValidationError{Kind: kind, Fields: []dto.FieldError{{Field: field, Message: message}}}
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = f.Field + " " + f.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Kind, strings.Join(fields, "; "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Kind
+}
+
+// Режимы проверки тела запроса в DecodeBody.
+const (
+	// Full — создание: обязательные поля должны быть заданы.
+	Full = false
+	// Partial — PUT и PATCH: незаданные поля не меняются.
+	Partial = true
+)
+
+// DecodeBody читает из тела запроса ровно один JSON-объект в v, отклоняя
+// неизвестные поля, и проверяет его по тегам validate. Ошибки формата
+// возвращаются как ValidationError без подробностей декодера.
+func DecodeBody(r *http.Request, v any, partial bool) error {
+	const op = "httpserver.handlers.decodeBody"
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(v)
+	if err == nil && dec.More() {
+		err = errTrailingData
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, decodeError(err))
+	}
+
+	errs, err := validate.Struct(v, partial)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if len(errs) > 0 {
+		fields := make([]dto.FieldError, len(errs))
+		for i, e := range errs {
+			fields[i] = dto.FieldError{Field: e.Field, Message: e.Message}
+		}
+		return fmt.Errorf("%s: %w", op, &ValidationError{Kind: InvalidBody, Fields: fields})
+	}
+	return nil
+}
+
+var errTrailingData = errors.New("unexpected data after JSON object")
+
+func decodeError(err error) error {
+	var (
+		typeErr    *json.UnmarshalTypeError
+		maxSizeErr *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &maxSizeErr):
+		return fmt.Errorf("%w: limit %d bytes", BodyTooLarge, maxSizeErr.Limit)
+	case errors.Is(err, io.EOF):
+		return fmt.Errorf("%w: empty body", InvalidBody)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return Invalid(InvalidBody, typeErr.Field, "has wrong type, expected "+typeErr.Type.Kind().String())
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		return Invalid(InvalidBody, field, "is not allowed")
+	default:
+		return fmt.Errorf("%w: %w", InvalidBody, err)
+	}
+}
@@ -2,28 +2,38 @@ package httpserver
 
 import (
 	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/httpserver/middleware/bodylimit"
+	"Effective_Mobile/internal/httpserver/middleware/requestid"
 	"Effective_Mobile/internal/httpserver/routes"
 	"Effective_Mobile/internal/logger"
 	"context"
+	"net"
 	"net/http"
 )
 
 type HTTPServer struct {
 	server *http.Server
+	cancel context.CancelFunc
 }
 
 func New(cfg config.HTTPServer, router routes.Router) *HTTPServer {
+	// Базовый контекст запросов отменяется при остановке сервера, чтобы
+	// прервать зависшие обращения к БД и внешним API.
+	baseCtx, cancel := context.WithCancel(context.Background())
+
 	srv := &http.Server{
+		BaseContext:  func(net.Listener) context.Context { return baseCtx },
 		Addr:         cfg.Address,
-		Handler:      &router,
-		ReadTimeout:  cfg.Timeout,
-		WriteTimeout: cfg.Timeout,
+		Handler:      requestid.Middleware(bodylimit.Middleware(cfg.MaxBodySize, router.ServeHTTP)),
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
 		IdleTimeout:  cfg.IdleTimeout,
 	}
 
-	logger.Info("httpserver: initialized with address %s (timeout: %s, idle timeout: %s)", cfg.Address, cfg.Timeout, cfg.IdleTimeout)
+	logger.Info("httpserver: initialized with address %s (read timeout: %s, write timeout: %s, idle timeout: %s)",
+		cfg.Address, cfg.ReadTimeout, cfg.WriteTimeout, cfg.IdleTimeout)
 
-	return &HTTPServer{server: srv}
+	return &HTTPServer{server: srv, cancel: cancel}
 }
 
 func (s *HTTPServer) Start() error {
@@ -40,6 +50,7 @@ func (s *HTTPServer) Start() error {
 func (s *HTTPServer) Shutdown(ctx context.Context) error {
 	logger.Info("httpserver: shutting down server...")
 	err := s.server.Shutdown(ctx)
+	s.cancel()
 	if err != nil {
 		logger.Error("httpserver: shutdown error: %v", err)
 	} else {
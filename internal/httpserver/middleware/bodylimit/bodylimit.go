@@ -0,0 +1,17 @@
+package bodylimit
+
+import "net/http"
+
+// Middleware ограничивает тело запроса limit байтами: чтение сверх лимита
+// возвращает *http.MaxBytesError. 0 — без ограничения.
+func Middleware(limit int64, next http.HandlerFunc) http.HandlerFunc {
+	if limit <= 0 {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		next(w, r)
+	}
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"Effective_Mobile/internal/httpserver/middleware/requestid"
 	"Effective_Mobile/internal/logger"
 	"net/http"
 	"time"
@@ -19,21 +20,23 @@ func Middleware(next http.HandlerFunc) http.HandlerFunc {
 		duration := time.Since(start)
 
 		if rw.statusCode >= 400 {
-			logger.Error("→ %s %s | %d | %v | IP: %s | UA: %s | Err: %s",
+			logger.Error("→ %s %s | %d | %v | ID: %s | IP: %s | UA: %s | Err: %s",
 				r.Method,
 				r.URL.Path,
 				rw.statusCode,
 				duration,
+				requestid.FromContext(r.Context()),
 				r.RemoteAddr,
 				r.UserAgent(),
 				rw.errMsg,
 			)
 		} else {
-			logger.Info("→ %s %s | %d | %v | IP: %s | UA: %s",
+			logger.Info("→ %s %s | %d | %v | ID: %s | IP: %s | UA: %s",
 				r.Method,
 				r.URL.Path,
 				rw.statusCode,
 				duration,
+				requestid.FromContext(r.Context()),
 				r.RemoteAddr,
 				r.UserAgent(),
 			)
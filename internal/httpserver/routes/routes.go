@@ -2,36 +2,42 @@ package routes
 
 import (
 	_ "Effective_Mobile/docs"
+	"Effective_Mobile/internal/httpserver/handlers"
 	"Effective_Mobile/internal/httpserver/handlers/del"
 	"Effective_Mobile/internal/httpserver/handlers/get"
 	"Effective_Mobile/internal/httpserver/handlers/post"
 	"Effective_Mobile/internal/httpserver/handlers/put"
+	"Effective_Mobile/internal/httpserver/handlers/status"
 	log "Effective_Mobile/internal/httpserver/middleware/logger"
 	"Effective_Mobile/internal/logger"
-	"Effective_Mobile/internal/storage/pg"
-	"errors"
+	"Effective_Mobile/internal/storage"
 	"fmt"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"net/http"
 	"strings"
 )
 
-var MethodNotAllowed = errors.New("method not allowed")
-
 type Router struct {
 	getHandler     http.HandlerFunc
+	getOneHandler  http.HandlerFunc
 	postHandler    http.HandlerFunc
 	putHandler     http.HandlerFunc
 	deleteHandler  http.HandlerFunc
+	statusHandler  http.HandlerFunc
 	swaggerHandler http.Handler
 }
 
-func New(storage *pg.Storage) *Router {
+// New собирает маршруты. При async POST /people ставит обогащение в очередь.
+// POST и запись PUT выполняются в транзакции tx.
+func New(repo storage.Repository, tx storage.Transactor, queue storage.JobQueue, changes storage.RefreshStore,
+	enricher post.Enricher, async bool) *Router {
 	return &Router{
-		getHandler:     log.Middleware(get.New(storage)),
-		postHandler:    log.Middleware(post.New(storage)),
-		putHandler:     log.Middleware(put.New(storage, storage)),
-		deleteHandler:  log.Middleware(del.New(storage)),
+		getHandler:     log.Middleware(get.New(repo)),
+		getOneHandler:  log.Middleware(get.NewByID(repo)),
+		postHandler:    log.Middleware(post.New(tx, enricher, async)),
+		putHandler:     log.Middleware(put.New(repo, tx, enricher)),
+		deleteHandler:  log.Middleware(del.New(repo)),
+		statusHandler:  log.Middleware(status.New(repo, queue, changes)),
 		swaggerHandler: httpSwagger.WrapHandler,
 	}
 }
@@ -63,9 +69,12 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	case len(parts) == 2 && parts[0] == "people":
 		logger.Debug("%s: matched route /people/{id}", op)
 		r.handlePeopleWithID(w, req)
+	case len(parts) == 3 && parts[0] == "people" && parts[2] == "enrichment":
+		logger.Debug("%s: matched route /people/{id}/enrichment", op)
+		r.statusHandler(w, req)
 	default:
 		logger.Error("%s: unknown path %q", op, req.URL.Path)
-		http.Error(w, fmt.Sprintf("%s: %s", op, MethodNotAllowed), http.StatusMethodNotAllowed)
+		handlers.RespondError(w, req, op, fmt.Errorf("%w: %s", handlers.RouteNotFound, req.URL.Path))
 	}
 }
 
@@ -84,7 +93,7 @@ func (r *Router) handlePeople(w http.ResponseWriter, req *http.Request) {
 	default:
 		logger.Error("%s: method %s not allowed", op, req.Method)
 		w.Header().Set("Allow", "GET, POST")
-		http.Error(w, fmt.Sprintf("%s: %s", op, MethodNotAllowed), http.StatusMethodNotAllowed)
+		handlers.RespondError(w, req, op, handlers.MethodNotAllowed)
 	}
 }
 
@@ -92,21 +101,21 @@ func (r *Router) handlePeopleWithID(w http.ResponseWriter, req *http.Request) {
 	const op = "httpserver.routes.handlePeopleWithID"
 
 	switch req.Method {
-	case http.MethodPut:
-		logger.Debug("%s: PUT /people/{id}", op)
+	case http.MethodPut, http.MethodPatch:
+		logger.Debug("%s: %s /people/{id}", op, req.Method)
 		r.putHandler(w, req)
 	case http.MethodDelete:
 		logger.Debug("%s: DELETE /people/{id}", op)
 		r.deleteHandler(w, req)
 	case http.MethodGet:
-		logger.Debug("%s: GET /people", op)
-		r.getHandler(w, req)
+		logger.Debug("%s: GET /people/{id}", op)
+		r.getOneHandler(w, req)
 	case http.MethodOptions:
 		w.WriteHeader(http.StatusNoContent)
 	default:
 		logger.Error("%s: method %s not allowed", op, req.Method)
-		w.Header().Set("Allow", "PUT, DELETE")
-		http.Error(w, fmt.Sprintf("%s: %s", op, MethodNotAllowed), http.StatusMethodNotAllowed)
+		w.Header().Set("Allow", "GET, PUT, PATCH, DELETE")
+		handlers.RespondError(w, req, op, handlers.MethodNotAllowed)
 	}
 }
 
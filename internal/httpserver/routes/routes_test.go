@@ -0,0 +1,75 @@
+package routes
+
+import (
+	"Effective_Mobile/internal/httpserver/handlers"
+	"Effective_Mobile/internal/httpserver/handlers/dto"
+	"Effective_Mobile/internal/httpserver/handlers/handlertest"
+	"Effective_Mobile/internal/httpserver/middleware/requestid"
+	"Effective_Mobile/internal/storage/memory"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func newRouter(t *testing.T) http.HandlerFunc {
+	t.Helper()
+
+	repo := memory.New()
+	return New(repo, repo, repo, repo, handlertest.NewEnrichment(t, handlertest.Options{}), false).ServeHTTP
+}
+
+func TestRouterProblems(t *testing.T) {
+	h := newRouter(t)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+		code   string
+		allow  string
+	}{
+		{"unknown route", http.MethodGet, "/nope", http.StatusNotFound, handlers.CodeNotFound, ""},
+		{"too deep", http.MethodGet, "/people/1/enrichment/age", http.StatusNotFound, handlers.CodeNotFound, ""},
+		{"missing person", http.MethodGet, "/people/7", http.StatusNotFound, handlers.CodeNotFound, ""},
+		{"bad id", http.MethodPatch, "/people/abc", http.StatusBadRequest, handlers.CodeInvalidRequest, ""},
+		{"collection method", http.MethodDelete, "/people", http.StatusMethodNotAllowed, handlers.CodeMethodNotAllowed, "GET, POST"},
+		{"item method", http.MethodPost, "/people/1", http.StatusMethodNotAllowed, handlers.CodeMethodNotAllowed, "GET, PUT, PATCH, DELETE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := handlertest.Do(t, h, tt.method, tt.target, "", requestid.Header, "req-42")
+			problem := handlertest.AssertProblem(t, rec, tt.status, tt.code)
+			if problem.RequestID != "req-42" || problem.Instance != tt.target {
+				t.Errorf("got request_id %q, instance %q", problem.RequestID, problem.Instance)
+			}
+			if allow := rec.Header().Get("Allow"); allow != tt.allow {
+				t.Errorf("got Allow %q, want %q", allow, tt.allow)
+			}
+		})
+	}
+}
+
+func TestRouterServesPeople(t *testing.T) {
+	h := newRouter(t)
+
+	if rec := handlertest.Do(t, h, http.MethodGet, "/health", ""); rec.Code != http.StatusOK {
+		t.Fatalf("GET /health: got %d", rec.Code)
+	}
+
+	var created dto.Response
+	handlertest.AssertStatus(t, handlertest.Do(t, h, http.MethodPost, "/people", `{"name":"Ivan","surname":"Smith"}`),
+		http.StatusCreated, &created)
+	path := "/people/" + strconv.Itoa(created.ID)
+
+	var user dto.UserResponse
+	rec := handlertest.Do(t, h, http.MethodGet, path, "")
+	handlertest.AssertStatus(t, rec, http.StatusOK, &user)
+	if user.Name != "Ivan" || rec.Header().Get("ETag") != `"1"` {
+		t.Fatalf("GET %s: got %+v, ETag %s", path, user, rec.Header().Get("ETag"))
+	}
+
+	handlertest.AssertStatus(t, handlertest.Do(t, h, http.MethodGet, path+"/enrichment", ""), http.StatusOK, nil)
+	handlertest.AssertStatus(t, handlertest.Do(t, h, http.MethodPatch, path, `{"age":50}`), http.StatusOK, nil)
+	handlertest.AssertStatus(t, handlertest.Do(t, h, http.MethodDelete, path, ""), http.StatusOK, nil)
+}
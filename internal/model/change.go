@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// EnrichmentChange — изменение атрибута при повторном обогащении.
+type EnrichmentChange struct {
+	ID        int64     `json:"id"`
+	PersonID  int       `json:"person_id"`
+	Attribute string    `json:"attribute"`
+	OldValue  *string   `json:"old_value,omitempty"`
+	NewValue  *string   `json:"new_value,omitempty"`
+	Provider  string    `json:"provider,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobDead    = "dead"
+)
+
+// EnrichmentJob — задача фонового обогащения человека.
+type EnrichmentJob struct {
+	ID        int64     `json:"id"`
+	PersonID  int       `json:"person_id"`
+	Status    string    `json:"status"`
+	Attempts  int       `json:"attempts"`
+	LastError *string   `json:"last_error,omitempty"`
+	RunAt     time.Time `json:"run_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
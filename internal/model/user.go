@@ -1,29 +1,150 @@
 package model
 
+import "time"
+
+// ProviderManual — источник значений, заданных вручную через API.
+const ProviderManual = "manual"
+
+const (
+	EnrichmentComplete = "complete"
+	EnrichmentPartial  = "partial"
+	EnrichmentPending  = "pending_enrichment"
+	EnrichmentFailed   = "failed"
+)
+
 type User struct {
-	ID          int     `json:"id,omitempty"`
-	Name        string  `json:"name" example:"Dmitriy"`
-	Surname     string  `json:"surname" example:"Ivanov"`
+	ID      int    `json:"id,omitempty"`
+	Name    string `json:"name" example:"Dmitriy"`
+	Surname string `json:"surname" example:"Ivanov"`
+	// NameKey и SurnameKey — нормализованные ключи для поиска и уникальности.
+	NameKey     string  `json:"-"`
+	SurnameKey  string  `json:"-"`
 	Patronymic  *string `json:"patronymic,omitempty" example:"Sergeevich"`
 	Age         *int    `json:"age,omitempty" example:"30"`
 	Gender      *string `json:"gender,omitempty" example:"male"`
 	Nationality *string `json:"nationality,omitempty" example:"RU"`
+	// CountryHint — страна (ISO 3166-1 alpha-2) для локализованного обогащения.
+	CountryHint *string `json:"country_hint,omitempty" example:"RU"`
+	// PendingEnrichment — атрибуты, которые не удалось обогатить. nil — не менять при обновлении.
+	PendingEnrichment []string `json:"pending_enrichment,omitempty"`
+	EnrichmentStatus  string   `json:"enrichment_status,omitempty" example:"complete"`
+	// Enrichment — происхождение и достоверность обогащённых атрибутов.
+	Enrichment *Enrichment `json:"enrichment,omitempty"`
+	// Locked — атрибуты, заданные вручную: обогащение их не перезаписывает. nil — не менять при обновлении.
+	Locked []string `json:"locked_attributes,omitempty"`
+	// Unset — атрибуты (age, gender, nationality), которые нужно сбросить в NULL при обновлении.
+	Unset []string `json:"-"`
+	// EnrichedAt — время последней попытки обогащения, в том числе неудачной.
+	// nil — не менять при обновлении.
+	EnrichedAt *time.Time `json:"-"`
+	// Version увеличивается при каждом изменении. В Update и Delete ненулевое
+	// значение — ожидаемая версия: при несовпадении возвращается ErrVersionConflict.
+	Version int `json:"version,omitempty"`
+}
+
+// Clone возвращает копию пользователя без общих с оригиналом указателей и срезов.
+func (u User) Clone() User {
+	u.Patronymic = clonePtr(u.Patronymic)
+	u.Age = clonePtr(u.Age)
+	u.Gender = clonePtr(u.Gender)
+	u.Nationality = clonePtr(u.Nationality)
+	u.CountryHint = clonePtr(u.CountryHint)
+	u.EnrichedAt = clonePtr(u.EnrichedAt)
+	if u.PendingEnrichment != nil {
+		u.PendingEnrichment = append([]string{}, u.PendingEnrichment...)
+	}
+	if u.Locked != nil {
+		u.Locked = append([]string{}, u.Locked...)
+	}
+	if u.Unset != nil {
+		u.Unset = append([]string{}, u.Unset...)
+	}
+	u.Enrichment = u.Enrichment.Clone()
+	return u
+}
+
+type Enrichment struct {
+	Age         *Attribution `json:"age,omitempty"`
+	Gender      *Attribution `json:"gender,omitempty"`
+	Nationality *Attribution `json:"nationality,omitempty"`
+}
+
+func (e *Enrichment) Clone() *Enrichment {
+	if e == nil {
+		return nil
+	}
+	return &Enrichment{
+		Age:         e.Age.Clone(),
+		Gender:      e.Gender.Clone(),
+		Nationality: e.Nationality.Clone(),
+	}
+}
+
+// Attribution описывает, откуда и с какой уверенностью получен атрибут.
+type Attribution struct {
+	Provider    string   `json:"provider"`
+	Probability *float64 `json:"probability,omitempty"`
+	Count       int      `json:"count"`
+	// CountryID — страна, для которой локализован запрос.
+	CountryID string    `json:"country_id,omitempty"`
+	Rule      string    `json:"rule,omitempty"`
+	Countries []Country `json:"countries,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+	// LowConfidence — результат отклонён порогами достоверности: атрибут оставлен
+	// пустым или, при повторном обогащении, сохранил прежнее значение.
+	LowConfidence bool `json:"low_confidence,omitempty"`
+}
+
+func (a *Attribution) Clone() *Attribution {
+	if a == nil {
+		return nil
+	}
+	c := *a
+	c.Probability = clonePtr(a.Probability)
+	if a.Countries != nil {
+		c.Countries = append([]Country{}, a.Countries...)
+	}
+	return &c
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// Source заполняется провайдером: API его не возвращают.
+type Source struct {
+	Provider  string `json:"provider,omitempty"`
+	CountryID string `json:"country_id,omitempty"`
+	// Rule — правило, по которому локальный провайдер вывел значение.
+	Rule      string    `json:"rule,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+func NewSource(provider, countryID string) Source {
+	return Source{Provider: provider, CountryID: countryID, FetchedAt: time.Now().UTC()}
 }
 
 type UserAge struct {
+	Source
 	Count int    `json:"count"`
 	Name  string `json:"name"`
-	Age   int    `json:"age"`
+	Age   *int   `json:"age"`
 }
 
 type UserGender struct {
+	Source
 	Count       int     `json:"count"`
 	Name        string  `json:"name"`
-	Gender      string  `json:"gender"`
+	Gender      *string `json:"gender"`
 	Probability float64 `json:"probability"`
 }
 
 type UserNationality struct {
+	Source
 	Count     int       `json:"count"`
 	Name      string    `json:"name"`
 	Countries []Country `json:"country"`
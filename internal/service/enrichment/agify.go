@@ -0,0 +1,36 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/model"
+	"context"
+	"net/http"
+)
+
+const ageEnrichURL = "https://api.agify.io/"
+
+type Agify struct {
+	api apiProvider
+}
+
+// NewAgify создаёт провайдера по настройкам cfg; пустой URL — публичный адрес API.
+func NewAgify(client *http.Client, cfg config.Provider) *Agify {
+	return &Agify{api: newAPIProvider("agify", "Age", ageEnrichURL, true, client, cfg)}
+}
+
+func (p *Agify) Name() string {
+	return p.api.name
+}
+
+func (p *Agify) Age(ctx context.Context, q Query) (*model.UserAge, error) {
+	return fetchOne(ctx, p.api, q, setAgeSource)
+}
+
+// AgeBatch запрашивает возраст для нескольких имён одним вызовом.
+func (p *Agify) AgeBatch(ctx context.Context, qs []Query) ([]*model.UserAge, error) {
+	return fetchAPI(ctx, p.api, qs, setAgeSource)
+}
+
+func setAgeSource(r *model.UserAge, s model.Source) {
+	r.Source = s
+}
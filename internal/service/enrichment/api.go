@@ -0,0 +1,79 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// apiProvider — общая часть agify, genderize и nationalize: адрес API, ключ
+// платного тарифа, клиент с таймаутом провайдера и разбор ответов. Сами
+// провайдеры задают только имя, адрес по умолчанию и тип ответа.
+type apiProvider struct {
+	name    string
+	kind    string
+	client  *http.Client
+	baseURL string
+	apiKey  string
+	// localized — API принимает country_id и отвечает для этой страны.
+	localized bool
+}
+
+// newAPIProvider создаёт провайдера по настройкам cfg; пустой URL — defaultURL.
+func newAPIProvider(name, kind, defaultURL string, localized bool, client *http.Client, cfg config.Provider) apiProvider {
+	baseURL := cfg.URL
+	if baseURL == "" {
+		baseURL = defaultURL
+	}
+	return apiProvider{
+		name:      name,
+		kind:      kind,
+		client:    providerClient(client, cfg.Timeout),
+		baseURL:   baseURL,
+		apiKey:    cfg.APIKey,
+		localized: localized,
+	}
+}
+
+func (p apiProvider) source(q Query) model.Source {
+	if !p.localized {
+		return model.NewSource(p.name, "")
+	}
+	return model.NewSource(p.name, q.CountryID)
+}
+
+// fetchAPI запрашивает у API все имена qs одним вызовом (страна общая, см.
+// enrichURL) и разбирает ответ; setSource записывает в результат провенанс.
+func fetchAPI[R any](ctx context.Context, p apiProvider, qs []Query, setSource func(*R, model.Source)) ([]*R, error) {
+	const op = "service.enrichment.fetchAPI"
+
+	u := enrichURL(p.baseURL, p.apiKey, qs, p.localized)
+	logger.Debug("%s: enriching %s for %d names from %s", op, p.kind, len(qs), redact(u))
+
+	body, err := FetchBody(ctx, p.client, u, p.kind)
+	if err != nil {
+		return nil, err
+	}
+
+	source := p.source(qs[0])
+	res, err := decodeBatch(body, qs, func(r *R) { setSource(r, source) })
+	if err != nil {
+		logger.Error("%s: failed to unmarshal %s data from %s: %v", op, p.kind, p.name, err)
+		return nil, fmt.Errorf("%s: %s: %w", op, p.name, err)
+	}
+
+	logger.Info("%s: %s returned %s for %d names", op, p.name, p.kind, len(res))
+	return res, nil
+}
+
+// fetchOne — запрос одного имени через fetchAPI.
+func fetchOne[R any](ctx context.Context, p apiProvider, q Query, setSource func(*R, model.Source)) (*R, error) {
+	res, err := fetchAPI(ctx, p, []Query{q}, setSource)
+	if err != nil {
+		return nil, err
+	}
+	return res[0], nil
+}
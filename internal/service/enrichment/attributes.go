@@ -0,0 +1,165 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/model"
+	"context"
+	"slices"
+)
+
+// Threshold — минимальные требования к ответу провайдера. Ответы ниже порога
+// не записываются в атрибут, а помечаются как low confidence.
+type Threshold struct {
+	MinProbability float64
+	MinCount       int
+}
+
+func (t Threshold) accepts(a *model.Attribution) bool {
+	if a.Count < t.MinCount {
+		return false
+	}
+	return a.Probability == nil || *a.Probability >= t.MinProbability
+}
+
+// outcome — результат обогащения одного атрибута. apply записывает его в пользователя.
+type outcome struct {
+	status Status
+	apply  func(*model.User)
+	// country — наиболее вероятная страна, если атрибут — национальность.
+	country string
+}
+
+func locked(user *model.User, attr string) bool {
+	return slices.Contains(user.Locked, attr)
+}
+
+// unlocked убирает задачи для атрибутов, заданных вручную.
+func unlocked(user *model.User, tasks []task) []task {
+	res := tasks[:0]
+	for _, t := range tasks {
+		if !locked(user, t.attr) {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+func lockedResults(user *model.User) []result {
+	var res []result
+	for _, attr := range []string{AttrAge, AttrGender, AttrNationality} {
+		if locked(user, attr) {
+			res = append(res, result{attr: attr, outcome: outcome{status: StatusLocked}})
+		}
+	}
+	return res
+}
+
+func lowConfidence(attr string, a *model.Attribution, set func(*model.Enrichment)) outcome {
+	a.LowConfidence = true
+	return outcome{
+		status: StatusLowConfidence,
+		apply: func(u *model.User) {
+			set(u.Enrichment)
+			u.Unset = append(u.Unset, attr)
+		},
+	}
+}
+
+func (s *Service) fetchGender(ctx context.Context, q Query) (outcome, error) {
+	res, err := s.gender.Gender(ctx, q)
+	if err != nil {
+		return outcome{}, err
+	}
+	return s.genderOutcome(res), nil
+}
+
+func (s *Service) genderOutcome(res *model.UserGender) outcome {
+	a := &model.Attribution{
+		Provider:    res.Provider,
+		Probability: &res.Probability,
+		Count:       res.Count,
+		CountryID:   res.CountryID,
+		Rule:        res.Rule,
+		FetchedAt:   res.FetchedAt,
+	}
+	set := func(e *model.Enrichment) { e.Gender = a }
+
+	if res.Gender == nil || *res.Gender == "" || !s.genderThreshold.accepts(a) {
+		return lowConfidence(AttrGender, a, set)
+	}
+
+	return outcome{
+		status: StatusSucceeded,
+		apply: func(u *model.User) {
+			u.Gender = res.Gender
+			set(u.Enrichment)
+		},
+	}
+}
+
+func (s *Service) fetchAge(ctx context.Context, q Query) (outcome, error) {
+	res, err := s.age.Age(ctx, q)
+	if err != nil {
+		return outcome{}, err
+	}
+	return s.ageOutcome(res), nil
+}
+
+func (s *Service) ageOutcome(res *model.UserAge) outcome {
+	a := &model.Attribution{
+		Provider:  res.Provider,
+		Count:     res.Count,
+		CountryID: res.CountryID,
+		FetchedAt: res.FetchedAt,
+	}
+	set := func(e *model.Enrichment) { e.Age = a }
+
+	if res.Age == nil || !s.ageThreshold.accepts(a) {
+		return lowConfidence(AttrAge, a, set)
+	}
+
+	return outcome{
+		status: StatusSucceeded,
+		apply: func(u *model.User) {
+			u.Age = res.Age
+			set(u.Enrichment)
+		},
+	}
+}
+
+func (s *Service) fetchNationality(ctx context.Context, q Query) (outcome, error) {
+	res, err := s.nationality.Nationality(ctx, q)
+	if err != nil {
+		return outcome{}, err
+	}
+	return s.nationalityOutcome(res), nil
+}
+
+func (s *Service) nationalityOutcome(res *model.UserNationality) outcome {
+	a := &model.Attribution{
+		Provider:  res.Provider,
+		Count:     res.Count,
+		Countries: res.Countries,
+		FetchedAt: res.FetchedAt,
+	}
+	set := func(e *model.Enrichment) { e.Nationality = a }
+
+	if len(res.Countries) == 0 {
+		return lowConfidence(AttrNationality, a, set)
+	}
+
+	// выбираем наиболее вероятную национальность, сохраняя всё распределение
+	top := res.Countries[0]
+	a.Probability = &top.Probability
+	if !s.nationalityThreshold.accepts(a) {
+		return lowConfidence(AttrNationality, a, set)
+	}
+
+	return outcome{
+		status: StatusSucceeded,
+		apply: func(u *model.User) {
+			u.Nationality = &top.CountryID
+			set(u.Enrichment)
+		},
+		country: top.CountryID,
+	}
+}
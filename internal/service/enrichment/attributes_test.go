@@ -0,0 +1,71 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/model"
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestThresholdAccepts(t *testing.T) {
+	p := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name      string
+		threshold Threshold
+		a         model.Attribution
+		want      bool
+	}{
+		{"no threshold", Threshold{}, model.Attribution{Probability: p(0.1)}, true},
+		{"probability at the minimum", Threshold{MinProbability: 0.8}, model.Attribution{Probability: p(0.8)}, true},
+		{"probability below", Threshold{MinProbability: 0.8}, model.Attribution{Probability: p(0.79)}, false},
+		{"count at the minimum", Threshold{MinCount: 10}, model.Attribution{Count: 10}, true},
+		{"count below", Threshold{MinCount: 10}, model.Attribution{Count: 9}, false},
+		{"no probability for age", Threshold{MinProbability: 0.8, MinCount: 1}, model.Attribution{Count: 5}, true},
+		{"count below with high probability", Threshold{MinProbability: 0.5, MinCount: 10},
+			model.Attribution{Probability: p(0.99), Count: 3}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.threshold.accepts(&tt.a); got != tt.want {
+				t.Fatalf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnrichBelowThresholdIsLowConfidence(t *testing.T) {
+	p := newStub("remote")
+	p.counts = 5
+	p.genders["Anna"] = ""
+	s := New(p, p, p, config.Enrichment{AgeMinCount: 10, NationalityMinProbability: 0.95})
+
+	user := &model.User{Name: "Anna"}
+	report, err := s.Enrich(context.Background(), user)
+	if err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+
+	for _, attr := range []string{AttrAge, AttrGender, AttrNationality} {
+		if report[attr] != StatusLowConfidence {
+			t.Errorf("%s: got %q, want low_confidence", attr, report[attr])
+		}
+	}
+	if user.Age != nil || user.Gender != nil || user.Nationality != nil {
+		t.Errorf("low confidence answers written: age %v, gender %v, nationality %v",
+			user.Age, user.Gender, user.Nationality)
+	}
+	// заполненные раньше атрибуты сбрасываются при записи
+	slices.Sort(user.Unset)
+	if want := []string{AttrAge, AttrGender, AttrNationality}; !slices.Equal(user.Unset, want) {
+		t.Errorf("got unset %v, want %v", user.Unset, want)
+	}
+	// ответ сохраняется в происхождении с пометкой, но повторно не запрашивается
+	if !user.Enrichment.Age.LowConfidence || user.Enrichment.Age.Count != 5 {
+		t.Errorf("age: got %+v", user.Enrichment.Age)
+	}
+	if len(user.PendingEnrichment) != 0 || user.EnrichmentStatus != model.EnrichmentComplete {
+		t.Errorf("got status %q, pending %v", user.EnrichmentStatus, user.PendingEnrichment)
+	}
+}
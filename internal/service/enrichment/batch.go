@@ -0,0 +1,345 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// MaxBatchSize — предельное число имён (name[]) в одном запросе к API.
+const MaxBatchSize = 10
+
+var ErrBatchMismatch = errors.New("batch response does not match request")
+
+// enrichURL собирает адрес запроса: одно имя передаётся как name, несколько —
+// как name[]. Страна берётся из первого запроса, поэтому в пачке она общая.
+// Ключ платного тарифа передаётся параметром apikey.
+func enrichURL(base, apiKey string, qs []Query, withCountry bool) string {
+	v := url.Values{}
+	if len(qs) == 1 {
+		v.Set("name", qs[0].Name)
+	} else {
+		for _, q := range qs {
+			v.Add("name[]", q.Name)
+		}
+	}
+	if withCountry && qs[0].CountryID != "" {
+		v.Set("country_id", qs[0].CountryID)
+	}
+	if apiKey != "" {
+		v.Set("apikey", apiKey)
+	}
+	return base + "?" + v.Encode()
+}
+
+// echo — имя, которое API возвращает в каждом ответе.
+type echo struct {
+	Name string `json:"name"`
+}
+
+// decodeBatch разбирает массив ответов и сопоставляет их запросам qs по имени
+// из ответа: порядок ответов не важен, но на каждое имя должен прийти ответ.
+// Одно имя enrichURL передаёт как name, и API отвечает объектом, а не массивом.
+func decodeBatch[R any](body []byte, qs []Query, setSource func(*R)) ([]*R, error) {
+	const op = "service.enrichment.decodeBatch"
+
+	var (
+		items []R
+		names []echo
+	)
+	if len(qs) == 1 {
+		body = append(append([]byte("["), body...), ']')
+	}
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := json.Unmarshal(body, &names); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(items) != len(qs) {
+		return nil, fmt.Errorf("%s: %w: got %d results for %d names", op, ErrBatchMismatch, len(items), len(qs))
+	}
+
+	res := make([]*R, len(qs))
+	used := make([]bool, len(items))
+	for i, q := range qs {
+		j := matchName(names, used, i, q.Name)
+		if j < 0 {
+			return nil, fmt.Errorf("%s: %w: no result for %q", op, ErrBatchMismatch, q.Name)
+		}
+		used[j] = true
+		setSource(&items[j])
+		res[i] = &items[j]
+	}
+	return res, nil
+}
+
+// matchName ищет неиспользованный ответ с именем name, начиная с позиции
+// запроса i: обычно API отвечает в порядке запроса.
+func matchName(names []echo, used []bool, i int, name string) int {
+	for k := range names {
+		j := (i + k) % len(names)
+		if !used[j] && strings.EqualFold(names[j].Name, name) {
+			return j
+		}
+	}
+	return -1
+}
+
+// each — запасной путь для провайдеров без пакетных запросов. Запросы, на
+// которые провайдер ответил ErrNoMatch, остаются nil.
+func each[R any](ctx context.Context, qs []Query, call func(context.Context, Query) (*R, error)) ([]*R, error) {
+	res := make([]*R, len(qs))
+	for i, q := range qs {
+		r, err := call(ctx, q)
+		if errors.Is(err, ErrNoMatch) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		res[i] = r
+	}
+	return res, nil
+}
+
+func ageBatch(p AgeProvider) func(context.Context, []Query) ([]*model.UserAge, error) {
+	if b, ok := p.(AgeBatcher); ok {
+		return b.AgeBatch
+	}
+	return func(ctx context.Context, qs []Query) ([]*model.UserAge, error) { return each(ctx, qs, p.Age) }
+}
+
+func genderBatch(p GenderProvider) func(context.Context, []Query) ([]*model.UserGender, error) {
+	if b, ok := p.(GenderBatcher); ok {
+		return b.GenderBatch
+	}
+	return func(ctx context.Context, qs []Query) ([]*model.UserGender, error) { return each(ctx, qs, p.Gender) }
+}
+
+func nationalityBatch(p NationalityProvider) func(context.Context, []Query) ([]*model.UserNationality, error) {
+	if b, ok := p.(NationalityBatcher); ok {
+		return b.NationalityBatch
+	}
+	return func(ctx context.Context, qs []Query) ([]*model.UserNationality, error) {
+		return each(ctx, qs, p.Nationality)
+	}
+}
+
+type fetched[R any] struct {
+	res *R
+	err error
+}
+
+// byName — ключ запроса к сетевому API: фамилию и отчество провайдеры не
+// принимают, поэтому запросы с одинаковыми именем и страной для них одинаковы.
+func byName(q Query) Query {
+	return Query{Name: q.Name, CountryID: q.CountryID}
+}
+
+// perName схлопывает запросы сетевого провайдера по byName и раздаёт ответ
+// всем исходным запросам. Локальным правилам нужны фамилия и отчество, поэтому
+// их запросы передаются как есть.
+func perName[P Provider, R any](p P, fetch func(context.Context, []Query) ([]*R, error)) func(context.Context, []Query) ([]*R, error) {
+	if isLocal(p) {
+		return fetch
+	}
+	return func(ctx context.Context, qs []Query) ([]*R, error) {
+		index := make(map[Query]int, len(qs))
+		unique := make([]Query, 0, len(qs))
+		for _, q := range qs {
+			key := byName(q)
+			if _, ok := index[key]; !ok {
+				index[key] = len(unique)
+				unique = append(unique, key)
+			}
+		}
+
+		got, err := fetch(ctx, unique)
+		if got == nil {
+			return nil, err
+		}
+
+		res := make([]*R, len(qs))
+		for i, q := range qs {
+			if r := got[index[byName(q)]]; r != nil {
+				v := *r
+				res[i] = &v
+			}
+		}
+		return res, err
+	}
+}
+
+// batchFetch убирает повторяющиеся запросы, группирует их по стране в пачки
+// не больше MaxBatchSize разных имён и выполняет пачки параллельно. Запросы с
+// одним именем попадают в одну пачку, чтобы сетевой провайдер запросил его
+// один раз (см. perName). Запрос без ответа получает ошибку пачки или ErrNoMatch.
+func batchFetch[R any](ctx context.Context, qs []Query,
+	fetch func(context.Context, []Query) ([]*R, error)) map[Query]fetched[R] {
+
+	const op = "service.enrichment.batchFetch"
+
+	byCountry := make(map[string][]Query)
+	seen := make(map[Query]bool, len(qs))
+	for _, q := range qs {
+		if seen[q] {
+			continue
+		}
+		seen[q] = true
+		byCountry[q.CountryID] = append(byCountry[q.CountryID], q)
+	}
+
+	var chunks [][]Query
+	for _, group := range byCountry {
+		slices.SortStableFunc(group, func(a, b Query) int { return strings.Compare(a.Name, b.Name) })
+
+		var (
+			chunk []Query
+			names int
+		)
+		for i, q := range group {
+			if i == 0 || q.Name != group[i-1].Name {
+				if names == MaxBatchSize {
+					chunks = append(chunks, chunk)
+					chunk, names = nil, 0
+				}
+				names++
+			}
+			chunk = append(chunk, q)
+		}
+		if len(chunk) > 0 {
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results = make(map[Query]fetched[R], len(seen))
+	)
+
+	for _, chunk := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			res, err := fetch(ctx, chunk)
+			if err != nil && !errors.Is(err, ErrNoProviders) {
+				logger.Error("%s: batch of %d names failed: %v", op, len(chunk), err)
+			}
+			if err == nil {
+				err = fmt.Errorf("%s: %w", op, ErrNoMatch)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for i, q := range chunk {
+				if res == nil || res[i] == nil {
+					results[q] = fetched[R]{err: err}
+					continue
+				}
+				results[q] = fetched[R]{res: res[i]}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// wanted возвращает запросы людей, у которых атрибут не заблокирован.
+func wanted(users []*model.User, qs []Query, attr string) []Query {
+	res := make([]Query, 0, len(qs))
+	for i, user := range users {
+		if !locked(user, attr) {
+			res = append(res, qs[i])
+		}
+	}
+	return res
+}
+
+func toResult[R any](attr string, f fetched[R], build func(*R) outcome) result {
+	if f.err != nil {
+		return result{attr: attr, err: f.err}
+	}
+	return result{attr: attr, outcome: build(f.res)}
+}
+
+// EnrichBatch обогащает несколько человек, объединяя их имена в запросы по
+// MaxBatchSize имён. Одинаковые имена запрашиваются один раз. Ошибка пачки
+// не прерывает обработку: её атрибуты помечаются failed, как в режиме partial.
+// Ошибка возвращается только при отмене ctx.
+func (s *Service) EnrichBatch(ctx context.Context, users []*model.User) ([]Report, error) {
+	const op = "service.enrichment.enrichBatch"
+	logger.Info("%s: start batch enrichment for %d users", op, len(users))
+
+	parent := ctx
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	queries := make([]Query, len(users))
+	names := make([]Query, len(users))
+	results := make([][]result, len(users))
+	for i, user := range users {
+		queries[i] = s.queryFor(user)
+		names[i] = Query{Name: queries[i].Name}
+		results[i] = lockedResults(user)
+	}
+
+	nationalities := batchFetch(ctx, wanted(users, names, AttrNationality), nationalityBatch(s.nationality))
+	for i, user := range users {
+		if locked(user, AttrNationality) {
+			continue
+		}
+		r := toResult(AttrNationality, nationalities[names[i]], s.nationalityOutcome)
+		if queries[i].CountryID == "" && s.localize && r.err == nil && r.status == StatusSucceeded {
+			queries[i].CountryID = r.country
+		}
+		results[i] = append(results[i], r)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		ages    map[Query]fetched[model.UserAge]
+		genders map[Query]fetched[model.UserGender]
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		ages = batchFetch(ctx, wanted(users, queries, AttrAge), ageBatch(s.age))
+	}()
+	go func() {
+		defer wg.Done()
+		genders = batchFetch(ctx, wanted(users, queries, AttrGender), genderBatch(s.gender))
+	}()
+	wg.Wait()
+
+	reports := make([]Report, len(users))
+	for i, user := range users {
+		if !locked(user, AttrGender) {
+			results[i] = append(results[i], toResult(AttrGender, genders[queries[i]], s.genderOutcome))
+		}
+		if !locked(user, AttrAge) {
+			results[i] = append(results[i], toResult(AttrAge, ages[queries[i]], s.ageOutcome))
+		}
+		reports[i] = finish(user, results[i])
+	}
+
+	if err := parent.Err(); err != nil {
+		return reports, fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("%s: batch enrichment complete for %d users", op, len(users))
+	return reports, nil
+}
@@ -0,0 +1,167 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEnrichURL(t *testing.T) {
+	one, _ := url.Parse(enrichURL("http://api/", "", []Query{{Name: "ivan", CountryID: "RU"}}, true))
+	if q := one.Query(); q.Get("name") != "ivan" || q.Get("country_id") != "RU" || q.Has("name[]") {
+		t.Errorf("one name: got %v", q)
+	}
+
+	many, _ := url.Parse(enrichURL("http://api/", "key", []Query{{Name: "ivan", CountryID: "RU"}, {Name: "anna"}}, false))
+	if q := many.Query(); !slices.Equal(q["name[]"], []string{"ivan", "anna"}) || q.Has("country_id") || q.Get("apikey") != "key" {
+		t.Errorf("two names: got %v", q)
+	}
+}
+
+func TestDecodeBatch(t *testing.T) {
+	qs := []Query{{Name: "ivan"}, {Name: "anna"}, {Name: "ivan"}}
+	tests := []struct {
+		name    string
+		qs      []Query
+		body    string
+		ages    []int
+		wantErr error
+	}{
+		{"single object", qs[:1], `{"name":"ivan","age":47}`, []int{47}, nil},
+		{"aligned", qs[:2], `[{"name":"ivan","age":47},{"name":"anna","age":35}]`, []int{47, 35}, nil},
+		{"reordered", qs[:2], `[{"name":"Anna","age":35},{"name":"ivan","age":47}]`, []int{47, 35}, nil},
+		{"repeated name", qs, `[{"name":"ivan","age":47},{"name":"anna","age":35},{"name":"ivan","age":48}]`,
+			[]int{47, 35, 48}, nil},
+		{"fewer items", qs[:2], `[{"name":"ivan","age":47}]`, nil, ErrBatchMismatch},
+		{"unknown name", qs[:2], `[{"name":"ivan","age":47},{"name":"john","age":52}]`, nil, ErrBatchMismatch},
+		{"object for a batch", qs[:2], `{"name":"ivan","age":47}`, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := 0
+			res, err := decodeBatch([]byte(tt.body), tt.qs, func(r *model.UserAge) { sources++ })
+			if tt.ages == nil {
+				if err == nil {
+					t.Fatalf("got %v, want an error", res)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeBatch: %v", err)
+			}
+			for i, r := range res {
+				if *r.Age != tt.ages[i] || !strings.EqualFold(r.Name, tt.qs[i].Name) {
+					t.Errorf("query %d (%s): got %s %d, want %d", i, tt.qs[i].Name, r.Name, *r.Age, tt.ages[i])
+				}
+			}
+			if sources != len(tt.qs) {
+				t.Errorf("source set %d times, want %d", sources, len(tt.qs))
+			}
+		})
+	}
+}
+
+func TestBatchFetchChunks(t *testing.T) {
+	var qs []Query
+	for i := 0; i < 25; i++ {
+		qs = append(qs, Query{Name: fmt.Sprintf("n%02d", i)})
+	}
+	// повтор запроса, то же имя с другой фамилией и другая страна
+	qs = append(qs, qs[3], Query{Name: "n03", Surname: "Smith"}, Query{Name: "n03", CountryID: "RU"})
+
+	var (
+		mu     sync.Mutex
+		chunks [][]Query
+	)
+	results := batchFetch(context.Background(), qs, func(ctx context.Context, chunk []Query) ([]*model.UserAge, error) {
+		mu.Lock()
+		chunks = append(chunks, chunk)
+		mu.Unlock()
+
+		res := make([]*model.UserAge, len(chunk))
+		for i, q := range chunk {
+			if q.Name == "n07" {
+				continue
+			}
+			age := len(q.Name) + len(q.Surname)
+			res[i] = &model.UserAge{Name: q.Name, Age: &age}
+		}
+		return res, nil
+	})
+
+	if len(chunks) != 4 {
+		t.Fatalf("got %d chunks, want 3 for 25 names and 1 for RU", len(chunks))
+	}
+	total := 0
+	for _, chunk := range chunks {
+		names := map[string]bool{}
+		for _, q := range chunk {
+			names[q.Name] = true
+			if q.CountryID != chunk[0].CountryID {
+				t.Errorf("chunk mixes countries: %+v", chunk)
+			}
+		}
+		if len(names) > MaxBatchSize {
+			t.Errorf("chunk has %d names", len(names))
+		}
+		// одно имя с разными фамилиями попадает в одну пачку
+		if names["n03"] && chunk[0].CountryID == "" && len(chunk) != len(names)+1 {
+			t.Errorf("n03 queries split across chunks: %+v", chunk)
+		}
+		total += len(chunk)
+	}
+	if total != 27 {
+		t.Errorf("fetched %d queries, want 27 without the repeated one", total)
+	}
+
+	if r := results[Query{Name: "n03", Surname: "Smith"}]; r.err != nil || *r.res.Age != 8 {
+		t.Errorf("n03 Smith: got %+v", r)
+	}
+	if r := results[Query{Name: "n07"}]; !errors.Is(r.err, ErrNoMatch) {
+		t.Errorf("n07: got %v, want ErrNoMatch", r.err)
+	}
+}
+
+func TestBatchFetchChunkError(t *testing.T) {
+	down := errors.New("down")
+	results := batchFetch(context.Background(), []Query{{Name: "ivan"}, {Name: "anna"}},
+		func(ctx context.Context, chunk []Query) ([]*model.UserAge, error) { return nil, down })
+
+	for q, r := range results {
+		if !errors.Is(r.err, down) {
+			t.Errorf("%s: got %v, want the chunk error", q.Name, r.err)
+		}
+	}
+}
+
+func TestEnrichBatchRequestsEachNameOnce(t *testing.T) {
+	p := newStub("remote")
+	s := New(AgeChain{p}, GenderChain{p}, NationalityChain{p}, config.Enrichment{Partial: true})
+
+	users := []*model.User{{Name: "Ivan", Surname: "Smith"}, {Name: "Anna"}, {Name: "Ivan", Surname: "Jones"}, {Name: "Zzz"}}
+	reports, err := s.EnrichBatch(context.Background(), users)
+	if err != nil {
+		t.Fatalf("EnrichBatch: %v", err)
+	}
+
+	// stub без пакетного API: по одному запросу на имя и атрибут, фамилия не важна
+	if n := len(p.Calls()); n != 9 {
+		t.Errorf("got %d provider calls, want 3 names for 3 attributes", n)
+	}
+	if *users[2].Age != 47 || *users[1].Gender != "female" || *users[0].Nationality != "RU" {
+		t.Errorf("got %v, %v, %v", users[2].Age, users[1].Gender, users[0].Nationality)
+	}
+	if reports[3][AttrAge] != StatusLowConfidence || len(users[3].PendingEnrichment) != 0 {
+		t.Errorf("unknown name: got %v, pending %v", reports[3], users[3].PendingEnrichment)
+	}
+}
@@ -0,0 +1,283 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/storage"
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheStore — постоянное хранилище кэша (таблица enrichment_cache в PostgreSQL).
+type CacheStore interface {
+	GetCache(ctx context.Context, key string) ([]byte, time.Time, error)
+	SetCache(ctx context.Context, key string, value []byte, expiresAt time.Time) error
+}
+
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+	Size   int
+}
+
+// Cache — LRU-кэш ответов провайдеров с TTL. При наличии store промахи
+// проверяются в постоянном хранилище, а новые значения записываются в него.
+type Cache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+	store    CacheStore
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type cacheEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func NewCache(capacity int, ttl time.Duration, store CacheStore) *Cache {
+	return &Cache{
+		ttl:      ttl,
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+		store:    store,
+	}
+}
+
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, bool) {
+	const op = "service.enrichment.cache.get"
+
+	if value, ok := c.getLocal(key); ok {
+		c.hits.Add(1)
+		logger.Debug("%s: hit %s", op, key)
+		return value, true
+	}
+
+	if c.store != nil {
+		value, expiresAt, err := c.store.GetCache(ctx, key)
+		switch {
+		case err == nil:
+			c.setLocal(key, value, expiresAt)
+			c.hits.Add(1)
+			logger.Debug("%s: store hit %s", op, key)
+			return value, true
+		case !errors.Is(err, storage.ErrCacheMiss):
+			logger.Error("%s: store lookup failed: %v", op, err)
+		}
+	}
+
+	c.misses.Add(1)
+	logger.Debug("%s: miss %s", op, key)
+	return nil, false
+}
+
+func (c *Cache) Set(ctx context.Context, key string, value []byte) {
+	const op = "service.enrichment.cache.set"
+
+	expiresAt := time.Now().Add(c.ttl)
+	c.setLocal(key, value, expiresAt)
+
+	if c.store != nil {
+		if err := c.store.SetCache(ctx, key, value, expiresAt); err != nil {
+			logger.Error("%s: store write failed: %v", op, err)
+		}
+	}
+}
+
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	size := c.order.Len()
+	c.mu.Unlock()
+
+	return CacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Size:   size,
+	}
+}
+
+func (c *Cache) getLocal(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(el)
+	return entry.value, true
+}
+
+func (c *Cache) setLocal(key string, value []byte, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*cacheEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, value: value, expiresAt: expiresAt})
+
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// cacheKey строит ключ из атрибута, провайдера, нормализованного имени и
+// подсказки страны.
+func cacheKey(attr, provider string, q Query) string {
+	name := strings.ToLower(strings.TrimSpace(q.Name))
+	country := strings.ToUpper(strings.TrimSpace(q.CountryID))
+	return attr + ":" + provider + ":" + name + ":" + country
+}
+
+func cached[R any](ctx context.Context, c *Cache, key string, fetch func() (*R, error)) (*R, error) {
+	if res, ok := cacheLookup[R](ctx, c, key); ok {
+		return res, nil
+	}
+
+	res, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	cacheStore(ctx, c, key, res)
+	return res, nil
+}
+
+// cachedBatch отдаёт найденные в кэше ответы, а промахи запрашивает одним вызовом fetch.
+func cachedBatch[R any](ctx context.Context, c *Cache, attr, provider string, qs []Query,
+	fetch func(context.Context, []Query) ([]*R, error)) ([]*R, error) {
+
+	res := make([]*R, len(qs))
+	var (
+		misses []Query
+		idx    []int
+	)
+	for i, q := range qs {
+		if r, ok := cacheLookup[R](ctx, c, cacheKey(attr, provider, q)); ok {
+			res[i] = r
+			continue
+		}
+		misses = append(misses, q)
+		idx = append(idx, i)
+	}
+
+	if len(misses) == 0 {
+		return res, nil
+	}
+
+	// при частичном ответе сохраняем то, что удалось получить
+	fetched, err := fetch(ctx, misses)
+	if fetched == nil {
+		return nil, err
+	}
+
+	for j, r := range fetched {
+		if r == nil {
+			continue
+		}
+		res[idx[j]] = r
+		cacheStore(ctx, c, cacheKey(attr, provider, misses[j]), r)
+	}
+
+	return res, err
+}
+
+func cacheLookup[R any](ctx context.Context, c *Cache, key string) (*R, bool) {
+	const op = "service.enrichment.cacheLookup"
+
+	body, ok := c.Get(ctx, key)
+	if !ok {
+		return nil, false
+	}
+
+	var res R
+	if err := json.Unmarshal(body, &res); err != nil {
+		logger.Error("%s: corrupted cache entry %s", op, key)
+		return nil, false
+	}
+	return &res, true
+}
+
+func cacheStore[R any](ctx context.Context, c *Cache, key string, res *R) {
+	const op = "service.enrichment.cacheStore"
+
+	body, err := json.Marshal(res)
+	if err != nil {
+		logger.Error("%s: failed to marshal %s: %v", op, key, err)
+		return
+	}
+	c.Set(ctx, key, body)
+}
+
+type cachedAge struct {
+	AgeProvider
+	cache *Cache
+}
+
+func (p cachedAge) Age(ctx context.Context, q Query) (*model.UserAge, error) {
+	return cached(ctx, p.cache, cacheKey("age", p.Name(), q), func() (*model.UserAge, error) {
+		return p.AgeProvider.Age(ctx, q)
+	})
+}
+
+func (p cachedAge) AgeBatch(ctx context.Context, qs []Query) ([]*model.UserAge, error) {
+	return cachedBatch(ctx, p.cache, "age", p.Name(), qs, ageBatch(p.AgeProvider))
+}
+
+type cachedGender struct {
+	GenderProvider
+	cache *Cache
+}
+
+func (p cachedGender) Gender(ctx context.Context, q Query) (*model.UserGender, error) {
+	return cached(ctx, p.cache, cacheKey("gender", p.Name(), q), func() (*model.UserGender, error) {
+		return p.GenderProvider.Gender(ctx, q)
+	})
+}
+
+func (p cachedGender) GenderBatch(ctx context.Context, qs []Query) ([]*model.UserGender, error) {
+	return cachedBatch(ctx, p.cache, "gender", p.Name(), qs, genderBatch(p.GenderProvider))
+}
+
+type cachedNationality struct {
+	NationalityProvider
+	cache *Cache
+}
+
+func (p cachedNationality) Nationality(ctx context.Context, q Query) (*model.UserNationality, error) {
+	return cached(ctx, p.cache, cacheKey("nationality", p.Name(), q), func() (*model.UserNationality, error) {
+		return p.NationalityProvider.Nationality(ctx, q)
+	})
+}
+
+func (p cachedNationality) NationalityBatch(ctx context.Context, qs []Query) ([]*model.UserNationality, error) {
+	return cachedBatch(ctx, p.cache, "nationality", p.Name(), qs, nationalityBatch(p.NationalityProvider))
+}
@@ -0,0 +1,113 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/storage"
+	"context"
+	"testing"
+	"time"
+)
+
+type mapStore map[string][]byte
+
+func (s mapStore) GetCache(ctx context.Context, key string) ([]byte, time.Time, error) {
+	v, ok := s[key]
+	if !ok {
+		return nil, time.Time{}, storage.ErrCacheMiss
+	}
+	return v, time.Now().Add(time.Hour), nil
+}
+
+func (s mapStore) SetCache(ctx context.Context, key string, value []byte, expiresAt time.Time) error {
+	s[key] = value
+	return nil
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewCache(2, time.Hour, nil)
+
+	c.Set(ctx, "a", []byte("1"))
+	c.Set(ctx, "b", []byte("2"))
+	c.Get(ctx, "a")
+	c.Set(ctx, "c", []byte("3"))
+
+	if _, ok := c.Get(ctx, "b"); ok {
+		t.Error("b survived eviction though a was used later")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(ctx, key); !ok {
+			t.Errorf("%s evicted", key)
+		}
+	}
+	if stats := c.Stats(); stats.Size != 2 || stats.Hits != 3 || stats.Misses != 1 {
+		t.Errorf("got stats %+v", stats)
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	ctx := context.Background()
+	c := NewCache(10, time.Hour, nil)
+
+	c.setLocal("old", []byte("1"), time.Now().Add(-time.Second))
+	if _, ok := c.Get(ctx, "old"); ok {
+		t.Fatal("expired entry returned")
+	}
+	if c.Stats().Size != 0 {
+		t.Fatal("expired entry kept")
+	}
+}
+
+func TestCacheFallsBackToStore(t *testing.T) {
+	ctx := context.Background()
+	store := mapStore{}
+	NewCache(10, time.Hour, store).Set(ctx, "k", []byte("v"))
+
+	// новый экземпляр, как после перезапуска, находит значение в хранилище
+	c := NewCache(10, time.Hour, store)
+	if v, ok := c.Get(ctx, "k"); !ok || string(v) != "v" {
+		t.Fatalf("got %q, %t from store", v, ok)
+	}
+	if c.Stats().Size != 1 {
+		t.Fatal("store hit was not kept locally")
+	}
+}
+
+func TestCacheKeyNormalizesNameAndCountry(t *testing.T) {
+	a := cacheKey(AttrAge, "agify", Query{Name: " Ivan ", CountryID: "ru"})
+	b := cacheKey(AttrAge, "agify", Query{Name: "ivan", CountryID: "RU", Surname: "Petrov"})
+	if a != b {
+		t.Fatalf("got different keys %q and %q", a, b)
+	}
+	if a == cacheKey(AttrAge, "agify", Query{Name: "ivan"}) {
+		t.Fatal("country is not part of the key")
+	}
+}
+
+func TestCachedBatchFetchesOnlyMisses(t *testing.T) {
+	ctx := context.Background()
+	c := NewCache(10, time.Hour, nil)
+	remote := &stub{name: "remote", ages: map[string]int{"anna": 35, "ivan": 47}}
+	p := cachedAge{AgeProvider: remote, cache: c}
+
+	if _, err := p.Age(ctx, Query{Name: "ivan"}); err != nil {
+		t.Fatalf("Age: %v", err)
+	}
+
+	res, err := p.AgeBatch(ctx, []Query{{Name: "ivan"}, {Name: "anna"}, {Name: "zzz"}})
+	if err != nil {
+		t.Fatalf("AgeBatch: %v", err)
+	}
+	if *res[0].Age != 47 || *res[1].Age != 35 || res[2] != nil {
+		t.Fatalf("got %v", res)
+	}
+
+	calls := remote.Calls()
+	if len(calls) != 3 || calls[1].Name != "anna" || calls[2].Name != "zzz" {
+		t.Fatalf("got calls %+v, want ivan once and then only the misses", calls)
+	}
+
+	// промах ErrNoMatch не кэшируется, найденное имя больше не запрашивается
+	if _, err = p.AgeBatch(ctx, []Query{{Name: "anna"}}); err != nil || len(remote.Calls()) != 3 {
+		t.Fatalf("cached anna was requested again: %v, %d calls", err, len(remote.Calls()))
+	}
+}
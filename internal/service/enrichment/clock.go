@@ -0,0 +1,20 @@
+package enrichment
+
+import "time"
+
+// clock — источник времени для повторов, circuit breaker и лимитов. В тестах
+// подменяется, чтобы задержки и окна проверялись без ожидания.
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
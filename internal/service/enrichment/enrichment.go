@@ -1,69 +1,383 @@
 package enrichment
 
 import (
+	"Effective_Mobile/internal/config"
 	"Effective_Mobile/internal/logger"
 	"Effective_Mobile/internal/model"
+	"Effective_Mobile/lib/normalize"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 )
 
-const (
-	ageEnrichURL         = "https://api.agify.io/?name="
-	genderEnrichURL      = "https://api.genderize.io/?name="
-	nationalityEnrichURL = "https://api.nationalize.io/?name="
-)
+type Service struct {
+	age         AgeProvider
+	gender      GenderProvider
+	nationality NationalityProvider
+	timeout     time.Duration
+	partial     bool
+	localize    bool
+	// reject — вернуть ErrBudgetExhausted, даже если в режиме partial атрибут можно отложить.
+	reject bool
+
+	ageThreshold         Threshold
+	genderThreshold      Threshold
+	nationalityThreshold Threshold
+}
+
+func New(age AgeProvider, gender GenderProvider, nationality NationalityProvider, cfg config.Enrichment) *Service {
+	return &Service{
+		age:         age,
+		gender:      gender,
+		nationality: nationality,
+		timeout:     cfg.Timeout,
+		partial:     cfg.Partial,
+		localize:    cfg.Localize,
+		reject:      cfg.RateLimitPolicy == PolicyReject,
+
+		ageThreshold:         Threshold{MinCount: cfg.AgeMinCount},
+		genderThreshold:      Threshold{MinProbability: cfg.GenderMinProbability, MinCount: cfg.GenderMinCount},
+		nationalityThreshold: Threshold{MinProbability: cfg.NationalityMinProbability, MinCount: cfg.NationalityMinCount},
+	}
+}
+
+// NewFromConfig собирает цепочки провайдеров из реестра по именам из конфигурации.
+// Удалённые провайдеры оборачиваются ограничителем limiter (если не nil),
+// повторами и, если cache не nil, кэшем: попадания в кэш не расходуют лимит.
+func NewFromConfig(cfg config.Enrichment, registry *Registry, cache *Cache, limiter *Limiter) (*Service, error) {
+	const op = "service.enrichment.newFromConfig"
+
+	switch cfg.RateLimitPolicy {
+	case PolicyQueue, PolicyDegrade, PolicyReject:
+	default:
+		return nil, fmt.Errorf("%s: unknown rate limit policy %q", op, cfg.RateLimitPolicy)
+	}
+
+	age, err := registry.AgeChain(cfg.AgeProviders)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	gender, err := registry.GenderChain(cfg.GenderProviders)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	nationality, err := registry.NationalityChain(cfg.NationalityProviders)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resilience := NewResilience(cfg)
+	for i, p := range age {
+		if isLocal(p) {
+			continue
+		}
+		if limiter != nil {
+			p = limitedAge{AgeProvider: p, l: limiter}
+		}
+		age[i] = resilientAge{AgeProvider: p, r: resilience}
+		if cache != nil {
+			age[i] = cachedAge{AgeProvider: age[i], cache: cache}
+		}
+	}
+	for i, p := range gender {
+		if isLocal(p) {
+			continue
+		}
+		if limiter != nil {
+			p = limitedGender{GenderProvider: p, l: limiter}
+		}
+		gender[i] = resilientGender{GenderProvider: p, r: resilience}
+		if cache != nil {
+			gender[i] = cachedGender{GenderProvider: gender[i], cache: cache}
+		}
+	}
+	for i, p := range nationality {
+		if isLocal(p) {
+			continue
+		}
+		if limiter != nil {
+			p = limitedNationality{NationalityProvider: p, l: limiter}
+		}
+		nationality[i] = resilientNationality{NationalityProvider: p, r: resilience}
+		if cache != nil {
+			nationality[i] = cachedNationality{NationalityProvider: nationality[i], cache: cache}
+		}
+	}
+
+	logger.Info("%s: providers: age=[%s] gender=[%s] nationality=[%s]",
+		op, age.Name(), gender.Name(), nationality.Name())
+
+	return New(age, gender, nationality, cfg), nil
+}
+
+func NewHTTPClient(cfg config.Enrichment) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = cfg.MaxIdleConns
+	transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+	transport.IdleConnTimeout = cfg.IdleConnTimeout
 
-func Enrich(user *model.User) error {
+	return &http.Client{
+		Transport: transport,
+		Timeout:   cfg.RequestTimeout,
+	}
+}
+
+// Enrich запрашивает пол, возраст и национальность в пределах общего дедлайна.
+// Если страна не указана в user.CountryHint и включена локализация, сначала
+// определяется национальность, а возраст и пол запрашиваются для этой страны.
+// В режиме partial ошибки отдельных провайдеров не прерывают обогащение:
+// неполученные атрибуты попадают в user.PendingEnrichment. Иначе первая
+// ошибка отменяет остальные запросы и возвращается вызывающему. Атрибуты из
+// user.Locked не запрашиваются и отмечаются в отчёте как locked.
+func (s *Service) Enrich(ctx context.Context, user *model.User) (Report, error) {
 	const op = "service.enrichment.enrich"
 	logger.Info("%s: start enrichment for user: %s", op, user.Name)
 
-	if err := EnrichGender(user); err != nil {
-		logger.Error("%s: gender enrichment failed: %v", op, err)
-		return fmt.Errorf("%s: %w", op, err)
+	parent := ctx
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
 	}
-	if err := EnrichAge(user); err != nil {
-		logger.Error("%s: age enrichment failed: %v", op, err)
-		return fmt.Errorf("%s: %w", op, err)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	q := s.queryFor(user)
+
+	nationality := unlocked(user, []task{{AttrNationality, s.fetchNationality}})
+	ageGender := unlocked(user, []task{{AttrGender, s.fetchGender}, {AttrAge, s.fetchAge}})
+
+	var (
+		tasks    []task
+		results  []result
+		firstErr error
+	)
+
+	if q.CountryID == "" && s.localize && len(nationality) > 0 {
+		tasks = nationality
+		results, firstErr = s.runAll(ctx, cancel, q, tasks)
+		if results[0].status == StatusSucceeded {
+			q.CountryID = results[0].country
+			logger.Debug("%s: localizing age and gender to %s", op, q.CountryID)
+		}
+
+		if firstErr == nil || s.partial {
+			more, err := s.runAll(ctx, cancel, q, ageGender)
+			tasks = append(tasks, ageGender...)
+			results = append(results, more...)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	} else {
+		tasks = append(ageGender, nationality...)
+		results, firstErr = s.runAll(ctx, cancel, q, tasks)
 	}
-	if err := EnrichNationality(user); err != nil {
-		logger.Error("%s: nationality enrichment failed: %v", op, err)
-		return fmt.Errorf("%s: %w", op, err)
+
+	report := finish(user, append(results, lockedResults(user)...))
+
+	if err := parent.Err(); err != nil {
+		return report, fmt.Errorf("%s: %w", op, err)
+	}
+	if firstErr != nil && !s.partial {
+		return report, fmt.Errorf("%s: %w: %w", op, ErrUpstream, firstErr)
+	}
+	if s.reject {
+		for _, r := range results {
+			if errors.Is(r.err, ErrBudgetExhausted) {
+				return report, fmt.Errorf("%s: %w", op, r.err)
+			}
+		}
 	}
 
-	logger.Info("%s: enrichment complete for user: %s", op, user.Name)
-	return nil
+	logger.Info("%s: enrichment complete for user %s: %v", op, user.Name, report)
+	return report, nil
+}
+
+type task struct {
+	attr  string
+	fetch func(context.Context, Query) (outcome, error)
 }
 
-func FetchBody(url string, context string) ([]byte, error) {
+type result struct {
+	outcome
+	attr string
+	err  error
+}
+
+// queryFor строит запрос к провайдерам. Заданная вручную национальность
+// используется для локализации, если подсказки страны нет.
+func (s *Service) queryFor(user *model.User) Query {
+	q := Query{Name: normalize.QueryName(user.Name), Surname: user.Surname}
+	if user.Patronymic != nil {
+		q.Patronymic = *user.Patronymic
+	}
+	if user.CountryHint != nil {
+		q.CountryID = strings.ToUpper(strings.TrimSpace(*user.CountryHint))
+	}
+	if q.CountryID == "" && s.localize && locked(user, AttrNationality) && user.Nationality != nil {
+		q.CountryID = *user.Nationality
+	}
+	return q
+}
+
+// finish записывает полученные атрибуты в пользователя, выставляет статус
+// обогащения и возвращает отчёт.
+func finish(user *model.User, results []result) Report {
+	if user.Enrichment == nil {
+		user.Enrichment = &model.Enrichment{}
+	}
+
+	report := make(Report, len(results))
+	for _, r := range results {
+		switch {
+		case errors.Is(r.err, ErrNoProviders):
+			report[r.attr] = StatusSkipped
+		case errors.Is(r.err, ErrNoMatch):
+			report[r.attr] = StatusLowConfidence
+		case r.err != nil:
+			report[r.attr] = StatusFailed
+		default:
+			if r.apply != nil {
+				r.apply(user)
+			}
+			report[r.attr] = r.status
+		}
+	}
+
+	now := time.Now().UTC()
+	user.EnrichedAt = &now
+	user.PendingEnrichment = report.Pending()
+	user.EnrichmentStatus = model.EnrichmentComplete
+	if len(user.PendingEnrichment) > 0 {
+		user.EnrichmentStatus = model.EnrichmentPartial
+	}
+
+	return report
+}
+
+// runAll выполняет задачи параллельно и возвращает первую ошибку провайдера.
+// Без partial первая ошибка отменяет остальные задачи. ErrNoProviders и
+// ErrNoMatch ошибками не считаются.
+func (s *Service) runAll(ctx context.Context, cancel context.CancelFunc, q Query, tasks []task) ([]result, error) {
+	const op = "service.enrichment.runAll"
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		results  = make([]result, len(tasks))
+	)
+
+	for i, t := range tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i].attr = t.attr
+			results[i].outcome, results[i].err = t.fetch(ctx, q)
+			// отсутствие ответа — не отказ провайдера: атрибут станет
+			// skipped или low_confidence, остальные задачи не отменяются
+			if results[i].err == nil || errors.Is(results[i].err, ErrNoProviders) ||
+				errors.Is(results[i].err, ErrNoMatch) {
+				return
+			}
+
+			logger.Error("%s: %s enrichment failed: %v", op, t.attr, results[i].err)
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = results[i].err
+			}
+			mu.Unlock()
+			if !s.partial {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results, firstErr
+}
+
+// providerClient возвращает клиент с собственным таймаутом провайдера, если он
+// задан. Транспорт (пул соединений) остаётся общим.
+func providerClient(client *http.Client, timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		return client
+	}
+	c := *client
+	c.Timeout = timeout
+	return &c
+}
+
+// redact скрывает ключ API в адресе перед записью в лог.
+func redact(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	if !q.Has("apikey") {
+		return rawURL
+	}
+	q.Set("apikey", "REDACTED")
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// redactErr скрывает ключ API в адресе, который net/http добавляет в текст ошибки.
+func redactErr(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = redact(urlErr.URL)
+	}
+	return err
+}
+
+func FetchBody(ctx context.Context, client *http.Client, rawURL string, kind string) ([]byte, error) {
 	const op = "service.enrichment.FetchBody"
-	logger.Debug("%s: sending GET request to %s", op, url)
+	logger.Debug("%s: sending GET request to %s", op, redact(rawURL))
 
-	res, err := http.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
 	if err != nil {
-		logger.Error("%s: failed GET request for %s: %v", op, context, err)
-		return nil, fmt.Errorf("%s: %w", op+context, err)
+		err = redactErr(err)
+		logger.Error("%s: failed to build request for %s: %v", op, kind, err)
+		return nil, fmt.Errorf("%s: %w", op+kind, err)
 	}
 
-	if res.StatusCode != http.StatusOK {
-		logger.Error("%s: %s returned status code %d", op, context, res.StatusCode)
-		return nil, fmt.Errorf("%s: unexpected status code: %d", op+context, res.StatusCode)
+	res, err := client.Do(req)
+	if err != nil {
+		err = redactErr(err)
+		logger.Error("%s: failed GET request for %s: %v", op, kind, err)
+		return nil, fmt.Errorf("%s: %w", op+kind, err)
 	}
 
 	defer func() {
 		if cerr := res.Body.Close(); cerr != nil {
-			logger.Error("%s: failed to close response body for %s: %v", op, context, cerr)
+			logger.Error("%s: failed to close response body for %s: %v", op, kind, cerr)
 		} else {
-			logger.Debug("%s: closed response body for %s", op, context)
+			logger.Debug("%s: closed response body for %s", op, kind)
 		}
 	}()
 
+	if res.StatusCode != http.StatusOK {
+		logger.Error("%s: %s returned status code %d", op, kind, res.StatusCode)
+		return nil, fmt.Errorf("%s: %w", op+kind, newStatusError(res))
+	}
+
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
-		logger.Error("%s: failed to read response body for %s: %v", op, context, err)
-		return nil, fmt.Errorf("%s: %w", op+context, err)
+		logger.Error("%s: failed to read response body for %s: %v", op, kind, err)
+		return nil, fmt.Errorf("%s: %w", op+kind, err)
 	}
 
-	logger.Debug("%s: response body fetched for %s: %s", op, context, string(body))
+	logger.Debug("%s: response body fetched for %s: %s", op, kind, string(body))
 	return body, nil
 }
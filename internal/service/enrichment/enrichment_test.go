@@ -0,0 +1,196 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/model"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newStub(name string) *stub {
+	return &stub{
+		name:      name,
+		ages:      map[string]int{"Ivan": 47, "Anna": 35},
+		genders:   map[string]string{"Ivan": "male", "Anna": "female"},
+		countries: map[string]string{"Ivan": "RU", "Anna": "RU"},
+	}
+}
+
+func TestEnrichPartialKeepsOtherAttributes(t *testing.T) {
+	down := errors.New("agify is down")
+	age := &stub{name: "agify", err: down}
+	s := New(age, newStub("genderize"), newStub("nationalize"), config.Enrichment{Partial: true})
+
+	user := &model.User{Name: "Ivan"}
+	report, err := s.Enrich(context.Background(), user)
+	if err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+
+	if report[AttrAge] != StatusFailed || report[AttrGender] != StatusSucceeded || report[AttrNationality] != StatusSucceeded {
+		t.Fatalf("got report %v", report)
+	}
+	if user.Age != nil || *user.Gender != "male" || *user.Nationality != "RU" {
+		t.Fatalf("got age %v, gender %v, nationality %v", user.Age, user.Gender, user.Nationality)
+	}
+	if user.EnrichmentStatus != model.EnrichmentPartial || !slices.Equal(user.PendingEnrichment, []string{AttrAge}) {
+		t.Fatalf("got status %q, pending %v", user.EnrichmentStatus, user.PendingEnrichment)
+	}
+	if user.EnrichedAt == nil {
+		t.Fatal("failed attempt did not set EnrichedAt")
+	}
+}
+
+func TestEnrichStrictCancelsOnFirstError(t *testing.T) {
+	down := errors.New("agify is down")
+	slow := newStub("genderize")
+	slow.delay = 5 * time.Second
+	s := New(&stub{name: "agify", err: down}, slow, newStub("nationalize"), config.Enrichment{})
+
+	start := time.Now()
+	report, err := s.Enrich(context.Background(), &model.User{Name: "Ivan"})
+	if !errors.Is(err, ErrUpstream) || !errors.Is(err, down) {
+		t.Fatalf("got %v, want ErrUpstream wrapping the provider error", err)
+	}
+	// ошибка agify отменяет ожидание genderize
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Enrich waited %s for the other providers", elapsed)
+	}
+	if report[AttrGender] != StatusFailed {
+		t.Fatalf("got gender %q, want the canceled request failed", report[AttrGender])
+	}
+}
+
+func TestEnrichAllFailedIsPartial(t *testing.T) {
+	down := errors.New("down")
+	s := New(&stub{err: down}, &stub{err: down}, &stub{err: down}, config.Enrichment{Partial: true})
+
+	user := &model.User{Name: "Ivan"}
+	if _, err := s.Enrich(context.Background(), user); err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+	want := []string{AttrAge, AttrGender, AttrNationality}
+	if user.EnrichmentStatus != model.EnrichmentPartial || !slices.Equal(user.PendingEnrichment, want) {
+		t.Fatalf("got status %q, pending %v, want all attributes pending", user.EnrichmentStatus, user.PendingEnrichment)
+	}
+}
+
+func TestEnrichRecordsProvenance(t *testing.T) {
+	p := newStub("remote")
+	p.counts = 42
+	s := New(p, p, p, config.Enrichment{})
+
+	user := &model.User{Name: "Anna"}
+	if _, err := s.Enrich(context.Background(), user); err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+
+	e := user.Enrichment
+	if e.Age.Provider != "remote" || e.Age.Count != 42 || e.Age.FetchedAt.IsZero() {
+		t.Errorf("age: got %+v", e.Age)
+	}
+	if e.Gender.Probability == nil || *e.Gender.Probability != 0.99 || e.Gender.Count != 42 {
+		t.Errorf("gender: got %+v", e.Gender)
+	}
+	// у национальности сохраняется всё распределение, а вероятность — у первой страны
+	if len(e.Nationality.Countries) != 1 || e.Nationality.Probability == nil || *e.Nationality.Probability != 0.9 {
+		t.Errorf("nationality: got %+v", e.Nationality)
+	}
+	if e.Age.LowConfidence || e.Gender.LowConfidence || e.Nationality.LowConfidence {
+		t.Error("accepted answers marked low confidence")
+	}
+}
+
+func TestEnrichLocalizesByNationality(t *testing.T) {
+	age, gender, nationality := newStub("agify"), newStub("genderize"), newStub("nationalize")
+	nationality.countries["Anna"] = "UA"
+	s := New(age, gender, nationality, config.Enrichment{Localize: true})
+
+	user := &model.User{Name: "Anna"}
+	if _, err := s.Enrich(context.Background(), user); err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+
+	if calls := nationality.Calls(); len(calls) != 1 || calls[0].CountryID != "" {
+		t.Fatalf("nationalize got %+v, want one request without a country", calls)
+	}
+	for _, p := range []*stub{age, gender} {
+		if calls := p.Calls(); len(calls) != 1 || calls[0].CountryID != "UA" {
+			t.Errorf("%s got %+v, want a request localized to UA", p.name, calls)
+		}
+	}
+	if user.Enrichment.Age.CountryID != "UA" {
+		t.Errorf("got age country %q, want UA in provenance", user.Enrichment.Age.CountryID)
+	}
+}
+
+func TestEnrichLocalizationSources(t *testing.T) {
+	hint, locked := " ua ", "KZ"
+
+	tests := []struct {
+		name    string
+		user    model.User
+		cfg     config.Enrichment
+		country string
+	}{
+		{"hint wins over nationality", model.User{Name: "Anna", CountryHint: &hint}, config.Enrichment{Localize: true}, "UA"},
+		{"hint without localization", model.User{Name: "Anna", CountryHint: &hint}, config.Enrichment{}, "UA"},
+		{"manual nationality", model.User{Name: "Anna", Nationality: &locked, Locked: []string{AttrNationality}},
+			config.Enrichment{Localize: true}, "KZ"},
+		{"localization off", model.User{Name: "Anna"}, config.Enrichment{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			age, nationality := newStub("agify"), newStub("nationalize")
+			s := New(age, newStub("genderize"), nationality, tt.cfg)
+
+			if _, err := s.Enrich(context.Background(), &tt.user); err != nil {
+				t.Fatalf("Enrich: %v", err)
+			}
+			if calls := age.Calls(); len(calls) != 1 || calls[0].CountryID != tt.country {
+				t.Fatalf("agify got %+v, want country %q", calls, tt.country)
+			}
+		})
+	}
+}
+
+func TestEnrichWithoutNationalityIsNotLocalized(t *testing.T) {
+	age := newStub("agify")
+	s := New(age, newStub("genderize"), &stub{name: "nationalize", err: errors.New("down")},
+		config.Enrichment{Localize: true, Partial: true})
+
+	report, err := s.Enrich(context.Background(), &model.User{Name: "Anna"})
+	if err != nil {
+		t.Fatalf("Enrich: %v", err)
+	}
+	// национальность не получена, но возраст и пол всё равно запрашиваются
+	if calls := age.Calls(); len(calls) != 1 || calls[0].CountryID != "" {
+		t.Fatalf("agify got %+v, want one request without a country", calls)
+	}
+	if report[AttrAge] != StatusSucceeded || report[AttrNationality] != StatusFailed {
+		t.Fatalf("got report %v", report)
+	}
+}
+
+func TestEnrichStrictNoMatchIsNotAFailure(t *testing.T) {
+	age := &stub{name: "offline", local: true, ages: map[string]int{}}
+	slow := newStub("genderize")
+	slow.delay = 20 * time.Millisecond
+	s := New(AgeChain{age}, slow, newStub("nationalize"), config.Enrichment{Localize: true})
+
+	user := &model.User{Name: "Anna"}
+	report, err := s.Enrich(context.Background(), user)
+	if err != nil {
+		t.Fatalf("Enrich: got %v, want a name without an answer to be low confidence", err)
+	}
+	// промах не отменяет другие запросы
+	if report[AttrAge] != StatusLowConfidence || report[AttrGender] != StatusSucceeded {
+		t.Fatalf("got report %v", report)
+	}
+	if user.Age != nil || len(user.PendingEnrichment) != 0 || user.EnrichmentStatus != model.EnrichmentComplete {
+		t.Fatalf("got age %v, pending %v, status %q", user.Age, user.PendingEnrichment, user.EnrichmentStatus)
+	}
+}
@@ -0,0 +1,36 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/model"
+	"context"
+	"net/http"
+)
+
+const genderEnrichURL = "https://api.genderize.io/"
+
+type Genderize struct {
+	api apiProvider
+}
+
+// NewGenderize создаёт провайдера по настройкам cfg; пустой URL — публичный адрес API.
+func NewGenderize(client *http.Client, cfg config.Provider) *Genderize {
+	return &Genderize{api: newAPIProvider("genderize", "Gender", genderEnrichURL, true, client, cfg)}
+}
+
+func (p *Genderize) Name() string {
+	return p.api.name
+}
+
+func (p *Genderize) Gender(ctx context.Context, q Query) (*model.UserGender, error) {
+	return fetchOne(ctx, p.api, q, setGenderSource)
+}
+
+// GenderBatch запрашивает пол для нескольких имён одним вызовом.
+func (p *Genderize) GenderBatch(ctx context.Context, qs []Query) ([]*model.UserGender, error) {
+	return fetchAPI(ctx, p.api, qs, setGenderSource)
+}
+
+func setGenderSource(r *model.UserGender, s model.Source) {
+	r.Source = s
+}
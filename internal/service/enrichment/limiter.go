@@ -0,0 +1,268 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrBudgetExhausted = errors.New("provider request budget exhausted")
+
+// Политики при исчерпании бюджета запросов.
+const (
+	// PolicyQueue — ждать свободного токена не дольше RateLimitMaxWait.
+	PolicyQueue = "queue"
+	// PolicyDegrade — не ждать: атрибут остаётся в pending, как при ошибке провайдера.
+	PolicyDegrade = "degrade"
+	// PolicyReject — не ждать и отклонить синхронный запрос с 503.
+	PolicyReject = "reject"
+)
+
+// BudgetError — запрос к провайдеру не отправлен из-за лимита.
+type BudgetError struct {
+	Provider string
+	// Daily — исчерпана суточная квота, а не лимит в секунду.
+	Daily      bool
+	RetryAfter time.Duration
+}
+
+func (e *BudgetError) Error() string {
+	if e.Daily {
+		return fmt.Sprintf("%s: daily quota exhausted", e.Provider)
+	}
+	return fmt.Sprintf("%s: rate limit exceeded, retry in %s", e.Provider, e.RetryAfter)
+}
+
+func (e *BudgetError) Unwrap() error {
+	return ErrBudgetExhausted
+}
+
+// QuotaStore — постоянный счётчик суточных квот (таблица provider_quota в PostgreSQL).
+type QuotaStore interface {
+	// ConsumeQuota увеличивает счётчик провайдера за day на n, если он не
+	// превысит limit, и сообщает, удалось ли это.
+	ConsumeQuota(ctx context.Context, provider string, day time.Time, n, limit int) (bool, error)
+}
+
+// Limiter ограничивает исходящие запросы: token bucket на запросы в секунду
+// и суточная квота на число имён для каждого провайдера.
+type Limiter struct {
+	policy  string
+	maxWait time.Duration
+	store   QuotaStore
+	clock   clock
+
+	limits map[string]config.Provider
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	usage   map[string]dailyUsage
+}
+
+type dailyUsage struct {
+	day  time.Time
+	used int
+}
+
+func NewLimiter(cfg config.Enrichment, store QuotaStore) *Limiter {
+	return &Limiter{
+		policy:  cfg.RateLimitPolicy,
+		maxWait: cfg.RateLimitMaxWait,
+		store:   store,
+		clock:   realClock{},
+		limits: map[string]config.Provider{
+			"agify":       cfg.Agify,
+			"genderize":   cfg.Genderize,
+			"nationalize": cfg.Nationalize,
+		},
+		buckets: make(map[string]*bucket),
+		usage:   make(map[string]dailyUsage),
+	}
+}
+
+// Acquire резервирует один запрос на names имён. По политике queue ждёт
+// токен; если ждать дольше maxWait или квота исчерпана, возвращает BudgetError.
+func (l *Limiter) Acquire(ctx context.Context, provider string, names int) error {
+	const op = "service.enrichment.limiter.acquire"
+
+	limit := l.limits[provider]
+
+	if b := l.bucket(provider, limit); b != nil {
+		maxWait := time.Duration(0)
+		if l.policy == PolicyQueue {
+			maxWait = l.maxWait
+			if deadline, ok := ctx.Deadline(); ok {
+				maxWait = min(maxWait, deadline.Sub(l.clock.Now()))
+			}
+		}
+
+		wait, ok := b.reserve(l.clock.Now(), maxWait)
+		if !ok {
+			logger.Info("%s: %s rate limit exceeded", op, provider)
+			return &BudgetError{Provider: provider, RetryAfter: wait}
+		}
+		if wait > 0 {
+			logger.Debug("%s: %s waiting %s for a token", op, provider, wait)
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%s: %w", op, ctx.Err())
+			case <-l.clock.After(wait):
+			}
+		}
+	}
+
+	if limit.DailyQuota <= 0 {
+		return nil
+	}
+
+	ok, err := l.consume(ctx, provider, names, limit.DailyQuota)
+	if err != nil {
+		// недоступность счётчика не должна останавливать обогащение
+		logger.Error("%s: %s quota check failed: %v", op, provider, err)
+		return nil
+	}
+	if !ok {
+		logger.Info("%s: %s daily quota of %d names exhausted", op, provider, limit.DailyQuota)
+		return &BudgetError{Provider: provider, Daily: true, RetryAfter: untilTomorrow(l.clock.Now())}
+	}
+	return nil
+}
+
+func (l *Limiter) bucket(provider string, limit config.Provider) *bucket {
+	if limit.RateLimit <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[provider]
+	if !ok {
+		burst := float64(max(limit.RateBurst, 1))
+		b = &bucket{rate: limit.RateLimit, burst: burst, tokens: burst, last: l.clock.Now()}
+		l.buckets[provider] = b
+	}
+	return b
+}
+
+func (l *Limiter) consume(ctx context.Context, provider string, n, limit int) (bool, error) {
+	day := today(l.clock.Now())
+	if l.store != nil {
+		return l.store.ConsumeQuota(ctx, provider, day, n, limit)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	u := l.usage[provider]
+	if !u.day.Equal(day) {
+		u = dailyUsage{day: day}
+	}
+	if u.used+n > limit {
+		return false, nil
+	}
+	u.used += n
+	l.usage[provider] = u
+	return true, nil
+}
+
+func today(now time.Time) time.Time {
+	y, m, d := now.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func untilTomorrow(now time.Time) time.Duration {
+	return today(now).AddDate(0, 0, 1).Sub(now)
+}
+
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// reserve забирает токен и возвращает, сколько ждать до его появления.
+// Если ждать дольше maxWait, токен не забирается и возвращается false.
+func (b *bucket) reserve(now time.Time, maxWait time.Duration) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+
+	wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	if wait > maxWait {
+		return wait, false
+	}
+	b.tokens--
+	return wait, true
+}
+
+type limitedAge struct {
+	AgeProvider
+	l *Limiter
+}
+
+func (p limitedAge) Age(ctx context.Context, q Query) (*model.UserAge, error) {
+	if err := p.l.Acquire(ctx, p.Name(), 1); err != nil {
+		return nil, err
+	}
+	return p.AgeProvider.Age(ctx, q)
+}
+
+func (p limitedAge) AgeBatch(ctx context.Context, qs []Query) ([]*model.UserAge, error) {
+	if err := p.l.Acquire(ctx, p.Name(), len(qs)); err != nil {
+		return nil, err
+	}
+	return ageBatch(p.AgeProvider)(ctx, qs)
+}
+
+type limitedGender struct {
+	GenderProvider
+	l *Limiter
+}
+
+func (p limitedGender) Gender(ctx context.Context, q Query) (*model.UserGender, error) {
+	if err := p.l.Acquire(ctx, p.Name(), 1); err != nil {
+		return nil, err
+	}
+	return p.GenderProvider.Gender(ctx, q)
+}
+
+func (p limitedGender) GenderBatch(ctx context.Context, qs []Query) ([]*model.UserGender, error) {
+	if err := p.l.Acquire(ctx, p.Name(), len(qs)); err != nil {
+		return nil, err
+	}
+	return genderBatch(p.GenderProvider)(ctx, qs)
+}
+
+type limitedNationality struct {
+	NationalityProvider
+	l *Limiter
+}
+
+func (p limitedNationality) Nationality(ctx context.Context, q Query) (*model.UserNationality, error) {
+	if err := p.l.Acquire(ctx, p.Name(), 1); err != nil {
+		return nil, err
+	}
+	return p.NationalityProvider.Nationality(ctx, q)
+}
+
+func (p limitedNationality) NationalityBatch(ctx context.Context, qs []Query) ([]*model.UserNationality, error) {
+	if err := p.l.Acquire(ctx, p.Name(), len(qs)); err != nil {
+		return nil, err
+	}
+	return nationalityBatch(p.NationalityProvider)(ctx, qs)
+}
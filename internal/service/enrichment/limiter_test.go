@@ -0,0 +1,200 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/model"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newTestLimiter возвращает Limiter на fakeClock с настройками agify для провайдера agify.
+func newTestLimiter(policy string, maxWait time.Duration, agify config.Provider, store QuotaStore) (*Limiter, *fakeClock) {
+	clock := newFakeClock()
+	l := NewLimiter(config.Enrichment{RateLimitPolicy: policy, RateLimitMaxWait: maxWait, Agify: agify}, store)
+	l.clock = clock
+	return l, clock
+}
+
+// quotaStore считает имена по дням, как таблица provider_quota.
+type quotaStore struct {
+	used map[time.Time]int
+	days []time.Time
+	err  error
+}
+
+func (s *quotaStore) ConsumeQuota(ctx context.Context, provider string, day time.Time, n, limit int) (bool, error) {
+	s.days = append(s.days, day)
+	if s.err != nil {
+		return false, s.err
+	}
+	if s.used[day]+n > limit {
+		return false, nil
+	}
+	s.used[day] += n
+	return true, nil
+}
+
+func TestBucketRefillAndBurst(t *testing.T) {
+	start := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	b := &bucket{rate: 2, burst: 3, tokens: 3, last: start}
+
+	for i := 0; i < 3; i++ {
+		if wait, ok := b.reserve(start, 0); !ok || wait != 0 {
+			t.Fatalf("request %d within burst: got %s, %t", i+1, wait, ok)
+		}
+	}
+	if wait, ok := b.reserve(start, 0); ok || wait != 500*time.Millisecond {
+		t.Fatalf("request over burst: got %s, %t, want to wait 500ms", wait, ok)
+	}
+
+	// за 250 мс набирается половина токена
+	if wait, ok := b.reserve(start.Add(250*time.Millisecond), 0); ok || wait != 250*time.Millisecond {
+		t.Fatalf("got %s, %t, want to wait 250ms more", wait, ok)
+	}
+	if _, ok := b.reserve(start.Add(500*time.Millisecond), 0); !ok {
+		t.Fatal("no token after refill")
+	}
+
+	// простой не копит больше burst
+	idle := start.Add(time.Minute)
+	for i := 0; i < 3; i++ {
+		if _, ok := b.reserve(idle, 0); !ok {
+			t.Fatalf("request %d after idle: no token", i+1)
+		}
+	}
+	if _, ok := b.reserve(idle, 0); ok {
+		t.Fatal("idle bucket gave more than burst tokens")
+	}
+}
+
+func TestLimiterPolicies(t *testing.T) {
+	tests := []struct {
+		policy  string
+		err     error
+		age     Status
+		pending []string
+		sleeps  []time.Duration
+	}{
+		{PolicyQueue, nil, StatusSucceeded, []string{}, []time.Duration{time.Second}},
+		{PolicyDegrade, nil, StatusFailed, []string{AttrAge}, nil},
+		{PolicyReject, ErrBudgetExhausted, StatusFailed, []string{AttrAge}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			l, clock := newTestLimiter(tt.policy, 2*time.Second, config.Provider{RateLimit: 1, RateBurst: 1}, nil)
+			cfg := config.Enrichment{Partial: true, RateLimitPolicy: tt.policy}
+			s := New(limitedAge{AgeProvider: newStub("agify"), l: l}, newStub("genderize"), newStub("nationalize"), cfg)
+
+			if _, err := s.Enrich(context.Background(), &model.User{Name: "Ivan"}); err != nil {
+				t.Fatalf("first Enrich: %v", err)
+			}
+
+			// второй запрос к agify в ту же секунду упирается в лимит
+			user := &model.User{Name: "Anna"}
+			report, err := s.Enrich(context.Background(), user)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if report[AttrAge] != tt.age || !slices.Equal(user.PendingEnrichment, tt.pending) {
+				t.Errorf("got age %q, pending %v, want %q, %v", report[AttrAge], user.PendingEnrichment, tt.age, tt.pending)
+			}
+			if !slices.Equal(clock.Sleeps(), tt.sleeps) {
+				t.Errorf("got waits %v, want %v", clock.Sleeps(), tt.sleeps)
+			}
+		})
+	}
+}
+
+func TestLimiterMaxWait(t *testing.T) {
+	l, clock := newTestLimiter(PolicyQueue, 300*time.Millisecond, config.Provider{RateLimit: 1, RateBurst: 1}, nil)
+	ctx := context.Background()
+
+	if err := l.Acquire(ctx, "agify", 1); err != nil {
+		t.Fatalf("first Acquire: %v", err)
+	}
+
+	// токен появится через секунду, а ждать можно только RATE_LIMIT_MAX_WAIT
+	var budgetErr *BudgetError
+	if err := l.Acquire(ctx, "agify", 1); !errors.As(err, &budgetErr) {
+		t.Fatalf("got %v, want BudgetError", err)
+	}
+	if budgetErr.Daily || budgetErr.RetryAfter != time.Second {
+		t.Errorf("got %+v, want the rate limit with Retry-After 1s", budgetErr)
+	}
+	if len(clock.Sleeps()) != 0 {
+		t.Errorf("waited %v beyond RATE_LIMIT_MAX_WAIT", clock.Sleeps())
+	}
+
+	// пропущенный запрос не забрал токен: через 750 мс ждать остаётся 250 мс
+	clock.Advance(750 * time.Millisecond)
+	if err := l.Acquire(ctx, "agify", 1); err != nil {
+		t.Fatalf("Acquire within RATE_LIMIT_MAX_WAIT: %v", err)
+	}
+	if want := []time.Duration{250 * time.Millisecond}; !slices.Equal(clock.Sleeps(), want) {
+		t.Errorf("got waits %v, want %v", clock.Sleeps(), want)
+	}
+
+	// у провайдера без лимита ожидания нет
+	if err := l.Acquire(ctx, "genderize", 1); err != nil {
+		t.Fatalf("genderize: %v", err)
+	}
+}
+
+func TestLimiterDailyQuotaRollsOverAtUTCMidnight(t *testing.T) {
+	l, clock := newTestLimiter(PolicyQueue, time.Second, config.Provider{DailyQuota: 3}, nil)
+	ctx := context.Background()
+
+	// 01:00 по Москве — ещё 22:00 предыдущего дня по UTC
+	clock.now = time.Date(2024, 3, 11, 1, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+
+	if err := l.Acquire(ctx, "agify", 2); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	var budgetErr *BudgetError
+	if err := l.Acquire(ctx, "agify", 2); !errors.As(err, &budgetErr) || !budgetErr.Daily {
+		t.Fatalf("got %v, want the daily quota exhausted", err)
+	}
+	if budgetErr.RetryAfter != 2*time.Hour {
+		t.Errorf("got Retry-After %s, want 2h until UTC midnight", budgetErr.RetryAfter)
+	}
+	if err := l.Acquire(ctx, "agify", 1); err != nil {
+		t.Fatalf("rejected batch consumed the quota: %v", err)
+	}
+
+	clock.Advance(2 * time.Hour)
+	if err := l.Acquire(ctx, "agify", 3); err != nil {
+		t.Fatalf("quota did not roll over at UTC midnight: %v", err)
+	}
+}
+
+func TestLimiterConsumesQuotaFromStore(t *testing.T) {
+	store := &quotaStore{used: make(map[time.Time]int)}
+	l, clock := newTestLimiter(PolicyQueue, time.Second, config.Provider{DailyQuota: 2}, store)
+	ctx := context.Background()
+
+	if err := l.Acquire(ctx, "agify", 2); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if err := l.Acquire(ctx, "agify", 1); !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("got %v, want the stored quota exhausted", err)
+	}
+	clock.Advance(12 * time.Hour)
+	if err := l.Acquire(ctx, "agify", 1); err != nil {
+		t.Fatalf("next day: %v", err)
+	}
+
+	day := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	want := []time.Time{day, day, day.AddDate(0, 0, 1)}
+	if !slices.EqualFunc(store.days, want, time.Time.Equal) {
+		t.Errorf("got days %v, want UTC midnights %v", store.days, want)
+	}
+
+	// недоступный счётчик не останавливает обогащение
+	store.err = errors.New("db is down")
+	if err := l.Acquire(ctx, "agify", 5); err != nil {
+		t.Errorf("got %v with the quota store down, want the request allowed", err)
+	}
+}
@@ -0,0 +1,37 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/model"
+	"context"
+	"net/http"
+)
+
+const nationalityEnrichURL = "https://api.nationalize.io/"
+
+// Nationalize не принимает country_id: национальность определяется по имени.
+type Nationalize struct {
+	api apiProvider
+}
+
+// NewNationalize создаёт провайдера по настройкам cfg; пустой URL — публичный адрес API.
+func NewNationalize(client *http.Client, cfg config.Provider) *Nationalize {
+	return &Nationalize{api: newAPIProvider("nationalize", "Nationality", nationalityEnrichURL, false, client, cfg)}
+}
+
+func (p *Nationalize) Name() string {
+	return p.api.name
+}
+
+func (p *Nationalize) Nationality(ctx context.Context, q Query) (*model.UserNationality, error) {
+	return fetchOne(ctx, p.api, q, setNationalitySource)
+}
+
+// NationalityBatch запрашивает национальность для нескольких имён одним вызовом.
+func (p *Nationalize) NationalityBatch(ctx context.Context, qs []Query) ([]*model.UserNationality, error) {
+	return fetchAPI(ctx, p.api, qs, setNationalitySource)
+}
+
+func setNationalitySource(r *model.UserNationality, s model.Source) {
+	r.Source = s
+}
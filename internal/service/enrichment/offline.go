@@ -0,0 +1,265 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrDatasetFormat = errors.New("unsupported dataset format")
+
+// DatasetEntry — статистика по имени. Записи с CountryID используются для
+// локализованных запросов, запись без страны — для всех остальных.
+type DatasetEntry struct {
+	Name              string          `json:"name"`
+	CountryID         string          `json:"country_id,omitempty"`
+	Count             int             `json:"count"`
+	Gender            string          `json:"gender,omitempty"`
+	GenderProbability float64         `json:"gender_probability"`
+	Age               *int            `json:"age,omitempty"`
+	Countries         []model.Country `json:"countries,omitempty"`
+}
+
+// Offline отвечает по локальному набору данных, загруженному в память.
+// На неизвестные имена возвращается ErrNoMatch, и цепочка опрашивает
+// следующего провайдера.
+type Offline struct {
+	entries map[datasetKey]DatasetEntry
+}
+
+type datasetKey struct {
+	name    string
+	country string
+}
+
+func NewOffline(entries []DatasetEntry) *Offline {
+	p := &Offline{entries: make(map[datasetKey]DatasetEntry, len(entries))}
+	for _, e := range entries {
+		// национальность выбирается по первой стране, поэтому сортируем по убыванию
+		sort.SliceStable(e.Countries, func(i, j int) bool {
+			return e.Countries[i].Probability > e.Countries[j].Probability
+		})
+		p.entries[newDatasetKey(e.Name, e.CountryID)] = e
+	}
+	return p
+}
+
+// LoadOffline читает набор данных из CSV или JSON (по расширению файла).
+func LoadOffline(path string) (*Offline, error) {
+	const op = "service.enrichment.loadOffline"
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer f.Close()
+
+	var entries []DatasetEntry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.NewDecoder(f).Decode(&entries)
+	case ".csv":
+		entries, err = readDatasetCSV(f)
+	default:
+		err = fmt.Errorf("%w: %s", ErrDatasetFormat, path)
+	}
+	if err != nil {
+		logger.Error("%s: failed to load dataset %s: %v", op, path, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("%s: loaded %d names from %s", op, len(entries), path)
+	return NewOffline(entries), nil
+}
+
+// readDatasetCSV разбирает CSV с заголовком
+// name,country_id,count,gender,gender_probability,age,countries,
+// где countries — список вида "RU:0.61;UA:0.2". Порядок колонок любой.
+func readDatasetCSV(r io.Reader) ([]DatasetEntry, error) {
+	const op = "service.enrichment.readDatasetCSV"
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("%s: %w: missing name column", op, ErrDatasetFormat)
+	}
+
+	var entries []DatasetEntry
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		get := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		e := DatasetEntry{
+			Name:      get("name"),
+			CountryID: get("country_id"),
+			Gender:    get("gender"),
+		}
+		if e.Count, err = atoiOrZero(get("count")); err != nil {
+			return nil, fmt.Errorf("%s: %s: count: %w", op, e.Name, err)
+		}
+		if v := get("gender_probability"); v != "" {
+			if e.GenderProbability, err = strconv.ParseFloat(v, 64); err != nil {
+				return nil, fmt.Errorf("%s: %s: gender_probability: %w", op, e.Name, err)
+			}
+		}
+		if v := get("age"); v != "" {
+			age, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: age: %w", op, e.Name, err)
+			}
+			e.Age = &age
+		}
+		if e.Countries, err = parseCountries(get("countries")); err != nil {
+			return nil, fmt.Errorf("%s: %s: countries: %w", op, e.Name, err)
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+func parseCountries(v string) ([]model.Country, error) {
+	if v == "" {
+		return nil, nil
+	}
+
+	var countries []model.Country
+	for _, part := range strings.Split(v, ";") {
+		id, prob, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrDatasetFormat, part)
+		}
+		p, err := strconv.ParseFloat(prob, 64)
+		if err != nil {
+			return nil, err
+		}
+		countries = append(countries, model.Country{CountryID: strings.ToUpper(id), Probability: p})
+	}
+	return countries, nil
+}
+
+func atoiOrZero(v string) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
+
+func newDatasetKey(name, country string) datasetKey {
+	return datasetKey{
+		name:    strings.ToLower(strings.TrimSpace(name)),
+		country: strings.ToUpper(strings.TrimSpace(country)),
+	}
+}
+
+func (p *Offline) Name() string {
+	return "offline"
+}
+
+func (p *Offline) Local() bool {
+	return true
+}
+
+// lookup ищет запись для страны запроса, а при её отсутствии — общую запись.
+// Если имени нет в наборе, возвращается ErrNoMatch, чтобы цепочка перешла к
+// следующему провайдеру, а не сохранила пустой ответ.
+func (p *Offline) lookup(q Query) (DatasetEntry, string, error) {
+	if q.CountryID != "" {
+		key := newDatasetKey(q.Name, q.CountryID)
+		if e, ok := p.entries[key]; ok {
+			return e, key.country, nil
+		}
+	}
+	e, ok := p.entries[newDatasetKey(q.Name, "")]
+	if !ok {
+		return DatasetEntry{}, "", ErrNoMatch
+	}
+	return e, "", nil
+}
+
+func (p *Offline) Age(ctx context.Context, q Query) (*model.UserAge, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	e, country, err := p.lookup(q)
+	if err != nil {
+		return nil, err
+	}
+	return &model.UserAge{
+		Source: model.NewSource(p.Name(), country),
+		Count:  e.Count,
+		Name:   q.Name,
+		Age:    e.Age,
+	}, nil
+}
+
+func (p *Offline) Gender(ctx context.Context, q Query) (*model.UserGender, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	e, country, err := p.lookup(q)
+	if err != nil {
+		return nil, err
+	}
+	res := &model.UserGender{
+		Source:      model.NewSource(p.Name(), country),
+		Count:       e.Count,
+		Name:        q.Name,
+		Probability: e.GenderProbability,
+	}
+	if e.Gender != "" {
+		res.Gender = &e.Gender
+	}
+	return res, nil
+}
+
+func (p *Offline) Nationality(ctx context.Context, q Query) (*model.UserNationality, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	e, _, err := p.lookup(Query{Name: q.Name})
+	if err != nil {
+		return nil, err
+	}
+	return &model.UserNationality{
+		Source:    model.NewSource(p.Name(), ""),
+		Count:     e.Count,
+		Name:      q.Name,
+		Countries: append([]model.Country(nil), e.Countries...),
+	}, nil
+}
@@ -0,0 +1,81 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/model"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOfflineLookup(t *testing.T) {
+	ctx := context.Background()
+	p := NewOffline([]DatasetEntry{
+		{Name: "Anna", Count: 10, Gender: "female", GenderProbability: 0.98, Age: intPtr(35),
+			Countries: []model.Country{{CountryID: "UA", Probability: 0.2}, {CountryID: "RU", Probability: 0.6}}},
+		{Name: "anna", CountryID: "ua", Count: 3, Age: intPtr(29)},
+		{Name: "alex", Count: 5},
+	})
+
+	age, err := p.Age(ctx, Query{Name: " ANNA ", CountryID: "UA"})
+	if err != nil || *age.Age != 29 || age.CountryID != "UA" {
+		t.Errorf("localized age: got %+v, %v", age, err)
+	}
+	age, err = p.Age(ctx, Query{Name: "anna", CountryID: "KZ"})
+	if err != nil || *age.Age != 35 || age.CountryID != "" {
+		t.Errorf("age without a country entry: got %+v, %v, want the common entry", age, err)
+	}
+
+	nationality, err := p.Nationality(ctx, Query{Name: "anna", CountryID: "UA"})
+	if err != nil || nationality.Countries[0].CountryID != "RU" {
+		t.Errorf("nationality: got %+v, %v, want countries sorted by probability", nationality, err)
+	}
+
+	gender, err := p.Gender(ctx, Query{Name: "alex"})
+	if err != nil || gender.Gender != nil {
+		t.Errorf("entry without gender: got %+v, %v", gender, err)
+	}
+
+	if _, err = p.Gender(ctx, Query{Name: "zzz"}); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("unknown name: got %v, want ErrNoMatch", err)
+	}
+}
+
+func TestLoadOffline(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "names.csv")
+	data := "count,name,gender,gender_probability,age,countries\n12,ivan,male,0.99,47,ru:0.8;UA:0.1\n"
+	if err := os.WriteFile(csvPath, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := LoadOffline(csvPath)
+	if err != nil {
+		t.Fatalf("LoadOffline: %v", err)
+	}
+	age, err := p.Age(context.Background(), Query{Name: "Ivan"})
+	if err != nil || *age.Age != 47 || age.Count != 12 {
+		t.Errorf("got %+v, %v", age, err)
+	}
+	nationality, _ := p.Nationality(context.Background(), Query{Name: "ivan"})
+	if len(nationality.Countries) != 2 || nationality.Countries[0].CountryID != "RU" {
+		t.Errorf("got countries %+v", nationality.Countries)
+	}
+
+	txt := filepath.Join(dir, "names.txt")
+	os.WriteFile(txt, []byte(data), 0o600)
+	if _, err = LoadOffline(txt); !errors.Is(err, ErrDatasetFormat) {
+		t.Errorf("txt: got %v, want ErrDatasetFormat", err)
+	}
+
+	bad := filepath.Join(dir, "bad.csv")
+	os.WriteFile(bad, []byte("name,countries\nivan,RU\n"), 0o600)
+	if _, err = LoadOffline(bad); !errors.Is(err, ErrDatasetFormat) {
+		t.Errorf("country without probability: got %v, want ErrDatasetFormat", err)
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
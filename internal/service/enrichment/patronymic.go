@@ -0,0 +1,95 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/model"
+	"context"
+	"strings"
+)
+
+const (
+	RulePatronymic = "patronymic"
+	RuleSurname    = "surname"
+
+	patronymicProbability = 0.99
+	surnameProbability    = 0.9
+)
+
+type genderSuffix struct {
+	suffix string
+	gender string
+}
+
+// Окончания проверяются по порядку, поэтому более длинные идут раньше.
+var (
+	patronymicSuffixes = []genderSuffix{
+		{"инична", "female"}, {"овна", "female"}, {"евна", "female"}, {"ична", "female"},
+		{"ович", "male"}, {"евич", "male"}, {"ич", "male"},
+		{"inichna", "female"}, {"ovna", "female"}, {"evna", "female"}, {"ichna", "female"},
+		{"ovich", "male"}, {"evich", "male"}, {"ich", "male"},
+	}
+	surnameSuffixes = []genderSuffix{
+		{"ская", "female"}, {"цкая", "female"}, {"ова", "female"}, {"ева", "female"},
+		{"ский", "male"}, {"цкий", "male"}, {"ов", "male"}, {"ев", "male"},
+		{"skaya", "female"}, {"ova", "female"}, {"eva", "female"},
+		{"skiy", "male"}, {"skii", "male"}, {"sky", "male"}, {"ov", "male"}, {"ev", "male"},
+	}
+)
+
+// Patronymic определяет пол по окончанию отчества, а если оно не задано или
+// не распознано — по окончанию фамилии. Работает локально; в цепочке
+// ENRICH_GENDER_PROVIDERS ставится перед удалённым провайдером, который
+// опрашивается только при ErrNoMatch.
+type Patronymic struct{}
+
+func NewPatronymic() *Patronymic {
+	return &Patronymic{}
+}
+
+func (p *Patronymic) Name() string {
+	return "patronymic"
+}
+
+func (p *Patronymic) Local() bool {
+	return true
+}
+
+func (p *Patronymic) Gender(ctx context.Context, q Query) (*model.UserGender, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rule, probability := RulePatronymic, patronymicProbability
+	gender, ok := matchSuffix(q.Patronymic, patronymicSuffixes)
+	if !ok {
+		rule, probability = RuleSurname, surnameProbability
+		gender, ok = matchSuffix(q.Surname, surnameSuffixes)
+	}
+	if !ok {
+		return nil, ErrNoMatch
+	}
+
+	source := model.NewSource(p.Name(), "")
+	source.Rule = rule
+	return &model.UserGender{
+		Source: source,
+		// правило не опирается на выборку, count = 1 проходит минимальный порог
+		Count:       1,
+		Name:        q.Name,
+		Gender:      &gender,
+		Probability: probability,
+	}, nil
+}
+
+func matchSuffix(word string, suffixes []genderSuffix) (string, bool) {
+	word = strings.ToLower(strings.TrimSpace(word))
+	if word == "" {
+		return "", false
+	}
+
+	for _, s := range suffixes {
+		if strings.HasSuffix(word, s.suffix) && len(word) > len(s.suffix) {
+			return s.gender, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,80 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/model"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPatronymicGender(t *testing.T) {
+	tests := []struct {
+		patronymic string
+		surname    string
+		gender     string
+		rule       string
+	}{
+		{"Петрович", "", "male", RulePatronymic},
+		{"Сергеевич", "", "male", RulePatronymic},
+		{"Ильич", "", "male", RulePatronymic},
+		{"Петровна", "", "female", RulePatronymic},
+		{"Сергеевна", "", "female", RulePatronymic},
+		{"Кузьминична", "", "female", RulePatronymic},
+		{"Ильинична", "Иванов", "female", RulePatronymic},
+		{" PETROVICH ", "", "male", RulePatronymic},
+		{"Ilyinichna", "", "female", RulePatronymic},
+		{"Sergeevna", "", "female", RulePatronymic},
+		{"", "Иванова", "female", RuleSurname},
+		{"", "Достоевский", "male", RuleSurname},
+		{"", "Вяземская", "female", RuleSurname},
+		{"", "Smirnova", "female", RuleSurname},
+		{"", "Tolstoy", "", ""},
+		{"Оглы", "Петров", "male", RuleSurname},
+		{"ич", "", "", ""},
+		{"", "", "", ""},
+	}
+	p := NewPatronymic()
+	for _, tt := range tests {
+		t.Run(tt.patronymic+"/"+tt.surname, func(t *testing.T) {
+			res, err := p.Gender(context.Background(), Query{Name: "x", Patronymic: tt.patronymic, Surname: tt.surname})
+			if tt.gender == "" {
+				if !errors.Is(err, ErrNoMatch) {
+					t.Fatalf("got %+v, %v, want ErrNoMatch", res, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Gender: %v", err)
+			}
+			if *res.Gender != tt.gender || res.Rule != tt.rule {
+				t.Fatalf("got %s by %s, want %s by %s", *res.Gender, res.Rule, tt.gender, tt.rule)
+			}
+		})
+	}
+}
+
+func TestPatronymicNoMatchWithoutPartial(t *testing.T) {
+	s := New(newStub("agify"), GenderChain{NewPatronymic()}, newStub("nationalize"), config.Enrichment{})
+
+	user := &model.User{Name: "Anna", Surname: "Smith"}
+	report, err := s.Enrich(context.Background(), user)
+	if err != nil {
+		t.Fatalf("Enrich: got %v, want an unknown ending not to fail enrichment", err)
+	}
+	if report[AttrGender] != StatusLowConfidence || report[AttrAge] != StatusSucceeded {
+		t.Fatalf("got report %v", report)
+	}
+	if user.Gender != nil || user.EnrichmentStatus != model.EnrichmentComplete {
+		t.Fatalf("got gender %v, status %q", user.Gender, user.EnrichmentStatus)
+	}
+
+	patronymic := "Petrovna"
+	user = &model.User{Name: "Anna", Surname: "Smith", Patronymic: &patronymic}
+	if _, err = s.Enrich(context.Background(), user); err != nil || *user.Gender != "female" {
+		t.Fatalf("got gender %v, %v, want female by patronymic", user.Gender, err)
+	}
+	if user.Enrichment.Gender.Rule != RulePatronymic || user.Enrichment.Gender.Provider != "patronymic" {
+		t.Fatalf("got provenance %+v", user.Enrichment.Gender)
+	}
+}
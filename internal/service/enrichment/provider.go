@@ -0,0 +1,223 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Query — входные данные для провайдера обогащения.
+type Query struct {
+	Name       string
+	Surname    string
+	Patronymic string
+	CountryID  string
+}
+
+// Provider — источник данных для обогащения. Провайдер реализует одну или
+// несколько возможностей: AgeProvider, GenderProvider, NationalityProvider.
+type Provider interface {
+	Name() string
+}
+
+type AgeProvider interface {
+	Provider
+	Age(ctx context.Context, q Query) (*model.UserAge, error)
+}
+
+type GenderProvider interface {
+	Provider
+	Gender(ctx context.Context, q Query) (*model.UserGender, error)
+}
+
+type NationalityProvider interface {
+	Provider
+	Nationality(ctx context.Context, q Query) (*model.UserNationality, error)
+}
+
+// AgeBatcher, GenderBatcher и NationalityBatcher — необязательные возможности
+// провайдера: ответ на несколько запросов (не больше MaxBatchSize, одна страна)
+// одним вызовом. Результаты возвращаются в порядке запросов.
+type AgeBatcher interface {
+	AgeBatch(ctx context.Context, qs []Query) ([]*model.UserAge, error)
+}
+
+type GenderBatcher interface {
+	GenderBatch(ctx context.Context, qs []Query) ([]*model.UserGender, error)
+}
+
+type NationalityBatcher interface {
+	NationalityBatch(ctx context.Context, qs []Query) ([]*model.UserNationality, error)
+}
+
+var (
+	ErrNoProviders = errors.New("no providers configured")
+	// ErrUpstream — обогащение прервано ошибкой внешнего провайдера.
+	ErrUpstream = errors.New("enrichment provider failed")
+	// ErrNoMatch — провайдер не может ответить на запрос (например, правило не
+	// сработало). Цепочка переходит к следующему провайдеру без записи об ошибке.
+	ErrNoMatch = errors.New("provider has no answer")
+)
+
+// localProvider отвечает без сетевых запросов: такие провайдеры не
+// оборачиваются повторами и кэшем.
+type localProvider interface {
+	Local() bool
+}
+
+func isLocal(p Provider) bool {
+	l, ok := p.(localProvider)
+	return ok && l.Local()
+}
+
+// AgeChain опрашивает провайдеров по порядку до первого успешного ответа.
+type AgeChain []AgeProvider
+
+func (c AgeChain) Name() string {
+	return chainName(c)
+}
+
+func (c AgeChain) Age(ctx context.Context, q Query) (*model.UserAge, error) {
+	return first(ctx, c, func(p AgeProvider) (*model.UserAge, error) { return p.Age(ctx, q) })
+}
+
+func (c AgeChain) AgeBatch(ctx context.Context, qs []Query) ([]*model.UserAge, error) {
+	return firstBatch(ctx, c, qs, func(p AgeProvider, qs []Query) ([]*model.UserAge, error) { return perName(p, ageBatch(p))(ctx, qs) })
+}
+
+type GenderChain []GenderProvider
+
+func (c GenderChain) Name() string {
+	return chainName(c)
+}
+
+func (c GenderChain) Gender(ctx context.Context, q Query) (*model.UserGender, error) {
+	return first(ctx, c, func(p GenderProvider) (*model.UserGender, error) { return p.Gender(ctx, q) })
+}
+
+func (c GenderChain) GenderBatch(ctx context.Context, qs []Query) ([]*model.UserGender, error) {
+	return firstBatch(ctx, c, qs, func(p GenderProvider, qs []Query) ([]*model.UserGender, error) {
+		return perName(p, genderBatch(p))(ctx, qs)
+	})
+}
+
+type NationalityChain []NationalityProvider
+
+func (c NationalityChain) Name() string {
+	return chainName(c)
+}
+
+func (c NationalityChain) Nationality(ctx context.Context, q Query) (*model.UserNationality, error) {
+	return first(ctx, c, func(p NationalityProvider) (*model.UserNationality, error) { return p.Nationality(ctx, q) })
+}
+
+func (c NationalityChain) NationalityBatch(ctx context.Context, qs []Query) ([]*model.UserNationality, error) {
+	return firstBatch(ctx, c, qs, func(p NationalityProvider, qs []Query) ([]*model.UserNationality, error) {
+		return perName(p, nationalityBatch(p))(ctx, qs)
+	})
+}
+
+func first[P Provider, R any](ctx context.Context, chain []P, call func(P) (R, error)) (R, error) {
+	const op = "service.enrichment.chain"
+
+	var zero R
+	if len(chain) == 0 {
+		return zero, fmt.Errorf("%s: %w", op, ErrNoProviders)
+	}
+
+	var errs []error
+	for _, p := range chain {
+		res, err := call(p)
+		if err == nil {
+			return res, nil
+		}
+
+		if errors.Is(err, ErrNoMatch) {
+			logger.Debug("%s: provider %s has no answer", op, p.Name())
+			continue
+		}
+
+		logger.Error("%s: provider %s failed: %v", op, p.Name(), err)
+		errs = append(errs, err)
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if len(errs) == 0 {
+		return zero, fmt.Errorf("%s: %w", op, ErrNoMatch)
+	}
+	return zero, fmt.Errorf("%s: %w", op, errors.Join(errs...))
+}
+
+// firstBatch передаёт каждому следующему провайдеру только запросы, на которые
+// предыдущие не ответили. Неотвеченные запросы остаются nil; если среди них
+// есть неудачные, вместе с частичным результатом возвращается ошибка.
+func firstBatch[P Provider, R any](ctx context.Context, chain []P, qs []Query,
+	call func(P, []Query) ([]*R, error)) ([]*R, error) {
+
+	const op = "service.enrichment.chainBatch"
+
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrNoProviders)
+	}
+
+	res := make([]*R, len(qs))
+	pending := make([]int, len(qs))
+	for i := range qs {
+		pending[i] = i
+	}
+
+	var errs []error
+	for _, p := range chain {
+		sub := make([]Query, len(pending))
+		for j, i := range pending {
+			sub[j] = qs[i]
+		}
+
+		got, err := call(p, sub)
+		if err != nil && got == nil {
+			logger.Error("%s: provider %s failed: %v", op, p.Name(), err)
+			errs = append(errs, err)
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		next := pending[:0]
+		for j, i := range pending {
+			if got[j] == nil {
+				next = append(next, i)
+				continue
+			}
+			res[i] = got[j]
+		}
+		pending = next
+
+		if len(pending) == 0 {
+			return res, nil
+		}
+	}
+
+	if len(errs) > 0 {
+		return res, fmt.Errorf("%s: %w", op, errors.Join(errs...))
+	}
+	return res, nil
+}
+
+func chainName[P Provider](chain []P) string {
+	names := make([]string, len(chain))
+	for i, p := range chain {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,165 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/config"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestChainFallsBackOnNoMatch(t *testing.T) {
+	ctx := context.Background()
+	offline := &stub{name: "offline", local: true, ages: map[string]int{"anna": 29}}
+	remote := &stub{name: "remote", ages: map[string]int{"anna": 35, "ivan": 47}}
+	chain := AgeChain{offline, remote}
+
+	res, err := chain.Age(ctx, Query{Name: "anna"})
+	if err != nil || *res.Age != 29 || res.Provider != "offline" {
+		t.Fatalf("anna: got %+v, %v, want 29 from offline", res, err)
+	}
+	if len(remote.Calls()) != 0 {
+		t.Fatalf("remote asked after the first provider answered")
+	}
+
+	res, err = chain.Age(ctx, Query{Name: "ivan"})
+	if err != nil || *res.Age != 47 || res.Provider != "remote" {
+		t.Fatalf("ivan: got %+v, %v, want 47 from remote", res, err)
+	}
+
+	if _, err = chain.Age(ctx, Query{Name: "zzz"}); !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("unknown name: got %v, want ErrNoMatch", err)
+	}
+	if _, err = (AgeChain{}).Age(ctx, Query{Name: "ivan"}); !errors.Is(err, ErrNoProviders) {
+		t.Fatalf("empty chain: got %v, want ErrNoProviders", err)
+	}
+}
+
+func TestChainJoinsErrors(t *testing.T) {
+	first := errors.New("first is down")
+	second := errors.New("second is down")
+	chain := GenderChain{&stub{name: "a", err: first}, &stub{name: "b", err: second}}
+
+	_, err := chain.Gender(context.Background(), Query{Name: "ivan"})
+	if !errors.Is(err, first) || !errors.Is(err, second) {
+		t.Fatalf("got %v, want both provider errors", err)
+	}
+	if errors.Is(err, ErrNoMatch) {
+		t.Fatalf("failed providers reported as no match: %v", err)
+	}
+}
+
+func TestRegistryChains(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&stub{name: "a"})
+	r.Register(NewPatronymic())
+	r.Disable("a")
+
+	chain, err := r.GenderChain([]string{"a", "patronymic"})
+	if err != nil {
+		t.Fatalf("GenderChain: %v", err)
+	}
+	if chain.Name() != "patronymic" {
+		t.Fatalf("got chain %s, want the disabled provider skipped", chain.Name())
+	}
+
+	if _, err = r.AgeChain([]string{"nope"}); !errors.Is(err, ErrUnknownProvider) {
+		t.Fatalf("unknown provider: got %v", err)
+	}
+	if _, err = r.AgeChain([]string{"patronymic"}); !errors.Is(err, ErrNotCapable) {
+		t.Fatalf("patronymic as age provider: got %v", err)
+	}
+}
+
+func TestAPIProviders(t *testing.T) {
+	var got []url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.URL.Query())
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Query().Has("name[]"):
+			w.Write([]byte(`[{"count":10,"name":"anna","age":35},{"count":0,"name":"zzz","age":null}]`))
+		case r.URL.Path == "/nationalize/":
+			w.Write([]byte(`{"count":7,"name":"ivan","country":[{"country_id":"RU","probability":0.8}]}`))
+		default:
+			w.Write([]byte(`{"count":12,"name":"ivan","age":47,"gender":"male","probability":0.99}`))
+		}
+	}))
+	defer srv.Close()
+
+	cfg := config.Provider{URL: srv.URL + "/agify/", APIKey: "secret"}
+	agify := NewAgify(srv.Client(), cfg)
+	ctx := context.Background()
+
+	age, err := agify.Age(ctx, Query{Name: "ivan", CountryID: "RU"})
+	if err != nil {
+		t.Fatalf("Age: %v", err)
+	}
+	if *age.Age != 47 || age.Count != 12 || age.Provider != "agify" || age.CountryID != "RU" {
+		t.Errorf("Age: got %+v", age)
+	}
+	if q := got[0]; q.Get("name") != "ivan" || q.Get("country_id") != "RU" || q.Get("apikey") != "secret" {
+		t.Errorf("agify request: got %v", q)
+	}
+
+	ages, err := agify.AgeBatch(ctx, []Query{{Name: "anna"}, {Name: "zzz"}})
+	if err != nil {
+		t.Fatalf("AgeBatch: %v", err)
+	}
+	if len(ages) != 2 || *ages[0].Age != 35 || ages[1].Age != nil || ages[1].Provider != "agify" {
+		t.Errorf("AgeBatch: got %+v, %+v", ages[0], ages[1])
+	}
+
+	gender, err := NewGenderize(srv.Client(), config.Provider{URL: srv.URL + "/genderize/"}).
+		Gender(ctx, Query{Name: "ivan", CountryID: "RU"})
+	if err != nil || *gender.Gender != "male" || gender.Provider != "genderize" || gender.CountryID != "RU" {
+		t.Errorf("Gender: got %+v, %v", gender, err)
+	}
+	if got[2].Has("apikey") {
+		t.Errorf("genderize without a key sent apikey: %v", got[2])
+	}
+
+	nationality, err := NewNationalize(srv.Client(), config.Provider{URL: srv.URL + "/nationalize/"}).
+		Nationality(ctx, Query{Name: "ivan", CountryID: "RU"})
+	if err != nil || nationality.Countries[0].CountryID != "RU" || nationality.Provider != "nationalize" {
+		t.Errorf("Nationality: got %+v, %v", nationality, err)
+	}
+	// nationalize не принимает страну и не локализует ответ
+	if got[3].Has("country_id") || nationality.Source.CountryID != "" {
+		t.Errorf("nationalize was localized: request %v, source %+v", got[3], nationality.Source)
+	}
+}
+
+func TestAPIProviderErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("name") == "broken" {
+			w.Write([]byte(`{"count":`))
+			return
+		}
+		w.Header().Set("X-Rate-Limit-Remaining", "0")
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	agify := NewAgify(srv.Client(), config.Provider{URL: srv.URL + "/", APIKey: "secret"})
+
+	_, err := agify.Age(context.Background(), Query{Name: "ivan"})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("got %v, want a StatusError", err)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("error leaks the API key: %v", err)
+	}
+	if statusErr.Code != http.StatusTooManyRequests || statusErr.Remaining != 0 || statusErr.RetryAfter.Seconds() != 7 {
+		t.Errorf("got %+v", statusErr)
+	}
+
+	if _, err = agify.Age(context.Background(), Query{Name: "broken"}); err == nil || errors.As(err, &statusErr) {
+		t.Errorf("malformed body: got %v, want a decode error", err)
+	}
+}
@@ -0,0 +1,90 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/logger"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown provider")
+	ErrNotCapable      = errors.New("provider does not support attribute")
+)
+
+// Registry хранит провайдеров по имени и собирает из них цепочки.
+type Registry struct {
+	providers map[string]Provider
+	disabled  map[string]bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{providers: make(map[string]Provider), disabled: make(map[string]bool)}
+}
+
+// DefaultRegistry регистрирует API agify, genderize и nationalize (по настройкам
+// из cfg) и локальное определение пола по отчеству и фамилии.
+func DefaultRegistry(client *http.Client, cfg config.Enrichment) *Registry {
+	r := NewRegistry()
+	r.Register(NewAgify(client, cfg.Agify))
+	r.Register(NewGenderize(client, cfg.Genderize))
+	r.Register(NewNationalize(client, cfg.Nationalize))
+	r.Register(NewPatronymic())
+
+	for name, p := range map[string]config.Provider{
+		"agify":       cfg.Agify,
+		"genderize":   cfg.Genderize,
+		"nationalize": cfg.Nationalize,
+	} {
+		if !p.Enabled {
+			r.Disable(name)
+		}
+	}
+	return r
+}
+
+func (r *Registry) Register(p Provider) {
+	r.providers[p.Name()] = p
+}
+
+// Disable исключает провайдера из цепочек, не требуя править списки *_PROVIDERS.
+func (r *Registry) Disable(name string) {
+	r.disabled[name] = true
+}
+
+func (r *Registry) AgeChain(names []string) (AgeChain, error) {
+	return lookup[AgeProvider](r, names, "age")
+}
+
+func (r *Registry) GenderChain(names []string) (GenderChain, error) {
+	return lookup[GenderProvider](r, names, "gender")
+}
+
+func (r *Registry) NationalityChain(names []string) (NationalityChain, error) {
+	return lookup[NationalityProvider](r, names, "nationality")
+}
+
+func lookup[P Provider](r *Registry, names []string, attr string) ([]P, error) {
+	const op = "service.enrichment.registry.lookup"
+
+	chain := make([]P, 0, len(names))
+	for _, name := range names {
+		p, ok := r.providers[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: %w: %q", op, ErrUnknownProvider, name)
+		}
+		if r.disabled[name] {
+			logger.Info("%s: %s provider %q is disabled, skipping", op, attr, name)
+			continue
+		}
+
+		capable, ok := p.(P)
+		if !ok {
+			return nil, fmt.Errorf("%s: %w: %q (%s)", op, ErrNotCapable, name, attr)
+		}
+		chain = append(chain, capable)
+	}
+
+	return chain, nil
+}
@@ -0,0 +1,44 @@
+package enrichment
+
+import "sort"
+
+const (
+	AttrAge         = "age"
+	AttrGender      = "gender"
+	AttrNationality = "nationality"
+)
+
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusSkipped   Status = "skipped"
+	// StatusLowConfidence — провайдер ответил, но ниже порога достоверности; атрибут не записан.
+	StatusLowConfidence Status = "low_confidence"
+	// StatusLocked — атрибут задан вручную и не обогащался.
+	StatusLocked Status = "locked"
+)
+
+// Report — результат обогащения по каждому атрибуту.
+type Report map[string]Status
+
+// Pending возвращает атрибуты, которые не удалось получить и нужно запросить повторно.
+func (r Report) Pending() []string {
+	pending := make([]string, 0)
+	for attr, status := range r {
+		if status == StatusFailed {
+			pending = append(pending, attr)
+		}
+	}
+	sort.Strings(pending)
+	return pending
+}
+
+func (r Report) Strings() map[string]string {
+	res := make(map[string]string, len(r))
+	for attr, status := range r {
+		res[attr] = string(status)
+	}
+	return res
+}
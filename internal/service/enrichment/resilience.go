@@ -0,0 +1,327 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// StatusError — ответ внешнего API с кодом, отличным от 200.
+type StatusError struct {
+	Code       int
+	RetryAfter time.Duration
+	// Remaining — значение X-Rate-Limit-Remaining, -1 если заголовка нет.
+	Remaining int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.Code)
+}
+
+func newStatusError(res *http.Response) *StatusError {
+	e := &StatusError{Code: res.StatusCode, Remaining: -1}
+
+	if v, err := strconv.Atoi(res.Header.Get("X-Rate-Limit-Remaining")); err == nil {
+		e.Remaining = v
+	}
+
+	if v := res.Header.Get("Retry-After"); v != "" {
+		if sec, err := strconv.Atoi(v); err == nil {
+			e.RetryAfter = time.Duration(sec) * time.Second
+		} else if at, err := http.ParseTime(v); err == nil {
+			e.RetryAfter = time.Until(at)
+		}
+	} else if sec, err := strconv.Atoi(res.Header.Get("X-Rate-Limit-Reset")); err == nil {
+		e.RetryAfter = time.Duration(sec) * time.Second
+	}
+
+	return e
+}
+
+// Resilience повторяет неудачные вызовы провайдеров с экспоненциальной
+// задержкой и ведёт отдельный circuit breaker для каждого провайдера.
+type Resilience struct {
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	threshold  int
+	cooldown   time.Duration
+	clock      clock
+	// jitter возвращает случайную задержку из [0, n).
+	jitter func(n time.Duration) time.Duration
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+func NewResilience(cfg config.Enrichment) *Resilience {
+	return &Resilience{
+		maxRetries: cfg.RetryMax,
+		baseDelay:  cfg.RetryBaseDelay,
+		maxDelay:   cfg.RetryMaxDelay,
+		threshold:  cfg.BreakerThreshold,
+		cooldown:   cfg.BreakerCooldown,
+		clock:      realClock{},
+		jitter:     rand.N[time.Duration],
+		breakers:   make(map[string]*breaker),
+	}
+}
+
+func (r *Resilience) Do(ctx context.Context, provider string, call func() error) error {
+	const op = "service.enrichment.resilience.do"
+
+	b := r.breaker(provider)
+	if !b.allow() {
+		logger.Error("%s: %s short-circuited", op, provider)
+		return fmt.Errorf("%s: %s: %w", op, provider, ErrCircuitOpen)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := call()
+		if err == nil {
+			b.success()
+			return nil
+		}
+
+		if ctx.Err() != nil || !isProviderFailure(err) {
+			b.release()
+			return err
+		}
+
+		delay, ok := r.backoff(attempt, err)
+		if !ok {
+			// исчерпанный лимит запросов не означает, что провайдер недоступен
+			if isRateLimited(err) {
+				b.release()
+			} else {
+				b.failure()
+			}
+			return err
+		}
+
+		logger.Info("%s: %s attempt %d failed, retrying in %s: %v", op, provider, attempt+1, delay, err)
+
+		select {
+		case <-ctx.Done():
+			b.release()
+			return fmt.Errorf("%s: %w", op, errors.Join(ctx.Err(), err))
+		case <-r.clock.After(delay):
+		}
+	}
+}
+
+// backoff возвращает задержку перед следующей попыткой или false, если
+// повторять бессмысленно.
+func (r *Resilience) backoff(attempt int, err error) (time.Duration, bool) {
+	if attempt >= r.maxRetries {
+		return 0, false
+	}
+
+	delay := r.baseDelay << attempt
+	if delay <= 0 || delay > r.maxDelay {
+		delay = r.maxDelay
+	}
+	delay = delay/2 + r.jitter(delay/2+1)
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		if statusErr.Remaining == 0 && statusErr.RetryAfter == 0 {
+			return 0, false
+		}
+		if statusErr.RetryAfter > r.maxDelay {
+			return 0, false
+		}
+		if statusErr.RetryAfter > delay {
+			delay = statusErr.RetryAfter
+		}
+	}
+
+	return delay, true
+}
+
+func (r *Resilience) breaker(provider string) *breaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.breakers[provider]
+	if !ok {
+		b = &breaker{name: provider, threshold: r.threshold, cooldown: r.cooldown, clock: r.clock}
+		r.breakers[provider] = b
+	}
+	return b
+}
+
+// isProviderFailure отделяет сбои провайдера (сеть, 429, 5xx) от ошибок,
+// которые повтор не исправит.
+func isProviderFailure(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code == http.StatusTooManyRequests || statusErr.Code >= http.StatusInternalServerError
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func isRateLimited(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.Code == http.StatusTooManyRequests
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+type breaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+	clock     clock
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func (b *breaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.clock.Now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		logger.Info("service.enrichment.breaker: %s half-open", b.name)
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != breakerClosed {
+		logger.Info("service.enrichment.breaker: %s closed", b.name)
+	}
+	b.state = breakerClosed
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.state == breakerHalfOpen || (b.threshold > 0 && b.failures >= b.threshold) {
+		if b.state != breakerOpen {
+			logger.Error("service.enrichment.breaker: %s opened after %d failures", b.name, b.failures)
+		}
+		b.state = breakerOpen
+		b.openedAt = b.clock.Now()
+	}
+}
+
+// release снимает пробный вызов без изменения состояния (отмена, ошибки клиента).
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+type resilientAge struct {
+	AgeProvider
+	r *Resilience
+}
+
+func (p resilientAge) Age(ctx context.Context, q Query) (res *model.UserAge, err error) {
+	err = p.r.Do(ctx, p.Name(), func() error {
+		res, err = p.AgeProvider.Age(ctx, q)
+		return err
+	})
+	return res, err
+}
+
+func (p resilientAge) AgeBatch(ctx context.Context, qs []Query) (res []*model.UserAge, err error) {
+	err = p.r.Do(ctx, p.Name(), func() error {
+		res, err = ageBatch(p.AgeProvider)(ctx, qs)
+		return err
+	})
+	return res, err
+}
+
+type resilientGender struct {
+	GenderProvider
+	r *Resilience
+}
+
+func (p resilientGender) Gender(ctx context.Context, q Query) (res *model.UserGender, err error) {
+	err = p.r.Do(ctx, p.Name(), func() error {
+		res, err = p.GenderProvider.Gender(ctx, q)
+		return err
+	})
+	return res, err
+}
+
+func (p resilientGender) GenderBatch(ctx context.Context, qs []Query) (res []*model.UserGender, err error) {
+	err = p.r.Do(ctx, p.Name(), func() error {
+		res, err = genderBatch(p.GenderProvider)(ctx, qs)
+		return err
+	})
+	return res, err
+}
+
+type resilientNationality struct {
+	NationalityProvider
+	r *Resilience
+}
+
+func (p resilientNationality) Nationality(ctx context.Context, q Query) (res *model.UserNationality, err error) {
+	err = p.r.Do(ctx, p.Name(), func() error {
+		res, err = p.NationalityProvider.Nationality(ctx, q)
+		return err
+	})
+	return res, err
+}
+
+func (p resilientNationality) NationalityBatch(ctx context.Context, qs []Query) (res []*model.UserNationality, err error) {
+	err = p.r.Do(ctx, p.Name(), func() error {
+		res, err = nationalityBatch(p.NationalityProvider)(ctx, qs)
+		return err
+	})
+	return res, err
+}
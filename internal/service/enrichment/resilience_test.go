@@ -0,0 +1,215 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/config"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newTestResilience возвращает Resilience на fakeClock с задержкой по верхней
+// границе джиттера, чтобы рост задержек проверялся точно.
+func newTestResilience(retries, threshold int) (*Resilience, *fakeClock) {
+	clock := newFakeClock()
+	r := NewResilience(config.Enrichment{
+		RetryMax:         retries,
+		RetryBaseDelay:   100 * time.Millisecond,
+		RetryMaxDelay:    time.Second,
+		BreakerThreshold: threshold,
+		BreakerCooldown:  30 * time.Second,
+	})
+	r.clock = clock
+	r.jitter = func(n time.Duration) time.Duration { return n - 1 }
+	return r, clock
+}
+
+func TestBackoffGrowsAndCaps(t *testing.T) {
+	r, _ := newTestResilience(10, 0)
+	failure := &StatusError{Code: http.StatusBadGateway, Remaining: -1}
+
+	var got []time.Duration
+	for attempt := 0; attempt < 6; attempt++ {
+		delay, ok := r.backoff(attempt, failure)
+		if !ok {
+			t.Fatalf("attempt %d: no retry", attempt)
+		}
+		got = append(got, delay)
+	}
+
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond,
+		800 * time.Millisecond, time.Second, time.Second}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got delays %v, want %v", got, want)
+	}
+
+	if _, ok := r.backoff(10, failure); ok {
+		t.Fatal("retry after RETRY_MAX attempts")
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	r, _ := newTestResilience(3, 0)
+	r.jitter = func(n time.Duration) time.Duration { return 0 }
+
+	// джиттер оставляет не меньше половины задержки
+	if delay, _ := r.backoff(2, errors.New("x")); delay != 200*time.Millisecond {
+		t.Fatalf("got %s, want half of 400ms", delay)
+	}
+}
+
+func TestBackoffHonoursRateLimitHeaders(t *testing.T) {
+	r, _ := newTestResilience(3, 0)
+
+	tests := []struct {
+		name  string
+		err   *StatusError
+		delay time.Duration
+		retry bool
+	}{
+		{"Retry-After longer than backoff", &StatusError{Code: 429, RetryAfter: 700 * time.Millisecond, Remaining: 0}, 700 * time.Millisecond, true},
+		{"Retry-After shorter than backoff", &StatusError{Code: 429, RetryAfter: time.Millisecond, Remaining: -1}, 100 * time.Millisecond, true},
+		{"Retry-After beyond RETRY_MAX_DELAY", &StatusError{Code: 429, RetryAfter: time.Minute, Remaining: 0}, 0, false},
+		{"quota exhausted without reset", &StatusError{Code: 429, Remaining: 0}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, ok := r.backoff(0, tt.err)
+			if ok != tt.retry || delay != tt.delay {
+				t.Fatalf("got %s, %t, want %s, %t", delay, ok, tt.delay, tt.retry)
+			}
+		})
+	}
+}
+
+func TestRetryClassification(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		calls int
+	}{
+		{"server error", &StatusError{Code: http.StatusInternalServerError, Remaining: -1}, 3},
+		{"rate limited", &StatusError{Code: http.StatusTooManyRequests, Remaining: -1}, 3},
+		{"timeout", os.ErrDeadlineExceeded, 3},
+		{"bad request", &StatusError{Code: http.StatusUnprocessableEntity, Remaining: -1}, 1},
+		{"unauthorized", &StatusError{Code: http.StatusUnauthorized, Remaining: -1}, 1},
+		{"decode error", errors.New("unexpected end of JSON input"), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, clock := newTestResilience(2, 0)
+
+			calls := 0
+			err := r.Do(context.Background(), "agify", func() error {
+				calls++
+				return tt.err
+			})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want the last call error", err)
+			}
+			if calls != tt.calls {
+				t.Fatalf("got %d calls, want %d", calls, tt.calls)
+			}
+			if len(clock.Sleeps()) != tt.calls-1 {
+				t.Fatalf("slept %v between %d calls", clock.Sleeps(), calls)
+			}
+		})
+	}
+}
+
+func TestRetrySucceeds(t *testing.T) {
+	r, clock := newTestResilience(3, 1)
+
+	calls := 0
+	err := r.Do(context.Background(), "agify", func() error {
+		calls++
+		if calls < 3 {
+			return &StatusError{Code: http.StatusServiceUnavailable, Remaining: -1}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("got %v after %d calls", err, calls)
+	}
+	if want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}; !slices.Equal(clock.Sleeps(), want) {
+		t.Fatalf("got sleeps %v, want %v", clock.Sleeps(), want)
+	}
+	// успех после повторов не считается отказом
+	if !r.breaker("agify").allow() {
+		t.Fatal("breaker opened after a successful retry")
+	}
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	r, clock := newTestResilience(0, 2)
+	ctx := context.Background()
+	down := &StatusError{Code: http.StatusBadGateway, Remaining: -1}
+
+	calls := 0
+	fail := func() error { calls++; return down }
+	ok := func() error { calls++; return nil }
+
+	// closed: отказы считаются до порога
+	for i := 0; i < 2; i++ {
+		if err := r.Do(ctx, "agify", fail); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("failure %d: breaker open before the threshold", i+1)
+		}
+	}
+
+	// open: вызовы не доходят до провайдера
+	if err := r.Do(ctx, "agify", ok); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got %v, want ErrCircuitOpen", err)
+	}
+	if calls != 2 {
+		t.Fatalf("open breaker let a call through: %d calls", calls)
+	}
+	// у другого провайдера свой breaker
+	if err := r.Do(ctx, "genderize", ok); err != nil {
+		t.Fatalf("genderize: %v", err)
+	}
+
+	// half-open: после паузы проходит один пробный вызов
+	clock.Advance(30 * time.Second)
+	b := r.breaker("agify")
+	if !b.allow() {
+		t.Fatal("no probe after the cooldown")
+	}
+	if b.allow() {
+		t.Fatal("second call allowed while the probe is in flight")
+	}
+	b.failure()
+	if b.state != breakerOpen {
+		t.Fatalf("failed probe left state %d, want open", b.state)
+	}
+
+	if err := r.Do(ctx, "agify", ok); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("breaker closed before the cooldown after a failed probe: %v", err)
+	}
+
+	clock.Advance(30 * time.Second)
+	if err := r.Do(ctx, "agify", ok); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if b.state != breakerClosed || b.failures != 0 {
+		t.Fatalf("successful probe left state %d with %d failures", b.state, b.failures)
+	}
+}
+
+func TestBreakerIgnoresClientErrorsAndRateLimits(t *testing.T) {
+	r, _ := newTestResilience(0, 1)
+	ctx := context.Background()
+
+	for _, err := range []error{
+		&StatusError{Code: http.StatusBadRequest, Remaining: -1},
+		&StatusError{Code: http.StatusTooManyRequests, Remaining: 0},
+		context.Canceled,
+	} {
+		r.Do(ctx, "agify", func() error { return err })
+	}
+	if err := r.Do(ctx, "agify", func() error { return nil }); err != nil {
+		t.Fatalf("breaker opened on errors that do not mean the provider is down: %v", err)
+	}
+}
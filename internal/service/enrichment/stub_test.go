@@ -0,0 +1,97 @@
+package enrichment
+
+import (
+	"Effective_Mobile/internal/model"
+	"context"
+	"sync"
+	"time"
+)
+
+// stub — провайдер всех трёх атрибутов с ответами по имени. На имена без
+// ответа возвращается ErrNoMatch, а при err — эта ошибка на любой запрос.
+type stub struct {
+	name   string
+	local  bool
+	delay  time.Duration
+	err    error
+	counts int
+
+	ages      map[string]int
+	genders   map[string]string
+	countries map[string]string
+
+	mu    sync.Mutex
+	calls []Query
+}
+
+func (s *stub) Name() string {
+	return s.name
+}
+
+func (s *stub) Local() bool {
+	return s.local
+}
+
+func (s *stub) Calls() []Query {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Query(nil), s.calls...)
+}
+
+func (s *stub) call(ctx context.Context, q Query) error {
+	s.mu.Lock()
+	s.calls = append(s.calls, q)
+	s.mu.Unlock()
+
+	if s.delay > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.delay):
+		}
+	}
+	return s.err
+}
+
+func (s *stub) count() int {
+	if s.counts == 0 {
+		return 100
+	}
+	return s.counts
+}
+
+func (s *stub) Age(ctx context.Context, q Query) (*model.UserAge, error) {
+	if err := s.call(ctx, q); err != nil {
+		return nil, err
+	}
+	age, ok := s.ages[q.Name]
+	if !ok {
+		return nil, ErrNoMatch
+	}
+	return &model.UserAge{Source: model.NewSource(s.name, q.CountryID), Count: s.count(), Name: q.Name, Age: &age}, nil
+}
+
+func (s *stub) Gender(ctx context.Context, q Query) (*model.UserGender, error) {
+	if err := s.call(ctx, q); err != nil {
+		return nil, err
+	}
+	gender, ok := s.genders[q.Name]
+	if !ok {
+		return nil, ErrNoMatch
+	}
+	return &model.UserGender{Source: model.NewSource(s.name, q.CountryID), Count: s.count(), Name: q.Name,
+		Gender: &gender, Probability: 0.99}, nil
+}
+
+func (s *stub) Nationality(ctx context.Context, q Query) (*model.UserNationality, error) {
+	if err := s.call(ctx, q); err != nil {
+		return nil, err
+	}
+	country, ok := s.countries[q.Name]
+	if !ok {
+		return nil, ErrNoMatch
+	}
+	return &model.UserNationality{Source: model.NewSource(s.name, ""), Count: s.count(), Name: q.Name,
+		Countries: []model.Country{{CountryID: country, Probability: 0.9}}}, nil
+}
@@ -0,0 +1,172 @@
+package importer
+
+import (
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/service/enrichment"
+	"Effective_Mobile/internal/storage"
+	"Effective_Mobile/lib/normalize"
+	"Effective_Mobile/lib/validate"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ChunkSize — сколько людей обогащается одним вызовом EnrichBatch: сто человек
+// укладываются в десять пакетных запросов на атрибут.
+const ChunkSize = 10 * enrichment.MaxBatchSize
+
+type Enricher interface {
+	EnrichBatch(ctx context.Context, users []*model.User) ([]enrichment.Report, error)
+}
+
+// Record — строка файла импорта. Правила те же, что у POST /people.
+type Record struct {
+	Name        string  `json:"name" validate:"required,max=100,name"`
+	Surname     string  `json:"surname" validate:"required,max=100,name"`
+	Patronymic  *string `json:"patronymic,omitempty" validate:"max=100,name"`
+	CountryHint *string `json:"country_hint,omitempty" validate:"country"`
+}
+
+// Summary — итог импорта.
+type Summary struct {
+	Read int
+	// Added — сохранённые люди, Duplicates — уже существующие, Invalid —
+	// строки, не прошедшие разбор или проверку.
+	Added      int
+	Duplicates int
+	Invalid    int
+}
+
+// Importer загружает людей из JSON Lines и обогащает их пакетными запросами.
+type Importer struct {
+	repo     storage.Repository
+	enricher Enricher
+}
+
+func New(repo storage.Repository, enricher Enricher) *Importer {
+	return &Importer{
+		repo:     repo,
+		enricher: enricher,
+	}
+}
+
+// Run читает по одной записи Record в строке. Некорректные строки и уже
+// существующие люди пропускаются с записью в лог; остальные обогащаются
+// пачками по ChunkSize и сохраняются. Ошибка возвращается при ошибке чтения,
+// отмене ctx или отказе хранилища.
+func (i *Importer) Run(ctx context.Context, r io.Reader) (Summary, error) {
+	const op = "service.importer.run"
+
+	var (
+		summary Summary
+		chunk   []*model.User
+		line    int
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		summary.Read++
+
+		user, err := parse(text)
+		if err != nil {
+			logger.Error("%s: line %d skipped: %v", op, line, err)
+			summary.Invalid++
+			continue
+		}
+
+		chunk = append(chunk, user)
+		if len(chunk) == ChunkSize {
+			if err = i.add(ctx, chunk, &summary); err != nil {
+				return summary, fmt.Errorf("%s: %w", op, err)
+			}
+			chunk = chunk[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return summary, fmt.Errorf("%s: line %d: %w", op, line+1, err)
+	}
+
+	if len(chunk) > 0 {
+		if err := i.add(ctx, chunk, &summary); err != nil {
+			return summary, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	logger.Info("%s: read %d, added %d, duplicates %d, invalid %d",
+		op, summary.Read, summary.Added, summary.Duplicates, summary.Invalid)
+	return summary, nil
+}
+
+func parse(text string) (*model.User, error) {
+	var rec Record
+
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rec); err != nil {
+		return nil, err
+	}
+
+	errs, err := validate.Struct(&rec, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for i, e := range errs {
+			msgs[i] = e.Error()
+		}
+		return nil, errors.New(strings.Join(msgs, "; "))
+	}
+
+	user := &model.User{
+		Name:       normalize.Clean(rec.Name),
+		Surname:    normalize.Clean(rec.Surname),
+		Patronymic: rec.Patronymic,
+	}
+	user.NameKey = normalize.Key(user.Name)
+	user.SurnameKey = normalize.Key(user.Surname)
+	if user.Patronymic != nil {
+		patronymic := normalize.Clean(*user.Patronymic)
+		user.Patronymic = &patronymic
+	}
+	if rec.CountryHint != nil {
+		hint := strings.ToUpper(strings.TrimSpace(*rec.CountryHint))
+		user.CountryHint = &hint
+	}
+	return user, nil
+}
+
+// add обогащает пачку одним вызовом EnrichBatch и сохраняет людей по одному:
+// дубликат не мешает сохранить остальных.
+func (i *Importer) add(ctx context.Context, users []*model.User, summary *Summary) error {
+	const op = "service.importer.add"
+
+	if _, err := i.enricher.EnrichBatch(ctx, users); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, user := range users {
+		id, err := i.repo.Add(ctx, *user)
+		if errors.Is(err, storage.ErrUserExists) {
+			logger.Info("%s: %s %s already exists, skipped", op, user.Name, user.Surname)
+			summary.Duplicates++
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		logger.Debug("%s: %s %s added with id %d", op, user.Name, user.Surname, id)
+		summary.Added++
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package importer
+
+import (
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/service/enrichment"
+	"Effective_Mobile/internal/storage"
+	"Effective_Mobile/internal/storage/memory"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// batches запоминает размеры пачек и выставляет всем возраст 30.
+type batches []int
+
+func (b *batches) EnrichBatch(ctx context.Context, users []*model.User) ([]enrichment.Report, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	*b = append(*b, len(users))
+	reports := make([]enrichment.Report, len(users))
+	for i, u := range users {
+		age := 30
+		u.Age = &age
+		reports[i] = enrichment.Report{enrichment.AttrAge: enrichment.StatusSucceeded}
+	}
+	return reports, nil
+}
+
+func TestImportEnrichesInBatches(t *testing.T) {
+	var lines []string
+	for i := 0; i < ChunkSize+5; i++ {
+		lines = append(lines, fmt.Sprintf(`{"name":"Ivan","surname":"Smith%c%c"}`, 'a'+i/26, 'a'+i%26))
+	}
+	repo := memory.New()
+	var calls batches
+
+	summary, err := New(repo, &calls).Run(context.Background(), strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if summary.Read != ChunkSize+5 || summary.Added != ChunkSize+5 {
+		t.Fatalf("got %+v", summary)
+	}
+	if len(calls) != 2 || calls[0] != ChunkSize || calls[1] != 5 {
+		t.Fatalf("got batches %v, want %d and 5", calls, ChunkSize)
+	}
+
+	users, err := repo.List(context.Background(), &storage.ListParam{User: model.User{NameKey: "ivan"}})
+	if err != nil || len(users) != ChunkSize+5 || users[0].Age == nil || *users[0].Age != 30 {
+		t.Fatalf("got %d stored people, %v", len(users), err)
+	}
+}
+
+func TestImportSkipsInvalidAndDuplicates(t *testing.T) {
+	input := `{"name":"  Ivan ","surname":"Smith","country_hint":"ru"}
+
+{"name":"ivan","surname":"SMITH"}
+{"name":"","surname":"Smith"}
+{"name":"Anna","surname":"Smith","age":30}
+not json
+{"name":"Anna","surname":"Smith","patronymic":"Petrovna"}
+`
+	repo := memory.New()
+	var calls batches
+
+	summary, err := New(repo, &calls).Run(context.Background(), strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := Summary{Read: 6, Added: 2, Duplicates: 1, Invalid: 3}
+	if summary != want {
+		t.Fatalf("got %+v, want %+v", summary, want)
+	}
+
+	users, err := repo.List(context.Background(), &storage.ListParam{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if users[0].Name != "Ivan" || users[0].CountryHint == nil || *users[0].CountryHint != "RU" {
+		t.Errorf("got %q with hint %v, want the cleaned name and an upper-case hint", users[0].Name, users[0].CountryHint)
+	}
+}
+
+func TestImportStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var calls batches
+	_, err := New(memory.New(), &calls).Run(ctx, strings.NewReader(`{"name":"Ivan","surname":"Smith"}`))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+}
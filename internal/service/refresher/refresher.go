@@ -0,0 +1,332 @@
+package refresher
+
+import (
+	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/service/enrichment"
+	"Effective_Mobile/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Enricher interface {
+	EnrichBatch(ctx context.Context, users []*model.User) ([]enrichment.Report, error)
+}
+
+// Summary — итог одного прохода.
+type Summary struct {
+	Checked int
+	Updated int
+	Changes int
+}
+
+// Refresher периодически повторно обогащает людей с пустыми, недостоверными
+// или устаревшими атрибутами. За проход обрабатывается не больше Budget
+// человек; следующий проход продолжает с места, где остановился предыдущий.
+type Refresher struct {
+	repo     storage.Repository
+	store    storage.RefreshStore
+	enricher Enricher
+	cfg      config.Refresh
+
+	mu     sync.Mutex
+	lastID int
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+func New(repo storage.Repository, store storage.RefreshStore, enricher Enricher, cfg config.Refresh) *Refresher {
+	return &Refresher{
+		repo:     repo,
+		store:    store,
+		enricher: enricher,
+		cfg:      cfg,
+	}
+}
+
+func (r *Refresher) Start(ctx context.Context) {
+	const op = "service.refresher.start"
+
+	ctx, r.cancel = context.WithCancel(ctx)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+
+			if _, err := r.RunOnce(ctx); err != nil && ctx.Err() == nil {
+				logger.Error("%s: refresh failed: %v", op, err)
+			}
+		}
+	}()
+
+	logger.Info("%s: refresh scheduled every %s", op, r.cfg.Interval)
+}
+
+// Stop останавливает планировщик и ждёт завершения текущего прохода.
+func (r *Refresher) Stop() {
+	const op = "service.refresher.stop"
+
+	if r.cancel == nil {
+		return
+	}
+
+	r.cancel()
+	r.wg.Wait()
+	logger.Info("%s: refresher stopped", op)
+}
+
+// RunOnce выполняет один проход: выбирает людей пачками по MaxBatchSize,
+// делая паузу BatchDelay между пачками, пока не исчерпан Budget.
+func (r *Refresher) RunOnce(ctx context.Context) (Summary, error) {
+	const op = "service.refresher.runOnce"
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var summary Summary
+	now := time.Now()
+	params := storage.StaleParam{
+		StaleBefore:   now.Add(-r.cfg.MaxAge),
+		MissingBefore: now.Add(-r.cfg.RetryMissing),
+	}
+
+	for summary.Checked < r.cfg.Budget {
+		if summary.Checked > 0 && r.cfg.BatchDelay > 0 {
+			timer := time.NewTimer(r.cfg.BatchDelay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return summary, fmt.Errorf("%s: %w", op, ctx.Err())
+			case <-timer.C:
+			}
+		}
+
+		params.AfterID = r.lastID
+		params.Limit = min(enrichment.MaxBatchSize, r.cfg.Budget-summary.Checked)
+
+		users, err := r.store.Stale(ctx, params)
+		if err != nil {
+			return summary, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if len(users) > 0 {
+			updated, changes, err := r.refresh(ctx, users)
+			if err != nil {
+				return summary, fmt.Errorf("%s: %w", op, err)
+			}
+			summary.Checked += len(users)
+			summary.Updated += updated
+			summary.Changes += changes
+			r.lastID = users[len(users)-1].ID
+		}
+
+		// дошли до конца таблицы — следующий проход начнётся сначала
+		if len(users) < params.Limit {
+			r.lastID = 0
+			break
+		}
+	}
+
+	logger.Info("%s: checked %d, updated %d, changes %d", op, summary.Checked, summary.Updated, summary.Changes)
+	return summary, nil
+}
+
+func (r *Refresher) refresh(ctx context.Context, users []*model.User) (int, int, error) {
+	const op = "service.refresher.refresh"
+
+	subjects := make([]*model.User, len(users))
+	for i, user := range users {
+		subjects[i] = &model.User{
+			Name:        user.Name,
+			Surname:     user.Surname,
+			Patronymic:  user.Patronymic,
+			CountryHint: user.CountryHint,
+			Nationality: user.Nationality,
+			Locked:      user.Locked,
+		}
+	}
+
+	reports, err := r.enricher.EnrichBatch(ctx, subjects)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	updated, changed := 0, 0
+	for i, user := range users {
+		update, changes := diff(user, subjects[i], reports[i])
+		if update == nil {
+			continue
+		}
+
+		// человека могли изменить, пока шло обогащение: такое обновление отбрасывается
+		update.Version = user.Version
+		touch := unchanged(user, update, changes)
+		if touch {
+			err = r.store.TouchEnrichedAt(ctx, user.ID, user.Version, *update.EnrichedAt, update.Enrichment)
+		} else {
+			err = r.repo.Update(ctx, user.ID, update)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return updated, changed, fmt.Errorf("%s: %w", op, err)
+			}
+			if errors.Is(err, storage.ErrVersionConflict) {
+				logger.Info("%s: person %d changed during refresh, skipped", op, user.ID)
+				continue
+			}
+			logger.Error("%s: failed to update person %d: %v", op, user.ID, err)
+			continue
+		}
+		if touch {
+			logger.Debug("%s: person %d unchanged, version kept", op, user.ID)
+			continue
+		}
+		updated++
+
+		if len(changes) == 0 {
+			continue
+		}
+		if err = r.store.RecordChanges(ctx, changes); err != nil {
+			logger.Error("%s: failed to record changes for person %d: %v", op, user.ID, err)
+			continue
+		}
+		changed += len(changes)
+		logger.Info("%s: person %d: %d attributes changed", op, user.ID, len(changes))
+	}
+
+	return updated, changed, nil
+}
+
+// diff собирает обновление из успешно полученных атрибутов и список
+// изменившихся значений. Недостоверный ответ не стирает уже известное
+// значение, а ошибка провайдера оставляет атрибут как есть. Время попытки
+// сохраняется всегда, чтобы атрибут без ответа не запрашивался на каждом проходе.
+func diff(current, enriched *model.User, report enrichment.Report) (*model.User, []model.EnrichmentChange) {
+	update := &model.User{Enrichment: &model.Enrichment{}, EnrichedAt: enriched.EnrichedAt}
+	var changes []model.EnrichmentChange
+
+	attrs := []struct {
+		name       string
+		old, new   *string
+		attributed *model.Attribution
+		set        func(*model.Attribution)
+		apply      func()
+	}{
+		{
+			name:       enrichment.AttrAge,
+			old:        itoa(current.Age),
+			new:        itoa(enriched.Age),
+			attributed: enriched.Enrichment.Age,
+			set:        func(a *model.Attribution) { update.Enrichment.Age = a },
+			apply:      func() { update.Age = enriched.Age },
+		},
+		{
+			name:       enrichment.AttrGender,
+			old:        current.Gender,
+			new:        enriched.Gender,
+			attributed: enriched.Enrichment.Gender,
+			set:        func(a *model.Attribution) { update.Enrichment.Gender = a },
+			apply:      func() { update.Gender = enriched.Gender },
+		},
+		{
+			name:       enrichment.AttrNationality,
+			old:        current.Nationality,
+			new:        enriched.Nationality,
+			attributed: enriched.Enrichment.Nationality,
+			set:        func(a *model.Attribution) { update.Enrichment.Nationality = a },
+			apply:      func() { update.Nationality = enriched.Nationality },
+		},
+	}
+
+	touched := false
+	pending := make([]string, 0)
+	for _, a := range attrs {
+		switch report[a.name] {
+		case enrichment.StatusSucceeded:
+			a.set(a.attributed)
+			a.apply()
+			touched = true
+			if !equal(a.old, a.new) {
+				changes = append(changes, model.EnrichmentChange{
+					PersonID:  current.ID,
+					Attribute: a.name,
+					OldValue:  a.old,
+					NewValue:  a.new,
+					Provider:  a.attributed.Provider,
+					ChangedAt: a.attributed.FetchedAt,
+				})
+			}
+		case enrichment.StatusLowConfidence:
+			// без атрибуции (ErrNoMatch) обновлять нечего
+			if a.attributed == nil {
+				continue
+			}
+			a.set(a.attributed)
+			touched = true
+		case enrichment.StatusFailed:
+			if a.old == nil {
+				pending = append(pending, a.name)
+			}
+		}
+	}
+
+	if !touched {
+		if enriched.EnrichedAt == nil {
+			return nil, nil
+		}
+		return &model.User{EnrichedAt: enriched.EnrichedAt}, nil
+	}
+
+	update.PendingEnrichment = pending
+	update.EnrichmentStatus = model.EnrichmentComplete
+	if len(pending) > 0 {
+		update.EnrichmentStatus = model.EnrichmentPartial
+	}
+	return update, changes
+}
+
+// unchanged сообщает, что обновление не меняет ни значений, ни статуса
+// обогащения: тогда достаточно сохранить время попытки и происхождение без
+// новой версии записи.
+func unchanged(current, update *model.User, changes []model.EnrichmentChange) bool {
+	if len(changes) > 0 || update.EnrichedAt == nil {
+		return false
+	}
+	if update.EnrichmentStatus != "" && update.EnrichmentStatus != current.EnrichmentStatus {
+		return false
+	}
+	return update.PendingEnrichment == nil || slices.Equal(update.PendingEnrichment, current.PendingEnrichment) ||
+		len(update.PendingEnrichment) == 0 && len(current.PendingEnrichment) == 0
+}
+
+func itoa(v *int) *string {
+	if v == nil {
+		return nil
+	}
+	s := strconv.Itoa(*v)
+	return &s
+}
+
+func equal(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
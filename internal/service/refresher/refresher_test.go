@@ -0,0 +1,129 @@
+package refresher
+
+import (
+	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/httpserver/handlers/get"
+	"Effective_Mobile/internal/httpserver/handlers/handlertest"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/service/enrichment"
+	"Effective_Mobile/internal/storage/memory"
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// enricherFunc обогащает пачку функцией, заданной в тесте.
+type enricherFunc func(ctx context.Context, users []*model.User) ([]enrichment.Report, error)
+
+func (f enricherFunc) EnrichBatch(ctx context.Context, users []*model.User) ([]enrichment.Report, error) {
+	return f(ctx, users)
+}
+
+// ages отвечает возрастом age, полом male и страной RU.
+func ages(age int) enricherFunc {
+	return func(ctx context.Context, users []*model.User) ([]enrichment.Report, error) {
+		now := time.Now().UTC()
+		gender, country := "male", "RU"
+
+		reports := make([]enrichment.Report, len(users))
+		for i, u := range users {
+			u.Age, u.Gender, u.Nationality = &age, &gender, &country
+			u.Enrichment = &model.Enrichment{
+				Age:         &model.Attribution{Provider: "agify", FetchedAt: now},
+				Gender:      &model.Attribution{Provider: "genderize", FetchedAt: now},
+				Nationality: &model.Attribution{Provider: "nationalize", FetchedAt: now},
+			}
+			u.EnrichedAt = &now
+			reports[i] = enrichment.Report{
+				enrichment.AttrAge:         enrichment.StatusSucceeded,
+				enrichment.AttrGender:      enrichment.StatusSucceeded,
+				enrichment.AttrNationality: enrichment.StatusSucceeded,
+			}
+		}
+		return reports, nil
+	}
+}
+
+var testConfig = config.Refresh{MaxAge: 24 * time.Hour, RetryMissing: time.Hour, Budget: 10}
+
+// addStale сохраняет Ивана 47 лет, обогащённого раньше MaxAge.
+func addStale(t *testing.T, repo *memory.Storage) int {
+	t.Helper()
+
+	fetched := time.Now().UTC().Add(-48 * time.Hour)
+	age, gender, country := 47, "male", "RU"
+	id, err := repo.Add(context.Background(), model.User{
+		Name: "Ivan", Surname: "Smith", NameKey: "ivan", SurnameKey: "smith",
+		Age: &age, Gender: &gender, Nationality: &country,
+		PendingEnrichment: []string{},
+		EnrichmentStatus:  model.EnrichmentComplete,
+		Enrichment: &model.Enrichment{
+			Age:         &model.Attribution{Provider: "agify", FetchedAt: fetched},
+			Gender:      &model.Attribution{Provider: "genderize", FetchedAt: fetched},
+			Nationality: &model.Attribution{Provider: "nationalize", FetchedAt: fetched},
+		},
+		EnrichedAt: &fetched,
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	return id
+}
+
+func etag(t *testing.T, repo *memory.Storage, id int) string {
+	t.Helper()
+
+	rec := handlertest.Do(t, get.NewByID(repo), http.MethodGet, "/people/"+strconv.Itoa(id), "")
+	handlertest.AssertStatus(t, rec, http.StatusOK, nil)
+	return rec.Header().Get("ETag")
+}
+
+func TestRefreshWithoutChangesKeepsETag(t *testing.T) {
+	repo := memory.New()
+	id := addStale(t, repo)
+	before := etag(t, repo, id)
+
+	summary, err := New(repo, repo, ages(47), testConfig).RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if summary.Checked != 1 || summary.Updated != 0 || summary.Changes != 0 {
+		t.Fatalf("got summary %+v, want one person checked and nothing updated", summary)
+	}
+
+	if after := etag(t, repo, id); after != before {
+		t.Errorf("got ETag %s after a refresh without changes, want %s", after, before)
+	}
+	// время попытки сохранено, поэтому человек не выбирается на следующем проходе
+	user := handlertest.Stored(t, repo, id)
+	if time.Since(user.Enrichment.Age.FetchedAt) > time.Minute || time.Since(*user.EnrichedAt) > time.Minute {
+		t.Errorf("got fetched_at %s, enriched_at %s, want the refresh time", user.Enrichment.Age.FetchedAt, user.EnrichedAt)
+	}
+	if summary, _ = New(repo, repo, ages(47), testConfig).RunOnce(context.Background()); summary.Checked != 0 {
+		t.Errorf("refreshed person selected again: %+v", summary)
+	}
+}
+
+func TestRefreshRecordsChanges(t *testing.T) {
+	repo := memory.New()
+	id := addStale(t, repo)
+	before := etag(t, repo, id)
+
+	summary, err := New(repo, repo, ages(48), testConfig).RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if summary.Updated != 1 || summary.Changes != 1 {
+		t.Fatalf("got summary %+v, want one update with one change", summary)
+	}
+
+	if after := etag(t, repo, id); after == before {
+		t.Errorf("ETag %s did not change with the age", after)
+	}
+	changes, err := repo.Changes(context.Background(), id)
+	if err != nil || len(changes) != 1 || changes[0].Attribute != enrichment.AttrAge {
+		t.Fatalf("got changes %+v, %v, want the age", changes, err)
+	}
+}
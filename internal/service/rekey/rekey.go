@@ -0,0 +1,90 @@
+package rekey
+
+import (
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/storage"
+	"Effective_Mobile/lib/normalize"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Conflict — люди, имена которых дают один ключ: уникальный индекс по ключам
+// нельзя создать, пока их не переименуют или не удалят.
+type Conflict struct {
+	NameKey    string
+	SurnameKey string
+	IDs        []int
+}
+
+// Summary — итог пересчёта.
+type Summary struct {
+	Checked int
+	Updated int
+	// Blocked — ключи, которые не записаны, потому что их занимает другой
+	// человек со старым ключом (уникальный индекс уже создан).
+	Blocked   int
+	Conflicts []Conflict
+}
+
+// Run пересчитывает ключи всех людей по normalize.Key. Ключи людей из
+// конфликтующих групп не меняются: группы возвращаются в Summary.Conflicts.
+// При dryRun ничего не записывается.
+func Run(ctx context.Context, store storage.KeyStore, dryRun bool) (Summary, error) {
+	const op = "service.rekey.run"
+
+	users, err := store.NameKeys(ctx)
+	if err != nil {
+		return Summary{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	type key struct{ name, surname string }
+
+	var (
+		summary = Summary{Checked: len(users)}
+		order   []key
+		groups  = make(map[key][]int, len(users))
+		current = make(map[int]key, len(users))
+	)
+	for _, u := range users {
+		k := key{normalize.Key(u.Name), normalize.Key(u.Surname)}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], u.ID)
+		current[u.ID] = key{u.NameKey, u.SurnameKey}
+	}
+
+	for _, k := range order {
+		ids := groups[k]
+		if len(ids) > 1 {
+			logger.Error("%s: people %v share keys %q %q", op, ids, k.name, k.surname)
+			summary.Conflicts = append(summary.Conflicts, Conflict{NameKey: k.name, SurnameKey: k.surname, IDs: ids})
+			continue
+		}
+
+		id := ids[0]
+		if current[id] == k {
+			continue
+		}
+		if dryRun {
+			summary.Updated++
+			continue
+		}
+
+		err = store.SetNameKeys(ctx, id, k.name, k.surname)
+		if errors.Is(err, storage.ErrUserExists) {
+			logger.Error("%s: keys %q %q of person %d are taken by an old key", op, k.name, k.surname, id)
+			summary.Blocked++
+			continue
+		}
+		if err != nil {
+			return summary, fmt.Errorf("%s: %w", op, err)
+		}
+		summary.Updated++
+	}
+
+	logger.Info("%s: checked %d, updated %d, blocked %d, conflicts %d",
+		op, summary.Checked, summary.Updated, summary.Blocked, len(summary.Conflicts))
+	return summary, nil
+}
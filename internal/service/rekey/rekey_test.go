@@ -0,0 +1,110 @@
+package rekey
+
+import (
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/storage"
+	"Effective_Mobile/internal/storage/memory"
+	"context"
+	"slices"
+	"testing"
+)
+
+// add сохраняет человека с ключами, как их заполнила старая миграция: lower без ё → е и транслитерации.
+func add(t *testing.T, repo *memory.Storage, name, surname, nameKey string) int {
+	t.Helper()
+
+	id, err := repo.Add(context.Background(), model.User{Name: name, Surname: surname, NameKey: nameKey, SurnameKey: "smith"})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	return id
+}
+
+func keys(t *testing.T, repo *memory.Storage, id int) string {
+	t.Helper()
+
+	users, err := repo.List(context.Background(), &storage.ListParam{User: model.User{ID: id}})
+	if err != nil || len(users) != 1 {
+		t.Fatalf("List: %v", err)
+	}
+	return users[0].NameKey + " " + users[0].SurnameKey
+}
+
+func TestRunRecomputesKeys(t *testing.T) {
+	repo := memory.New()
+	ctx := context.Background()
+	petr := add(t, repo, "Пётр", "Smith", "пётр")
+	ivan := add(t, repo, "Ivan", "Smith", "ivan")
+
+	dry, err := Run(ctx, repo, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if dry.Checked != 2 || dry.Updated != 1 || keys(t, repo, petr) != "пётр smith" {
+		t.Fatalf("dry run: got %+v, keys %q", dry, keys(t, repo, petr))
+	}
+
+	summary, err := Run(ctx, repo, false)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if summary.Updated != 1 || len(summary.Conflicts) != 0 {
+		t.Fatalf("got %+v", summary)
+	}
+	if keys(t, repo, petr) != "petr smith" || keys(t, repo, ivan) != "ivan smith" {
+		t.Fatalf("got keys %q and %q", keys(t, repo, petr), keys(t, repo, ivan))
+	}
+
+	// повторный запуск ничего не меняет
+	if again, _ := Run(ctx, repo, false); again.Updated != 0 {
+		t.Fatalf("second run updated %d people", again.Updated)
+	}
+}
+
+func TestRunReportsConflicts(t *testing.T) {
+	repo := memory.New()
+	ctx := context.Background()
+	cyrillic := add(t, repo, "Дмитрий", "Smith", "дмитрий")
+	latin := add(t, repo, "dmitriy", "Smith", "dmitriy")
+	anna := add(t, repo, "Анна", "Smith", "анна")
+
+	summary, err := Run(ctx, repo, false)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(summary.Conflicts) != 1 {
+		t.Fatalf("got conflicts %+v, want Дмитрий and dmitriy", summary.Conflicts)
+	}
+	c := summary.Conflicts[0]
+	if c.NameKey != "dmitriy" || !slices.Equal(c.IDs, []int{cyrillic, latin}) {
+		t.Fatalf("got conflict %+v", c)
+	}
+	// конфликтующие ключи не трогаются, остальные пересчитываются
+	if keys(t, repo, cyrillic) != "дмитрий smith" || keys(t, repo, anna) != "anna smith" {
+		t.Fatalf("got keys %q and %q", keys(t, repo, cyrillic), keys(t, repo, anna))
+	}
+}
+
+func TestRunBlockedByOldKey(t *testing.T) {
+	repo := memory.New()
+	ctx := context.Background()
+	// новый ключ Анны пока занят старым ключом человека, которого обработают позже
+	anna := add(t, repo, "Анна", "Smith", "анна")
+	add(t, repo, "Zed", "Smith", "anna")
+
+	summary, err := Run(ctx, repo, false)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if summary.Blocked != 1 || summary.Updated != 1 {
+		t.Fatalf("got %+v, want Anna blocked and Zed updated", summary)
+	}
+
+	// второй запуск дописывает освободившийся ключ
+	if summary, err = Run(ctx, repo, false); err != nil || summary.Updated != 1 || summary.Blocked != 0 {
+		t.Fatalf("second run: got %+v, %v", summary, err)
+	}
+	if keys(t, repo, anna) != "anna smith" {
+		t.Fatalf("got keys %q", keys(t, repo, anna))
+	}
+}
@@ -0,0 +1,275 @@
+package worker
+
+import (
+	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/service/enrichment"
+	"Effective_Mobile/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Enricher interface {
+	EnrichBatch(ctx context.Context, users []*model.User) ([]enrichment.Report, error)
+}
+
+// Worker разбирает очередь задач обогащения пулом горутин и дописывает
+// полученные атрибуты в хранилище. Задачи обрабатываются пачками, чтобы
+// имена уходили к провайдерам пакетными запросами.
+type Worker struct {
+	queue    storage.JobQueue
+	repo     storage.Repository
+	enricher Enricher
+	cfg      config.Worker
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+func New(queue storage.JobQueue, repo storage.Repository, enricher Enricher, cfg config.Worker) *Worker {
+	return &Worker{
+		queue:    queue,
+		repo:     repo,
+		enricher: enricher,
+		cfg:      cfg,
+	}
+}
+
+func (w *Worker) Start(ctx context.Context) {
+	const op = "service.worker.start"
+
+	ctx, w.cancel = context.WithCancel(ctx)
+	jobs := make(chan []*model.EnrichmentJob)
+
+	w.wg.Add(1)
+	go w.poll(ctx, jobs)
+
+	for i := 0; i < w.cfg.Count; i++ {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			for batch := range jobs {
+				w.process(ctx, batch)
+			}
+		}()
+	}
+
+	logger.Info("%s: started %d workers", op, w.cfg.Count)
+}
+
+// Stop останавливает опрос очереди и ждёт завершения обрабатываемых задач.
+func (w *Worker) Stop() {
+	const op = "service.worker.stop"
+
+	if w.cancel == nil {
+		return
+	}
+
+	w.cancel()
+	w.wg.Wait()
+	logger.Info("%s: workers stopped", op)
+}
+
+func (w *Worker) poll(ctx context.Context, jobs chan<- []*model.EnrichmentJob) {
+	const op = "service.worker.poll"
+
+	defer w.wg.Done()
+	defer close(jobs)
+
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		claimed, err := w.queue.Claim(ctx, w.cfg.BatchSize, w.cfg.Lease)
+		if err != nil && ctx.Err() == nil {
+			logger.Error("%s: failed to claim jobs: %v", op, err)
+		}
+
+		for start := 0; start < len(claimed); start += enrichment.MaxBatchSize {
+			select {
+			case jobs <- claimed[start:min(start+enrichment.MaxBatchSize, len(claimed))]:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		// полная пачка — вероятно, в очереди есть ещё задачи
+		if len(claimed) == w.cfg.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// conflictRetries — сколько раз задача перечитывает человека и обогащает его
+// заново, если его изменили, пока шло обогащение.
+const conflictRetries = 2
+
+func (w *Worker) process(ctx context.Context, batch []*model.EnrichmentJob) {
+	for attempt := 0; len(batch) > 0 && ctx.Err() == nil; attempt++ {
+		batch = w.run(ctx, batch, attempt < conflictRetries)
+	}
+}
+
+// run обогащает пачку и возвращает задачи, чей результат устарел из-за
+// параллельного изменения человека: их нужно выполнить заново.
+func (w *Worker) run(ctx context.Context, batch []*model.EnrichmentJob, rerun bool) []*model.EnrichmentJob {
+	const op = "service.worker.run"
+
+	jobs := make([]*model.EnrichmentJob, 0, len(batch))
+	updates := make([]*model.User, 0, len(batch))
+
+	for _, job := range batch {
+		logger.Debug("%s: job %d (person %d, attempt %d)", op, job.ID, job.PersonID, job.Attempts)
+
+		users, err := w.repo.List(ctx, &storage.ListParam{User: model.User{ID: job.PersonID}})
+		if err != nil {
+			w.retry(ctx, job, err)
+			continue
+		}
+
+		if len(users) == 0 {
+			logger.Info("%s: person %d no longer exists, job %d dropped", op, job.PersonID, job.ID)
+			w.complete(ctx, job)
+			continue
+		}
+
+		jobs = append(jobs, job)
+		updates = append(updates, &model.User{
+			Name:        users[0].Name,
+			Surname:     users[0].Surname,
+			Patronymic:  users[0].Patronymic,
+			CountryHint: users[0].CountryHint,
+			Nationality: users[0].Nationality,
+			Locked:      users[0].Locked,
+			Version:     users[0].Version,
+		})
+	}
+
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	if _, err := w.enricher.EnrichBatch(ctx, updates); err != nil {
+		for _, job := range jobs {
+			w.retry(ctx, job, err)
+		}
+		return nil
+	}
+
+	var stale []*model.EnrichmentJob
+	for i, job := range jobs {
+		if w.save(ctx, job, updates[i], rerun) {
+			stale = append(stale, job)
+		}
+	}
+	return stale
+}
+
+// save записывает результат обогащения и завершает задачу либо откладывает
+// её, если часть атрибутов получить не удалось. Запись проверяет версию,
+// прочитанную до обогащения: если человека за это время изменили, результат
+// отбрасывается и save возвращает true, когда разрешено обогатить его заново.
+func (w *Worker) save(ctx context.Context, job *model.EnrichmentJob, update *model.User, rerun bool) bool {
+	const op = "service.worker.save"
+
+	update.Name = ""
+	update.Surname = ""
+	update.Patronymic = nil
+	update.CountryHint = nil
+	// заданные вручную атрибуты не перезаписываются
+	if slices.Contains(update.Locked, enrichment.AttrNationality) {
+		update.Nationality = nil
+	}
+	update.Locked = nil
+
+	pending := len(update.PendingEnrichment) > 0
+	if pending && job.Attempts < w.cfg.MaxAttempts {
+		update.EnrichmentStatus = model.EnrichmentPending
+	}
+
+	if err := w.repo.Update(ctx, job.PersonID, update); err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) && rerun {
+			logger.Info("%s: person %d changed during enrichment, job %d runs again", op, job.PersonID, job.ID)
+			return true
+		}
+		w.retry(ctx, job, err)
+		return false
+	}
+
+	if !pending {
+		w.complete(ctx, job)
+		return false
+	}
+
+	w.retry(ctx, job, fmt.Errorf("pending attributes: %s", strings.Join(update.PendingEnrichment, ", ")))
+	return false
+}
+
+func (w *Worker) complete(ctx context.Context, job *model.EnrichmentJob) {
+	const op = "service.worker.complete"
+
+	if err := w.queue.Complete(ctx, job.ID); err != nil {
+		logger.Error("%s: failed to complete job %d: %v", op, job.ID, err)
+		return
+	}
+	logger.Info("%s: job %d done, person %d enriched", op, job.ID, job.PersonID)
+}
+
+// retry откладывает задачу с экспоненциальной задержкой, а после
+// MaxAttempts попыток переносит её в dead letter.
+func (w *Worker) retry(ctx context.Context, job *model.EnrichmentJob, cause error) {
+	const op = "service.worker.retry"
+
+	if ctx.Err() != nil {
+		logger.Info("%s: job %d interrupted, it will be reclaimed after lease", op, job.ID)
+		return
+	}
+
+	if job.Attempts >= w.cfg.MaxAttempts {
+		logger.Error("%s: job %d dead after %d attempts: %v", op, job.ID, job.Attempts, cause)
+		if err := w.queue.Fail(ctx, job.ID, cause.Error()); err != nil {
+			logger.Error("%s: failed to dead-letter job %d: %v", op, job.ID, err)
+		}
+		w.markFailed(ctx, job.PersonID)
+		return
+	}
+
+	runAt := time.Now().Add(w.cfg.RetryDelay << (job.Attempts - 1))
+	logger.Info("%s: job %d retry at %s: %v", op, job.ID, runAt.Format(time.RFC3339), cause)
+	if err := w.queue.Retry(ctx, job.ID, runAt, cause.Error()); err != nil {
+		logger.Error("%s: failed to reschedule job %d: %v", op, job.ID, err)
+	}
+}
+
+// markFailed выставляет статус failed, если человек так и остался без
+// атрибутов, и partial, если часть из них получить удалось.
+func (w *Worker) markFailed(ctx context.Context, personID int) {
+	const op = "service.worker.markFailed"
+
+	users, err := w.repo.List(ctx, &storage.ListParam{User: model.User{ID: personID}})
+	if err != nil || len(users) == 0 {
+		return
+	}
+
+	user := users[0]
+	status := model.EnrichmentPartial
+	if user.Age == nil && user.Gender == nil && user.Nationality == nil {
+		status = model.EnrichmentFailed
+	}
+
+	if err = w.repo.Update(ctx, personID, &model.User{EnrichmentStatus: status}); err != nil {
+		logger.Error("%s: failed to mark person %d as %s: %v", op, personID, status, err)
+	}
+}
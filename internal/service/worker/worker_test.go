@@ -0,0 +1,161 @@
+package worker
+
+import (
+	"Effective_Mobile/internal/config"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/service/enrichment"
+	"Effective_Mobile/internal/storage"
+	"Effective_Mobile/internal/storage/memory"
+	"context"
+	"testing"
+	"time"
+)
+
+// enricherFunc обогащает пачку функцией, заданной в тесте.
+type enricherFunc func(ctx context.Context, users []*model.User) ([]enrichment.Report, error)
+
+func (f enricherFunc) EnrichBatch(ctx context.Context, users []*model.User) ([]enrichment.Report, error) {
+	return f(ctx, users)
+}
+
+// ages обогащает возраст, а пустой age оставляет его в pending_enrichment.
+func ages(age int) enricherFunc {
+	return func(ctx context.Context, users []*model.User) ([]enrichment.Report, error) {
+		reports := make([]enrichment.Report, len(users))
+		for i, u := range users {
+			if age == 0 {
+				u.PendingEnrichment = []string{enrichment.AttrAge}
+				u.EnrichmentStatus = model.EnrichmentPartial
+				reports[i] = enrichment.Report{enrichment.AttrAge: enrichment.StatusFailed}
+				continue
+			}
+			u.Age = &age
+			u.PendingEnrichment = []string{}
+			u.EnrichmentStatus = model.EnrichmentComplete
+			reports[i] = enrichment.Report{enrichment.AttrAge: enrichment.StatusSucceeded}
+		}
+		return reports, nil
+	}
+}
+
+// enqueue добавляет человека и задачу на его обогащение.
+func enqueue(t *testing.T, repo *memory.Storage) int {
+	t.Helper()
+
+	ctx := context.Background()
+	id, err := repo.Add(ctx, model.User{Name: "Ivan", Surname: "Smith", NameKey: "ivan", SurnameKey: "smith",
+		EnrichmentStatus: model.EnrichmentPending})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err = repo.Enqueue(ctx, id); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	return id
+}
+
+// runOnce захватывает задачи и обрабатывает их так же, как пул воркеров.
+func runOnce(t *testing.T, w *Worker, repo *memory.Storage) {
+	t.Helper()
+
+	jobs, err := repo.Claim(context.Background(), w.cfg.BatchSize, w.cfg.Lease)
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	w.process(context.Background(), jobs)
+}
+
+func job(t *testing.T, repo *memory.Storage, id int) *model.EnrichmentJob {
+	t.Helper()
+
+	jobs, err := repo.Jobs(context.Background(), id)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("Jobs: got %v, %v", jobs, err)
+	}
+	return jobs[0]
+}
+
+func stored(t *testing.T, repo *memory.Storage, id int) *model.User {
+	t.Helper()
+
+	users, err := repo.List(context.Background(), &storage.ListParam{User: model.User{ID: id}})
+	if err != nil || len(users) != 1 {
+		t.Fatalf("List: got %v, %v", users, err)
+	}
+	return users[0]
+}
+
+var testConfig = config.Worker{Count: 1, BatchSize: 10, MaxAttempts: 2, RetryDelay: time.Minute, Lease: time.Minute}
+
+func TestWorkerEnrichesQueuedPerson(t *testing.T) {
+	repo := memory.New()
+	id := enqueue(t, repo)
+
+	runOnce(t, New(repo, repo, ages(47), testConfig), repo)
+
+	if j := job(t, repo, id); j.Status != model.JobDone {
+		t.Fatalf("got job status %q, want done", j.Status)
+	}
+	user := stored(t, repo, id)
+	if user.Age == nil || *user.Age != 47 || user.EnrichmentStatus != model.EnrichmentComplete {
+		t.Fatalf("got age %v, status %q", user.Age, user.EnrichmentStatus)
+	}
+	if user.Name != "Ivan" {
+		t.Fatalf("worker overwrote the name with %q", user.Name)
+	}
+}
+
+func TestWorkerRetriesPendingThenFails(t *testing.T) {
+	repo := memory.New()
+	id := enqueue(t, repo)
+	w := New(repo, repo, ages(0), testConfig)
+
+	runOnce(t, w, repo)
+	j := job(t, repo, id)
+	if j.Status != model.JobQueued || j.LastError == nil || !j.RunAt.After(time.Now()) {
+		t.Fatalf("got job %+v, want it queued for later", j)
+	}
+	if status := stored(t, repo, id).EnrichmentStatus; status != model.EnrichmentPending {
+		t.Fatalf("got status %q, want pending while attempts remain", status)
+	}
+
+	// последняя попытка: задача уходит в dead letter, человек без атрибутов — failed
+	repo.Retry(context.Background(), j.ID, time.Now(), "")
+	runOnce(t, w, repo)
+	if j = job(t, repo, id); j.Status != model.JobDead {
+		t.Fatalf("got job status %q, want dead after MaxAttempts", j.Status)
+	}
+	if status := stored(t, repo, id).EnrichmentStatus; status != model.EnrichmentFailed {
+		t.Fatalf("got status %q, want failed", status)
+	}
+}
+
+func TestWorkerRerunsAfterConcurrentChange(t *testing.T) {
+	repo := memory.New()
+	id := enqueue(t, repo)
+
+	runs := 0
+	enrich := ages(47)
+	w := New(repo, repo, enricherFunc(func(ctx context.Context, users []*model.User) ([]enrichment.Report, error) {
+		runs++
+		if runs == 1 {
+			// человека меняют, пока идёт обогащение
+			patronymic := "Petrovich"
+			if err := repo.Update(ctx, id, &model.User{Patronymic: &patronymic}); err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+		}
+		return enrich(ctx, users)
+	}), testConfig)
+
+	runOnce(t, w, repo)
+
+	if runs != 2 {
+		t.Fatalf("enriched %d times, want a rerun on the new version", runs)
+	}
+	user := stored(t, repo, id)
+	if user.Patronymic == nil || *user.Patronymic != "Petrovich" || user.Age == nil {
+		t.Fatalf("got patronymic %v, age %v, want both the concurrent change and the enrichment",
+			user.Patronymic, user.Age)
+	}
+}
@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/storage"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var _ storage.JobQueue = (*Storage)(nil)
+
+func (s *Storage) Enqueue(ctx context.Context, personID int) error {
+	const op = "storage.memory.enqueue"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[personID]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	now := time.Now()
+	s.nextJobID++
+	s.jobs[s.nextJobID] = &model.EnrichmentJob{
+		ID:        s.nextJobID,
+		PersonID:  personID,
+		Status:    model.JobQueued,
+		RunAt:     now,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	logger.Debug("%s: enrichment job queued for person %d", op, personID)
+	return nil
+}
+
+func (s *Storage) Claim(ctx context.Context, limit int, lease time.Duration) ([]*model.EnrichmentJob, error) {
+	const op = "storage.memory.claim"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	ready := make([]*model.EnrichmentJob, 0)
+	for _, job := range s.jobs {
+		if (job.Status == model.JobQueued && !job.RunAt.After(now)) ||
+			(job.Status == model.JobRunning && now.Sub(job.UpdatedAt) > lease) {
+			ready = append(ready, job)
+		}
+	}
+
+	sort.Slice(ready, func(i, j int) bool { return ready[i].RunAt.Before(ready[j].RunAt) })
+	if len(ready) > limit {
+		ready = ready[:limit]
+	}
+
+	claimed := make([]*model.EnrichmentJob, len(ready))
+	for i, job := range ready {
+		job.Status = model.JobRunning
+		job.Attempts++
+		job.UpdatedAt = now
+		j := *job
+		claimed[i] = &j
+	}
+
+	return claimed, nil
+}
+
+func (s *Storage) Complete(ctx context.Context, jobID int64) error {
+	const op = "storage.memory.complete"
+
+	return s.setJobStatus(ctx, op, jobID, model.JobDone, nil, nil)
+}
+
+func (s *Storage) Retry(ctx context.Context, jobID int64, runAt time.Time, reason string) error {
+	const op = "storage.memory.retry"
+
+	return s.setJobStatus(ctx, op, jobID, model.JobQueued, &runAt, &reason)
+}
+
+func (s *Storage) Fail(ctx context.Context, jobID int64, reason string) error {
+	const op = "storage.memory.fail"
+
+	return s.setJobStatus(ctx, op, jobID, model.JobDead, nil, &reason)
+}
+
+func (s *Storage) Jobs(ctx context.Context, personID int) ([]*model.EnrichmentJob, error) {
+	const op = "storage.memory.jobs"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobs := make([]*model.EnrichmentJob, 0)
+	for _, job := range s.jobs {
+		if job.PersonID == personID {
+			j := *job
+			jobs = append(jobs, &j)
+		}
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs, nil
+}
+
+func (s *Storage) setJobStatus(ctx context.Context, op string, jobID int64, status string,
+	runAt *time.Time, reason *string) error {
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[jobID]
+	if !ok {
+		logger.Debug("%s: job %d not found", op, jobID)
+		return nil
+	}
+
+	job.Status = status
+	job.UpdatedAt = time.Now()
+	if runAt != nil {
+		job.RunAt = *runAt
+	}
+	if reason != nil {
+		job.LastError = copyPtr(reason)
+	}
+
+	logger.Debug("%s: job %d is %s", op, jobID, status)
+	return nil
+}
@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/storage"
+	"context"
+	"fmt"
+	"sort"
+)
+
+var _ storage.KeyStore = (*Storage)(nil)
+
+func (s *Storage) NameKeys(ctx context.Context) ([]*model.User, error) {
+	const op = "storage.memory.nameKeys"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]*model.User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, &model.User{ID: u.ID, Name: u.Name, Surname: u.Surname,
+			NameKey: u.NameKey, SurnameKey: u.SurnameKey})
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (s *Storage) SetNameKeys(ctx context.Context, id int, nameKey, surnameKey string) error {
+	const op = "storage.memory.setNameKeys"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if s.exists(nameKey, surnameKey, id) {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+	}
+
+	user.NameKey, user.SurnameKey = nameKey, surnameKey
+	s.users[id] = user
+	s.rev++
+	return nil
+}
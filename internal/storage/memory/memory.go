@@ -0,0 +1,334 @@
+package memory
+
+import (
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/storage"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var _ storage.Repository = (*Storage)(nil)
+
+// Storage хранит людей в памяти процесса. Повторяет поведение pg.Storage,
+// включая уникальность пары (name_key, surname_key).
+type Storage struct {
+	mu     sync.RWMutex
+	users  map[int]model.User
+	nextID int
+
+	jobs      map[int64]*model.EnrichmentJob
+	nextJobID int64
+
+	changes      []model.EnrichmentChange
+	nextChangeID int64
+
+	// rev увеличивается при каждом изменении users и проверяется при фиксации транзакции.
+	rev    uint64
+	inside bool
+}
+
+func New() *Storage {
+	const op = "storage.memory.new"
+
+	logger.Info("%s: using in-memory storage", op)
+	return &Storage{
+		users: make(map[int]model.User),
+		jobs:  make(map[int64]*model.EnrichmentJob),
+	}
+}
+
+func (s *Storage) Add(ctx context.Context, user model.User) (int, error) {
+	const op = "storage.memory.add"
+
+	if err := ctx.Err(); err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.exists(user.NameKey, user.SurnameKey, 0) {
+		logger.Error("%s: user %s %s already exists", op, user.Name, user.Surname)
+		return -1, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+	}
+
+	if user.EnrichmentStatus == "" {
+		user.EnrichmentStatus = model.EnrichmentComplete
+	}
+
+	s.nextID++
+	user.ID = s.nextID
+	user.Version = 1
+	s.users[user.ID] = clone(user)
+	s.rev++
+
+	logger.Debug("%s: user added with ID %d", op, user.ID)
+	return user.ID, nil
+}
+
+func (s *Storage) List(ctx context.Context, params *storage.ListParam) ([]*model.User, error) {
+	const op = "storage.memory.list"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]*model.User, 0)
+	for _, user := range s.users {
+		if match(user, params.User) {
+			u := clone(user)
+			users = append(users, &u)
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	if params.Limit > 0 {
+		if params.Offset >= len(users) {
+			users = users[:0]
+		} else {
+			if params.Offset > 0 {
+				users = users[params.Offset:]
+			}
+			if params.Limit < len(users) {
+				users = users[:params.Limit]
+			}
+		}
+	}
+
+	logger.Debug("%s: found %d users", op, len(users))
+	return users, nil
+}
+
+func (s *Storage) Update(ctx context.Context, id int, user *model.User) error {
+	const op = "storage.memory.update"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if isEmpty(*user) {
+		logger.Error("%s: nothing to update", op)
+		return fmt.Errorf("%s: %w", op, storage.ErrNothingUpdate)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.users[id]
+	if !ok {
+		logger.Debug("%s: user with ID %d not found for update", op, id)
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if user.Version > 0 && user.Version != current.Version {
+		logger.Info("%s: user with ID %d is no longer at version %d", op, id, user.Version)
+		return fmt.Errorf("%s: %w", op, storage.ErrVersionConflict)
+	}
+
+	updated := merge(current, *user)
+	if s.exists(updated.NameKey, updated.SurnameKey, id) {
+		logger.Error("%s: user %s %s already exists", op, updated.Name, updated.Surname)
+		return fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+	}
+
+	updated.Version++
+	s.users[id] = updated
+	s.rev++
+	user.Version = updated.Version
+
+	logger.Debug("%s: updated user with ID %d to version %d", op, id, updated.Version)
+	return nil
+}
+
+func (s *Storage) Delete(ctx context.Context, id, version int) error {
+	const op = "storage.memory.del"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.users[id]
+	if !ok {
+		logger.Debug("%s: user with ID %d not found", op, id)
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if version > 0 && version != current.Version {
+		logger.Info("%s: user with ID %d is no longer at version %d", op, id, version)
+		return fmt.Errorf("%s: %w", op, storage.ErrVersionConflict)
+	}
+
+	delete(s.users, id)
+	s.rev++
+	for jobID, job := range s.jobs {
+		if job.PersonID == id {
+			delete(s.jobs, jobID)
+		}
+	}
+	s.changes = slices.DeleteFunc(s.changes, func(c model.EnrichmentChange) bool { return c.PersonID == id })
+
+	logger.Debug("%s: user with ID %d deleted", op, id)
+	return nil
+}
+
+func (s *Storage) Close() error {
+	const op = "storage.memory.close"
+
+	if s.inside {
+		return nil
+	}
+	logger.Info("%s: dropping in-memory data", op)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users = make(map[int]model.User)
+	s.jobs = make(map[int64]*model.EnrichmentJob)
+	s.changes = nil
+	s.rev++
+	return nil
+}
+
+// exists проверяет уникальность пары (name_key, surname_key), игнорируя запись с exceptID.
+func (s *Storage) exists(nameKey, surnameKey string, exceptID int) bool {
+	for id, user := range s.users {
+		if id != exceptID && user.NameKey == nameKey && user.SurnameKey == surnameKey {
+			return true
+		}
+	}
+	return false
+}
+
+func match(user, filter model.User) bool {
+	switch {
+	case filter.ID > 0 && user.ID != filter.ID:
+		return false
+	case filter.Name != "" && user.Name != filter.Name:
+		return false
+	case filter.Surname != "" && user.Surname != filter.Surname:
+		return false
+	case filter.NameKey != "" && user.NameKey != filter.NameKey:
+		return false
+	case filter.SurnameKey != "" && user.SurnameKey != filter.SurnameKey:
+		return false
+	case filter.Patronymic != nil && (user.Patronymic == nil || !strings.EqualFold(*user.Patronymic, *filter.Patronymic)):
+		return false
+	case filter.Age != nil && !equalPtr(user.Age, filter.Age):
+		return false
+	case filter.Gender != nil && !equalPtr(user.Gender, filter.Gender):
+		return false
+	case filter.Nationality != nil && !equalPtr(user.Nationality, filter.Nationality):
+		return false
+	case filter.EnrichmentStatus != "" && user.EnrichmentStatus != filter.EnrichmentStatus:
+		return false
+	}
+	return true
+}
+
+func merge(dst, src model.User) model.User {
+	if src.Name != "" {
+		dst.Name = src.Name
+	}
+	if src.Surname != "" {
+		dst.Surname = src.Surname
+	}
+	if src.NameKey != "" {
+		dst.NameKey = src.NameKey
+	}
+	if src.SurnameKey != "" {
+		dst.SurnameKey = src.SurnameKey
+	}
+	if src.Patronymic != nil {
+		dst.Patronymic = copyPtr(src.Patronymic)
+	}
+	if src.Age != nil {
+		dst.Age = copyPtr(src.Age)
+	}
+	if src.Gender != nil {
+		dst.Gender = copyPtr(src.Gender)
+	}
+	if src.Nationality != nil {
+		dst.Nationality = copyPtr(src.Nationality)
+	}
+	if src.CountryHint != nil {
+		dst.CountryHint = copyPtr(src.CountryHint)
+	}
+	if src.PendingEnrichment != nil {
+		dst.PendingEnrichment = append([]string{}, src.PendingEnrichment...)
+	}
+	if src.Locked != nil {
+		dst.Locked = append([]string{}, src.Locked...)
+	}
+	if src.EnrichmentStatus != "" {
+		dst.EnrichmentStatus = src.EnrichmentStatus
+	}
+	if src.EnrichedAt != nil {
+		dst.EnrichedAt = copyPtr(src.EnrichedAt)
+	}
+	for _, attr := range src.Unset {
+		switch {
+		case attr == "age" && src.Age == nil:
+			dst.Age = nil
+		case attr == "gender" && src.Gender == nil:
+			dst.Gender = nil
+		case attr == "nationality" && src.Nationality == nil:
+			dst.Nationality = nil
+		}
+	}
+	if src.Enrichment != nil {
+		merged := dst.Enrichment.Clone()
+		if merged == nil {
+			merged = &model.Enrichment{}
+		}
+		if src.Enrichment.Age != nil {
+			merged.Age = src.Enrichment.Age.Clone()
+		}
+		if src.Enrichment.Gender != nil {
+			merged.Gender = src.Enrichment.Gender.Clone()
+		}
+		if src.Enrichment.Nationality != nil {
+			merged.Nationality = src.Enrichment.Nationality.Clone()
+		}
+		dst.Enrichment = merged
+	}
+	return dst
+}
+
+func isEmpty(user model.User) bool {
+	return user.Name == "" && user.Surname == "" && user.NameKey == "" && user.SurnameKey == "" && user.Patronymic == nil &&
+		user.Age == nil && user.Gender == nil && user.Nationality == nil && user.CountryHint == nil &&
+		user.PendingEnrichment == nil && user.Locked == nil && user.EnrichmentStatus == "" && user.Enrichment == nil && user.EnrichedAt == nil &&
+		len(user.Unset) == 0
+}
+
+// clone возвращает копию пользователя для хранения: Unset в хранилище не попадает.
+func clone(user model.User) model.User {
+	user = user.Clone()
+	user.Unset = nil
+	return user
+}
+
+func copyPtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
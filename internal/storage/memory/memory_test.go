@@ -0,0 +1,267 @@
+package memory
+
+import (
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/storage"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func person(name, surname string) model.User {
+	return model.User{Name: name, Surname: surname, NameKey: name, SurnameKey: surname}
+}
+
+func TestAddRejectsDuplicateKeys(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	if _, err := s.Add(ctx, person("ivan", "petrov")); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := s.Add(ctx, person("ivan", "petrov")); !errors.Is(err, storage.ErrUserExists) {
+		t.Fatalf("second Add: got %v, want ErrUserExists", err)
+	}
+}
+
+func TestUpdateChecksVersion(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	id, err := s.Add(ctx, person("ivan", "petrov"))
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	age := 30
+	update := model.User{Age: &age, Version: 1}
+	if err = s.Update(ctx, id, &update); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if update.Version != 2 {
+		t.Fatalf("Update set version %d, want 2", update.Version)
+	}
+
+	stale := model.User{Age: &age, Version: 1}
+	if err = s.Update(ctx, id, &stale); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Fatalf("stale Update: got %v, want ErrVersionConflict", err)
+	}
+	if err = s.Delete(ctx, id, 1); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Fatalf("stale Delete: got %v, want ErrVersionConflict", err)
+	}
+	if err = s.Delete(ctx, id, 2); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err = s.Delete(ctx, id, 0); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Fatalf("second Delete: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestUpdateUnsetsLowConfidenceAttributes(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	age, gender := 30, "male"
+	user := person("ivan", "petrov")
+	user.Age, user.Gender = &age, &gender
+	id, err := s.Add(ctx, user)
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	if err = s.Update(ctx, id, &model.User{Unset: []string{"age"}}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	users, err := s.List(ctx, &storage.ListParam{User: model.User{ID: id}})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if users[0].Age != nil || users[0].Gender == nil {
+		t.Fatalf("got age %v, gender %v, want only age cleared", users[0].Age, users[0].Gender)
+	}
+}
+
+func TestWithTxRollsBackOnError(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+	boom := errors.New("boom")
+
+	err := s.WithTx(ctx, func(tx storage.Repo) error {
+		id, err := tx.Add(ctx, person("ivan", "petrov"))
+		if err != nil {
+			return err
+		}
+		if err = tx.Enqueue(ctx, id); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("WithTx: got %v, want boom", err)
+	}
+
+	users, err := s.List(ctx, &storage.ListParam{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(users) != 0 || len(s.jobs) != 0 {
+		t.Fatalf("rolled back transaction left %d users and %d jobs", len(users), len(s.jobs))
+	}
+}
+
+func TestWithTxCommitsJobs(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	var id int
+	err := s.WithTx(ctx, func(tx storage.Repo) error {
+		var err error
+		if id, err = tx.Add(ctx, person("ivan", "petrov")); err != nil {
+			return err
+		}
+		return tx.Enqueue(ctx, id)
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+
+	jobs, err := s.Jobs(ctx, id)
+	if err != nil {
+		t.Fatalf("Jobs: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != 1 || jobs[0].PersonID != id {
+		t.Fatalf("got jobs %+v, want one job for person %d", jobs, id)
+	}
+}
+
+func TestWithTxRetriesOnConcurrentChange(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	id, err := s.Add(ctx, person("ivan", "petrov"))
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	calls := 0
+	err = s.WithTx(ctx, func(tx storage.Repo) error {
+		calls++
+		if calls == 1 {
+			// параллельный запрос меняет человека после снимка транзакции
+			age := 40
+			if err := s.Update(ctx, id, &model.User{Age: &age}); err != nil {
+				return err
+			}
+		}
+		gender := "male"
+		return tx.Update(ctx, id, &model.User{Gender: &gender})
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("fn called %d times, want 2", calls)
+	}
+
+	users, err := s.List(ctx, &storage.ListParam{User: model.User{ID: id}})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if u := users[0]; u.Age == nil || *u.Age != 40 || u.Gender == nil || *u.Gender != "male" {
+		t.Fatalf("got %+v, want both concurrent changes", u)
+	}
+}
+
+func TestWithTxGivesUpAfterRetries(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	id, err := s.Add(ctx, person("ivan", "petrov"))
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	err = s.WithTx(ctx, func(tx storage.Repo) error {
+		age := 40
+		return s.Update(ctx, id, &model.User{Age: &age})
+	})
+	if !errors.Is(err, storage.ErrTxConflict) {
+		t.Fatalf("WithTx: got %v, want ErrTxConflict", err)
+	}
+}
+
+func TestTouchEnrichedAtKeepsVersion(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	id, err := s.Add(ctx, person("ivan", "petrov"))
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	attempted := time.Now().UTC()
+	enrichment := &model.Enrichment{Age: &model.Attribution{Provider: "agify", FetchedAt: attempted}}
+	if err = s.TouchEnrichedAt(ctx, id, 1, attempted, enrichment); err != nil {
+		t.Fatalf("TouchEnrichedAt: %v", err)
+	}
+
+	users, err := s.List(ctx, &storage.ListParam{User: model.User{ID: id}})
+	if err != nil || len(users) != 1 {
+		t.Fatalf("List: got %v, %v", users, err)
+	}
+	got := users[0]
+	if got.Version != 1 || got.EnrichedAt == nil || !got.EnrichedAt.Equal(attempted) || got.Enrichment.Age == nil {
+		t.Fatalf("got version %d, enriched_at %v, enrichment %+v", got.Version, got.EnrichedAt, got.Enrichment)
+	}
+
+	if err = s.TouchEnrichedAt(ctx, id, 2, attempted, nil); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Fatalf("TouchEnrichedAt with a stale version: got %v, want ErrVersionConflict", err)
+	}
+}
+
+func TestStaleWaitsForRetryMissing(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	attempted := time.Now()
+	user := person("ivan", "petrov")
+	user.EnrichedAt = &attempted
+	if _, err := s.Add(ctx, user); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	params := storage.StaleParam{
+		Limit:         10,
+		StaleBefore:   attempted.Add(-time.Hour),
+		MissingBefore: attempted.Add(-time.Minute),
+	}
+	users, err := s.Stale(ctx, params)
+	if err != nil {
+		t.Fatalf("Stale: %v", err)
+	}
+	if len(users) != 0 {
+		t.Fatalf("attributes attempted after MissingBefore were selected: %+v", users)
+	}
+
+	params.MissingBefore = attempted.Add(time.Minute)
+	if users, err = s.Stale(ctx, params); err != nil {
+		t.Fatalf("Stale: %v", err)
+	}
+	if len(users) != 1 {
+		t.Fatalf("got %d people, want the one attempted before MissingBefore", len(users))
+	}
+}
+
+func TestCanceledContext(t *testing.T) {
+	s := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := s.Add(ctx, person("ivan", "petrov")); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Add: got %v, want context.Canceled", err)
+	}
+	if _, err := s.List(ctx, &storage.ListParam{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("List: got %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,143 @@
+package memory
+
+import (
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/storage"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+var _ storage.RefreshStore = (*Storage)(nil)
+
+func (s *Storage) Stale(ctx context.Context, params storage.StaleParam) ([]*model.User, error) {
+	const op = "storage.memory.stale"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]*model.User, 0)
+	for _, user := range s.users {
+		if user.ID > params.AfterID && user.EnrichmentStatus != model.EnrichmentPending && stale(user, params) {
+			u := clone(user)
+			users = append(users, &u)
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	if params.Limit > 0 && len(users) > params.Limit {
+		users = users[:params.Limit]
+	}
+
+	logger.Debug("%s: found %d people to refresh", op, len(users))
+	return users, nil
+}
+
+// stale повторяет условие выборки pg.Storage.Stale.
+func stale(user model.User, params storage.StaleParam) bool {
+	var e model.Enrichment
+	if user.Enrichment != nil {
+		e = *user.Enrichment
+	}
+
+	attrs := []struct {
+		name        string
+		missing     bool
+		attribution *model.Attribution
+	}{
+		{"age", user.Age == nil, e.Age},
+		{"gender", user.Gender == nil, e.Gender},
+		{"nationality", user.Nationality == nil, e.Nationality},
+	}
+
+	for _, a := range attrs {
+		switch {
+		case slices.Contains(user.Locked, a.name):
+			continue
+		case a.attribution == nil:
+			// атрибуцию не получили: повторяем не раньше RetryMissing после попытки
+			if user.EnrichedAt == nil || user.EnrichedAt.Before(params.MissingBefore) {
+				return true
+			}
+		case (a.missing || a.attribution.LowConfidence) && a.attribution.FetchedAt.Before(params.MissingBefore):
+			return true
+		case a.attribution.FetchedAt.Before(params.StaleBefore):
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Storage) RecordChanges(ctx context.Context, changes []model.EnrichmentChange) error {
+	const op = "storage.memory.recordChanges"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range changes {
+		s.nextChangeID++
+		c.ID = s.nextChangeID
+		s.changes = append(s.changes, c)
+	}
+
+	logger.Debug("%s: recorded %d changes", op, len(changes))
+	return nil
+}
+
+func (s *Storage) Changes(ctx context.Context, personID int) ([]model.EnrichmentChange, error) {
+	const op = "storage.memory.changes"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	changes := make([]model.EnrichmentChange, 0)
+	for _, c := range s.changes {
+		if c.PersonID == personID {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+func (s *Storage) TouchEnrichedAt(ctx context.Context, id, version int, enrichedAt time.Time, enrichment *model.Enrichment) error {
+	const op = "storage.memory.touchEnrichedAt"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.users[id]
+	if !ok {
+		logger.Debug("%s: user with ID %d not found", op, id)
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if version != current.Version {
+		logger.Info("%s: user with ID %d is no longer at version %d", op, id, version)
+		return fmt.Errorf("%s: %w", op, storage.ErrVersionConflict)
+	}
+
+	// merge переносит только время попытки и происхождение, версию не трогаем
+	s.users[id] = merge(current, model.User{EnrichedAt: &enrichedAt, Enrichment: enrichment})
+	s.rev++
+
+	logger.Debug("%s: enrichment of user with ID %d touched at version %d", op, id, version)
+	return nil
+}
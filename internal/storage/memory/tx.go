@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/storage"
+	"context"
+	"fmt"
+	"slices"
+)
+
+var _ storage.Transactor = (*Storage)(nil)
+
+// txRetries — повторы транзакции, если людей изменили параллельно.
+const txRetries = 3
+
+// WithTx выполняет fn над копией людей и подменяет ими данные, только если
+// за это время никто другой их не менял (аналог serializable в pg.Storage).
+// Ошибка fn отбрасывает копию.
+func (s *Storage) WithTx(ctx context.Context, fn func(tx storage.Repo) error) error {
+	const op = "storage.memory.withTx"
+
+	if s.inside {
+		return fn(s)
+	}
+
+	for attempt := 0; ; attempt++ {
+		tx, rev := s.snapshot()
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if s.commit(tx, rev) {
+			return nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if attempt >= txRetries {
+			logger.Error("%s: giving up after %d attempts", op, attempt+1)
+			return fmt.Errorf("%s: %w", op, storage.ErrTxConflict)
+		}
+		logger.Info("%s: concurrent modification, retrying (attempt %d)", op, attempt+1)
+	}
+}
+
+func (s *Storage) snapshot() (*Storage, uint64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tx := &Storage{
+		users:  make(map[int]model.User, len(s.users)),
+		nextID: s.nextID,
+		jobs:   make(map[int64]*model.EnrichmentJob),
+		inside: true,
+	}
+	for id, user := range s.users {
+		tx.users[id] = clone(user)
+	}
+	return tx, s.rev
+}
+
+// commit переносит изменения tx. Задачи и журнал удалённых в транзакции людей
+// удаляются здесь же, как при каскаде в PostgreSQL, а поставленные в транзакции
+// задачи получают постоянные ID.
+func (s *Storage) commit(tx *Storage, rev uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.rev != rev {
+		return false
+	}
+
+	for id := range s.users {
+		if _, ok := tx.users[id]; ok {
+			continue
+		}
+		for jobID, job := range s.jobs {
+			if job.PersonID == id {
+				delete(s.jobs, jobID)
+			}
+		}
+		s.changes = slices.DeleteFunc(s.changes, func(c model.EnrichmentChange) bool { return c.PersonID == id })
+	}
+
+	jobIDs := make([]int64, 0, len(tx.jobs))
+	for id := range tx.jobs {
+		jobIDs = append(jobIDs, id)
+	}
+	slices.Sort(jobIDs)
+	for _, id := range jobIDs {
+		job := tx.jobs[id]
+		s.nextJobID++
+		job.ID = s.nextJobID
+		s.jobs[job.ID] = job
+	}
+
+	s.users = tx.users
+	s.nextID = tx.nextID
+	s.rev++
+	return true
+}
@@ -0,0 +1,48 @@
+package pg
+
+import (
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+func (s *Storage) GetCache(ctx context.Context, key string) ([]byte, time.Time, error) {
+	const op = "storage.pg.getCache"
+
+	var (
+		value     []byte
+		expiresAt time.Time
+	)
+
+	query := `SELECT value, expires_at FROM enrichment_cache WHERE key = $1 AND expires_at > now()`
+	err := s.q.QueryRowContext(ctx, query, key).Scan(&value, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Debug("%s: cache miss for %s", op, key)
+		return nil, time.Time{}, storage.ErrCacheMiss
+	}
+	if err != nil {
+		logger.Error("%s: select failed: %v", op, err)
+		return nil, time.Time{}, fmt.Errorf("%s: %w", op, withCtx(ctx, err))
+	}
+
+	logger.Debug("%s: cache hit for %s", op, key)
+	return value, expiresAt, nil
+}
+
+func (s *Storage) SetCache(ctx context.Context, key string, value []byte, expiresAt time.Time) error {
+	const op = "storage.pg.setCache"
+
+	query := `INSERT INTO enrichment_cache (key, value, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, expires_at = EXCLUDED.expires_at`
+	if _, err := s.q.ExecContext(ctx, query, key, string(value), expiresAt); err != nil {
+		logger.Error("%s: upsert failed: %v", op, err)
+		return fmt.Errorf("%s: %w", op, withCtx(ctx, err))
+	}
+
+	logger.Debug("%s: cached %s until %s", op, key, expiresAt)
+	return nil
+}
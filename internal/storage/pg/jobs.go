@@ -0,0 +1,137 @@
+package pg
+
+import (
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/storage"
+	"Effective_Mobile/lib/null"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+var _ storage.JobQueue = (*Storage)(nil)
+
+const jobColumns = "id, person_id, status, attempts, last_error, run_at, created_at, updated_at"
+
+func (s *Storage) Enqueue(ctx context.Context, personID int) error {
+	const op = "storage.pg.enqueue"
+
+	query := `INSERT INTO enrichment_jobs (person_id) VALUES ($1)`
+	if _, err := s.q.ExecContext(ctx, query, personID); err != nil {
+		logger.Error("%s: insert failed: %v", op, err)
+		return fmt.Errorf("%s: %w", op, withCtx(ctx, err))
+	}
+
+	logger.Debug("%s: enrichment job queued for person %d", op, personID)
+	return nil
+}
+
+func (s *Storage) Claim(ctx context.Context, limit int, lease time.Duration) ([]*model.EnrichmentJob, error) {
+	const op = "storage.pg.claim"
+
+	query := `UPDATE enrichment_jobs SET status = $1, attempts = attempts + 1, updated_at = now()
+		WHERE id IN (
+			SELECT id FROM enrichment_jobs
+			WHERE (status = $2 AND run_at <= now())
+			   OR (status = $1 AND updated_at < now() - make_interval(secs => $3))
+			ORDER BY run_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+
+	rows, err := s.q.QueryContext(ctx, query, model.JobRunning, model.JobQueued, lease.Seconds(), limit)
+	if err != nil {
+		logger.Error("%s: claim failed: %v", op, err)
+		return nil, fmt.Errorf("%s: %w", op, withCtx(ctx, err))
+	}
+
+	jobs, err := scanJobs(rows)
+	if err != nil {
+		logger.Error("%s: scan failed: %v", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(jobs) > 0 {
+		logger.Debug("%s: claimed %d jobs", op, len(jobs))
+	}
+	return jobs, nil
+}
+
+func (s *Storage) Complete(ctx context.Context, jobID int64) error {
+	const op = "storage.pg.complete"
+
+	return s.setJobStatus(ctx, op, jobID, model.JobDone, nil, nil)
+}
+
+func (s *Storage) Retry(ctx context.Context, jobID int64, runAt time.Time, reason string) error {
+	const op = "storage.pg.retry"
+
+	return s.setJobStatus(ctx, op, jobID, model.JobQueued, &runAt, &reason)
+}
+
+func (s *Storage) Fail(ctx context.Context, jobID int64, reason string) error {
+	const op = "storage.pg.fail"
+
+	return s.setJobStatus(ctx, op, jobID, model.JobDead, nil, &reason)
+}
+
+func (s *Storage) Jobs(ctx context.Context, personID int) ([]*model.EnrichmentJob, error) {
+	const op = "storage.pg.jobs"
+
+	query := `SELECT ` + jobColumns + ` FROM enrichment_jobs WHERE person_id = $1 ORDER BY id`
+	rows, err := s.q.QueryContext(ctx, query, personID)
+	if err != nil {
+		logger.Error("%s: select failed: %v", op, err)
+		return nil, fmt.Errorf("%s: %w", op, withCtx(ctx, err))
+	}
+
+	jobs, err := scanJobs(rows)
+	if err != nil {
+		logger.Error("%s: scan failed: %v", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return jobs, nil
+}
+
+func (s *Storage) setJobStatus(ctx context.Context, op string, jobID int64, status string,
+	runAt *time.Time, reason *string) error {
+
+	query := `UPDATE enrichment_jobs
+		SET status = $1, run_at = COALESCE($2, run_at), last_error = COALESCE($3, last_error), updated_at = now()
+		WHERE id = $4`
+	if _, err := s.q.ExecContext(ctx, query, status, runAt, reason, jobID); err != nil {
+		logger.Error("%s: update failed: %v", op, err)
+		return fmt.Errorf("%s: %w", op, withCtx(ctx, err))
+	}
+
+	logger.Debug("%s: job %d is %s", op, jobID, status)
+	return nil
+}
+
+func scanJobs(rows *sql.Rows) ([]*model.EnrichmentJob, error) {
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.Error("storage.pg.scanJobs: rows close failed: %v", err)
+		}
+	}()
+
+	jobs := make([]*model.EnrichmentJob, 0)
+	for rows.Next() {
+		var (
+			job       model.EnrichmentJob
+			lastError sql.NullString
+		)
+		if err := rows.Scan(&job.ID, &job.PersonID, &job.Status, &job.Attempts, &lastError,
+			&job.RunAt, &job.CreatedAt, &job.UpdatedAt); err != nil {
+			return nil, err
+		}
+		job.LastError = null.SqlNullStringValid(lastError)
+		jobs = append(jobs, &job)
+	}
+
+	return jobs, rows.Err()
+}
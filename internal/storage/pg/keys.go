@@ -0,0 +1,61 @@
+package pg
+
+import (
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/storage"
+	"context"
+	"fmt"
+
+	pq "github.com/lib/pq"
+)
+
+var _ storage.KeyStore = (*Storage)(nil)
+
+func (s *Storage) NameKeys(ctx context.Context) ([]*model.User, error) {
+	const op = "storage.pg.nameKeys"
+
+	rows, err := s.q.QueryContext(ctx,
+		"SELECT id, name, surname, COALESCE(name_key, ''), COALESCE(surname_key, '') FROM people ORDER BY id")
+	if err != nil {
+		logger.Error("%s: select failed: %v", op, err)
+		return nil, fmt.Errorf("%s: %w", op, withCtx(ctx, err))
+	}
+	defer rows.Close()
+
+	users := make([]*model.User, 0)
+	for rows.Next() {
+		var u model.User
+		if err = rows.Scan(&u.ID, &u.Name, &u.Surname, &u.NameKey, &u.SurnameKey); err != nil {
+			logger.Error("%s: scan failed: %v", op, err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		users = append(users, &u)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, withCtx(ctx, err))
+	}
+
+	logger.Debug("%s: read keys of %d people", op, len(users))
+	return users, nil
+}
+
+func (s *Storage) SetNameKeys(ctx context.Context, id int, nameKey, surnameKey string) error {
+	const op = "storage.pg.setNameKeys"
+
+	res, err := s.q.ExecContext(ctx, "UPDATE people SET name_key = $2, surname_key = $3 WHERE id = $1",
+		id, nameKey, surnameKey)
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+			logger.Error("%s: keys of user %d are taken: %v", op, id, err)
+			return fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+		}
+		logger.Error("%s: update failed: %v", op, err)
+		return fmt.Errorf("%s: %w", op, withCtx(ctx, err))
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	return nil
+}
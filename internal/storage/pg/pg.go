@@ -6,15 +6,29 @@ import (
 	"Effective_Mobile/internal/model"
 	"Effective_Mobile/internal/storage"
 	"Effective_Mobile/lib/null"
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
 	pq "github.com/lib/pq"
+	"slices"
 	"strconv"
 	"strings"
 )
 
+var _ storage.Repository = (*Storage)(nil)
+
+const peopleColumns = "id, name, surname, name_key, surname_key, patronymic, gender, age, nationality, pending_enrichment, enrichment_status, enrichment, country_hint, locked_attributes, enriched_at, version"
+
 type Storage struct {
 	db *sql.DB
+	// q — соединение или открытая транзакция, через которую идут запросы.
+	q         querier
+	isolation sql.IsolationLevel
+	retries   int
+	inside    bool
 }
 
 func New(cfDSN *config.DsnPG) (*Storage, error) {
@@ -23,6 +37,11 @@ func New(cfDSN *config.DsnPG) (*Storage, error) {
 	dsn := DSN(cfDSN)
 	logger.Info("%s: connecting to PostgreSQL", op)
 
+	isolation, ok := isolationLevels[cfDSN.TxIsolation]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown transaction isolation %q", op, cfDSN.TxIsolation)
+	}
+
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		logger.Error("%s: failed to open DB: %v", op, err)
@@ -30,7 +49,7 @@ func New(cfDSN *config.DsnPG) (*Storage, error) {
 	}
 
 	logger.Info("%s: successfully connected to PostgreSQL", op)
-	return &Storage{db: db}, nil
+	return &Storage{db: db, q: db, isolation: isolation, retries: cfDSN.TxRetries}, nil
 }
 
 func DSN(dsn *config.DsnPG) string {
@@ -44,17 +63,8 @@ func DSN(dsn *config.DsnPG) string {
 	)
 }
 
-func (s *Storage) Add(user model.User) (int, error) {
+func (s *Storage) Add(ctx context.Context, user model.User) (int, error) {
 	const op = "storage.pg.add"
-	//tx, err := s.db.BeginTx(ctx, nil)
-	//if err != nil {
-	//	return -1, fmt.Errorf("%s: %w", op, err)
-	//}
-	//defer func() {
-	//	if err != nil {
-	//		tx.Rollback()
-	//	}
-	//}()
 
 	args, columns, placeHolders := prepareQuery(user)
 
@@ -62,9 +72,8 @@ func (s *Storage) Add(user model.User) (int, error) {
 		strings.Join(columns, ", "),
 		strings.Join(placeHolders, ", "),
 	)
-	//res, err := tx.ExecContext(ctx, query, args)
 	var id int
-	err := s.db.QueryRow(query, args...).Scan(&id)
+	err := s.q.QueryRowContext(ctx, query, args...).Scan(&id)
 
 	if err != nil {
 		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
@@ -72,47 +81,50 @@ func (s *Storage) Add(user model.User) (int, error) {
 			return -1, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
 		}
 		logger.Error("%s: insert failed: %v", op, err)
-		return -1, fmt.Errorf("%s: %w", op, err)
+		return -1, fmt.Errorf("%s: %w", op, withCtx(ctx, err))
 	}
 
-	//if err = tx.Commit(); err != nil {
-	//	return -1, fmt.Errorf("%s: %w", op, err)
-	//}
-
 	logger.Debug("%s: user added with ID %d", op, id)
 	return id, nil
 }
 
-func (s *Storage) List(params *ListParam) ([]*model.User, error) {
+func (s *Storage) List(ctx context.Context, params *storage.ListParam) ([]*model.User, error) {
 	const op = "storage.pg.list"
 
 	args, columns, placeHolders := prepareQuery(params.User)
 
 	sb := strings.Builder{}
-	sb.WriteString("SELECT * FROM people")
+	sb.WriteString("SELECT " + peopleColumns + " FROM people")
 
 	if len(columns) > 0 {
 		sb.WriteString(" WHERE ")
 		for i, column := range columns {
-			sb.WriteString(column)
-			sb.WriteString(" = ")
-			sb.WriteString(placeHolders[i])
+			// отчество хранится как введено, поэтому фильтр по нему не зависит от регистра
+			if column == "patronymic" {
+				sb.WriteString("lower(patronymic) = lower(" + placeHolders[i] + ")")
+			} else {
+				sb.WriteString(column)
+				sb.WriteString(" = ")
+				sb.WriteString(placeHolders[i])
+			}
 			if i < len(columns)-1 {
 				sb.WriteString(" AND ")
 			}
 		}
 	}
 
+	sb.WriteString(" ORDER BY id")
+
 	if params.Limit > 0 {
 		sb.WriteString("\n")
 		sb.WriteString(fmt.Sprintf("LIMIT $%d OFFSET $%d", len(columns)+1, len(columns)+2))
 		args = append(args, params.Limit, params.Offset)
 	}
-s.
-	rows, err := s.db.Query(sb.String(), args...)
+
+	rows, err := s.q.QueryContext(ctx, sb.String(), args...)
 	if err != nil {
 		logger.Error("%s: list query failed: %v", op, err)
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, withCtx(ctx, err))
 	}
 
 	defer func() {
@@ -126,47 +138,34 @@ s.
 	users := make([]*model.User, 0)
 
 	for rows.Next() {
-		var (
-			patronymic  sql.NullString
-			age         sql.NullInt64
-			gender      sql.NullString
-			nationality sql.NullString
-		)
-
-		user := &model.User{}
-		if err = rows.Scan(&user.ID, &user.Name, &user.Surname,
-			&patronymic, &gender, &age, &nationality); err != nil {
+		user, err := scanUser(rows)
+		if err != nil {
 			logger.Error("%s: scan failed: %v", op, err)
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
 
-		user.Patronymic = null.SqlNullStringValid(patronymic)
-		user.Gender = null.SqlNullStringValid(gender)
-		user.Nationality = null.SqlNullStringValid(nationality)
-		user.Age = null.SqlNullInt64Valid(age)
-
 		users = append(users, user)
 	}
 
 	if err = rows.Err(); err != nil {
 		logger.Error("%s: rows error: %v", op, err)
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, withCtx(ctx, err))
 	}
 
 	logger.Debug("%s: found %d users", op, len(users))
 	return users, err
 }
 
-func (s *Storage) Delete(id int) error {
+func (s *Storage) Delete(ctx context.Context, id, version int) error {
 	const op = "storage.pg.del"
 
 	logger.Debug("%s: deleting user with ID %d", op, id)
 
-	query := `DELETE FROM people WHERE id = $1`
-	res, err := s.db.Exec(query, id)
+	query := `DELETE FROM people WHERE id = $1 AND ($2 = 0 OR version = $2)`
+	res, err := s.q.ExecContext(ctx, query, id, version)
 	if err != nil {
 		logger.Error("%s: delete failed: %v", op, err)
-		return fmt.Errorf("%s: %w", op, err)
+		return fmt.Errorf("%s: %w", op, withCtx(ctx, err))
 	}
 	affected, err := res.RowsAffected()
 	if err != nil {
@@ -174,18 +173,18 @@ func (s *Storage) Delete(id int) error {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 	if affected == 0 {
-		logger.Debug("%s: user with ID %d not found", op, id)
-		return storage.ErrUserNotFound
+		return s.missing(ctx, op, id, version)
 	}
 
 	logger.Debug("%s: user with ID %d deleted", op, id)
 	return nil
 }
 
-func (s *Storage) Update(id int, user *model.User) error {
+func (s *Storage) Update(ctx context.Context, id int, user *model.User) error {
 	const op = "storage.pg.update"
 
 	args, columns, placeHolders := prepareQuery(*user)
+	args, columns, placeHolders = prepareUnset(args, columns, placeHolders, user.Unset)
 	if len(columns) == 0 {
 		logger.Error("%s: nothing to update", op)
 		return fmt.Errorf("%s: %w", op, storage.ErrNothingUpdate)
@@ -197,38 +196,71 @@ func (s *Storage) Update(id int, user *model.User) error {
 	for i, column := range columns {
 		sb.WriteString(column)
 		sb.WriteString(" = ")
+		if column == "enrichment" {
+			// атрибуты, которые не обогащались, сохраняют прежнее происхождение
+			sb.WriteString("COALESCE(enrichment, '{}'::jsonb) || ")
+		}
 		sb.WriteString(placeHolders[i])
 		if i != len(columns)-1 {
 			sb.WriteString(", ")
 		}
 	}
 
-	sb.WriteString(" WHERE id = $")
+	sb.WriteString(", version = version + 1 WHERE id = $")
 	sb.WriteString(strconv.Itoa(len(columns) + 1))
 	args = append(args, id)
+	if user.Version > 0 {
+		sb.WriteString(" AND version = $")
+		sb.WriteString(strconv.Itoa(len(columns) + 2))
+		args = append(args, user.Version)
+	}
+	sb.WriteString(" RETURNING version")
 
-	res, err := s.db.Exec(sb.String(), args...)
+	var version int
+	err := s.q.QueryRowContext(ctx, sb.String(), args...).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s.missing(ctx, op, id, user.Version)
+	}
 	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+			logger.Error("%s: user already exists: %v", op, err)
+			return fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+		}
 		logger.Error("%s: update failed: %v", op, err)
-		return fmt.Errorf("%s: %w", op, err)
+		return fmt.Errorf("%s: %w", op, withCtx(ctx, err))
 	}
+	user.Version = version
 
-	affected, err := res.RowsAffected()
-	if err != nil {
-		logger.Error("%s: failed to get affected rows: %v", op, err)
-		return fmt.Errorf("%s: %w", op, err)
-	}
-	if affected == 0 {
-		logger.Debug("%s: user with ID %d not found for update", op, id)
-		return storage.ErrUserNotFound
+	logger.Debug("%s: updated user with ID %d to version %d", op, id, version)
+	return nil
+}
+
+// missing объясняет, почему запрос к строке id ничего не изменил: строки нет
+// или, если ожидалась версия version, она уже другая.
+func (s *Storage) missing(ctx context.Context, op string, id, version int) error {
+	if version > 0 {
+		var exists bool
+		err := s.q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM people WHERE id = $1)`, id).Scan(&exists)
+		if err != nil {
+			logger.Error("%s: existence check failed: %v", op, err)
+			return fmt.Errorf("%s: %w", op, withCtx(ctx, err))
+		}
+		if exists {
+			logger.Info("%s: user with ID %d is no longer at version %d", op, id, version)
+			return fmt.Errorf("%s: %w", op, storage.ErrVersionConflict)
+		}
 	}
 
-	logger.Debug("%s: updated user with ID %d", op, id)
-	return nil
+	logger.Debug("%s: user with ID %d not found", op, id)
+	return storage.ErrUserNotFound
 }
 
 func (s *Storage) Close() error {
 	const op = "storage.pg.close"
+
+	if s.inside {
+		return nil
+	}
 	logger.Info("%s: closing DB connection", op)
 
 	if err := s.db.Close(); err != nil {
@@ -239,10 +271,64 @@ func (s *Storage) Close() error {
 	return nil
 }
 
-type ListParam struct {
-	User   model.User
-	Limit  int
-	Offset int
+func scanUser(rows *sql.Rows) (*model.User, error) {
+	var (
+		patronymic  sql.NullString
+		age         sql.NullInt64
+		gender      sql.NullString
+		nationality sql.NullString
+		enrichment  []byte
+		countryHint sql.NullString
+		enrichedAt  sql.NullTime
+	)
+
+	user := &model.User{}
+	if err := rows.Scan(&user.ID, &user.Name, &user.Surname, &user.NameKey, &user.SurnameKey,
+		&patronymic, &gender, &age, &nationality, pq.Array(&user.PendingEnrichment),
+		&user.EnrichmentStatus, &enrichment, &countryHint, pq.Array(&user.Locked), &enrichedAt, &user.Version); err != nil {
+		return nil, err
+	}
+
+	if enrichment != nil {
+		user.Enrichment = &model.Enrichment{}
+		if err := json.Unmarshal(enrichment, user.Enrichment); err != nil {
+			return nil, err
+		}
+	}
+
+	user.Patronymic = null.SqlNullStringValid(patronymic)
+	user.Gender = null.SqlNullStringValid(gender)
+	user.Nationality = null.SqlNullStringValid(nationality)
+	user.CountryHint = null.SqlNullStringValid(countryHint)
+	user.Age = null.SqlNullInt64Valid(age)
+	if enrichedAt.Valid {
+		user.EnrichedAt = &enrichedAt.Time
+	}
+
+	return user, nil
+}
+
+// jsonb сериализует значение в JSON при передаче в запрос. Значение
+// передаётся строкой: []byte lib/pq отправил бы как bytea.
+type jsonb struct {
+	v any
+}
+
+func (j jsonb) Value() (driver.Value, error) {
+	b, err := json.Marshal(j.v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// withCtx добавляет к ошибке драйвера причину отмены контекста: lib/pq
+// при отмене возвращает собственную ошибку "canceling statement".
+func withCtx(ctx context.Context, err error) error {
+	if cerr := ctx.Err(); cerr != nil && !errors.Is(err, cerr) {
+		return fmt.Errorf("%w: %w", cerr, err)
+	}
+	return err
 }
 
 func prepareElemForQuery(args []interface{}, columns []string, placeHolders []string,
@@ -284,6 +370,18 @@ func prepareQuery(user model.User) ([]interface{}, []string, []string) {
 		args, columns, placeHolders = prepareElemForQuery(args, columns, placeHolders, &index, arg, column)
 	}
 
+	if user.NameKey != "" {
+		column := "name_key"
+		arg := user.NameKey
+		args, columns, placeHolders = prepareElemForQuery(args, columns, placeHolders, &index, arg, column)
+	}
+
+	if user.SurnameKey != "" {
+		column := "surname_key"
+		arg := user.SurnameKey
+		args, columns, placeHolders = prepareElemForQuery(args, columns, placeHolders, &index, arg, column)
+	}
+
 	if user.Patronymic != nil {
 		column := "patronymic"
 		arg := user.Patronymic
@@ -310,5 +408,60 @@ func prepareQuery(user model.User) ([]interface{}, []string, []string) {
 		args, columns, placeHolders = prepareElemForQuery(args, columns, placeHolders, &index, arg, column)
 	}
 
+	if user.CountryHint != nil {
+		column := "country_hint"
+		arg := user.CountryHint
+		args, columns, placeHolders = prepareElemForQuery(args, columns, placeHolders, &index, arg, column)
+	}
+
+	if user.PendingEnrichment != nil {
+		column := "pending_enrichment"
+		arg := pq.Array(user.PendingEnrichment)
+		args, columns, placeHolders = prepareElemForQuery(args, columns, placeHolders, &index, arg, column)
+	}
+
+	if user.Locked != nil {
+		column := "locked_attributes"
+		arg := pq.Array(user.Locked)
+		args, columns, placeHolders = prepareElemForQuery(args, columns, placeHolders, &index, arg, column)
+	}
+
+	if user.EnrichmentStatus != "" {
+		column := "enrichment_status"
+		arg := user.EnrichmentStatus
+		args, columns, placeHolders = prepareElemForQuery(args, columns, placeHolders, &index, arg, column)
+	}
+
+	if user.EnrichedAt != nil {
+		column := "enriched_at"
+		arg := *user.EnrichedAt
+		args, columns, placeHolders = prepareElemForQuery(args, columns, placeHolders, &index, arg, column)
+	}
+
+	if user.Enrichment != nil {
+		column := "enrichment"
+		arg := jsonb{user.Enrichment}
+		args, columns, placeHolders = prepareElemForQuery(args, columns, placeHolders, &index, arg, column)
+	}
+
+	return args, columns, placeHolders
+}
+
+// prepareUnset добавляет сброс в NULL обогащаемых атрибутов, для которых не
+// передано новое значение.
+func prepareUnset(args []interface{}, columns []string, placeHolders []string,
+	unset []string) ([]interface{}, []string, []string) {
+
+	index := len(columns) + 1
+	for _, column := range unset {
+		if column != "age" && column != "gender" && column != "nationality" {
+			continue
+		}
+		if slices.Contains(columns, column) {
+			continue
+		}
+		args, columns, placeHolders = prepareElemForQuery(args, columns, placeHolders, &index, nil, column)
+	}
+
 	return args, columns, placeHolders
 }
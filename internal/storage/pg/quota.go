@@ -0,0 +1,39 @@
+package pg
+
+import (
+	"Effective_Mobile/internal/logger"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ConsumeQuota атомарно увеличивает счётчик, только если он не превысит limit,
+// поэтому квота соблюдается и при нескольких экземплярах сервиса.
+func (s *Storage) ConsumeQuota(ctx context.Context, provider string, day time.Time, n, limit int) (bool, error) {
+	const op = "storage.pg.consumeQuota"
+
+	if n > limit {
+		return false, nil
+	}
+
+	query := `INSERT INTO provider_quota (provider, day, used) VALUES ($1, $2, $3)
+		ON CONFLICT (provider, day) DO UPDATE SET used = provider_quota.used + EXCLUDED.used
+		WHERE provider_quota.used + EXCLUDED.used <= $4
+		RETURNING used`
+
+	var used int
+	err := s.q.QueryRowContext(ctx, query, provider, day, n, limit).Scan(&used)
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Debug("%s: %s quota exhausted for %s", op, provider, day.Format(time.DateOnly))
+		return false, nil
+	}
+	if err != nil {
+		logger.Error("%s: upsert failed: %v", op, err)
+		return false, fmt.Errorf("%s: %w", op, withCtx(ctx, err))
+	}
+
+	logger.Debug("%s: %s used %d of %d names for %s", op, provider, used, limit, day.Format(time.DateOnly))
+	return true, nil
+}
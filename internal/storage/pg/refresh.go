@@ -0,0 +1,162 @@
+package pg
+
+import (
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/model"
+	"Effective_Mobile/internal/storage"
+	"Effective_Mobile/lib/null"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+var _ storage.RefreshStore = (*Storage)(nil)
+
+func (s *Storage) Stale(ctx context.Context, params storage.StaleParam) ([]*model.User, error) {
+	const op = "storage.pg.stale"
+
+	query := `SELECT ` + peopleColumns + ` FROM people p
+		WHERE id > $1 AND enrichment_status <> $2 AND EXISTS (
+			SELECT 1 FROM (VALUES
+				('age', p.age IS NULL),
+				('gender', p.gender IS NULL),
+				('nationality', p.nationality IS NULL)
+			) AS a(attr, missing)
+			WHERE NOT a.attr = ANY(p.locked_attributes) AND (
+				(p.enrichment->a.attr IS NULL AND (p.enriched_at IS NULL OR p.enriched_at < $3))
+				OR ((a.missing OR (p.enrichment->a.attr->>'low_confidence')::boolean IS TRUE)
+					AND (p.enrichment->a.attr->>'fetched_at')::timestamptz < $3)
+				OR (p.enrichment->a.attr->>'fetched_at')::timestamptz < $4
+			)
+		)
+		ORDER BY id
+		LIMIT $5`
+
+	rows, err := s.q.QueryContext(ctx, query, params.AfterID, model.EnrichmentPending,
+		params.MissingBefore, params.StaleBefore, params.Limit)
+	if err != nil {
+		logger.Error("%s: select failed: %v", op, err)
+		return nil, fmt.Errorf("%s: %w", op, withCtx(ctx, err))
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.Error("%s: rows close failed: %v", op, err)
+		}
+	}()
+
+	users := make([]*model.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			logger.Error("%s: scan failed: %v", op, err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Error("%s: rows error: %v", op, err)
+		return nil, fmt.Errorf("%s: %w", op, withCtx(ctx, err))
+	}
+
+	logger.Debug("%s: found %d people to refresh", op, len(users))
+	return users, nil
+}
+
+func (s *Storage) RecordChanges(ctx context.Context, changes []model.EnrichmentChange) error {
+	const op = "storage.pg.recordChanges"
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO enrichment_changes (person_id, attribute, old_value, new_value, provider, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	err := s.inTx(ctx, func(tx *Storage) error {
+		for _, c := range changes {
+			if _, err := tx.q.ExecContext(ctx, query, c.PersonID, c.Attribute, c.OldValue, c.NewValue, c.Provider, c.ChangedAt); err != nil {
+				logger.Error("%s: insert failed: %v", op, err)
+				return fmt.Errorf("%s: %w", op, withCtx(ctx, err))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("%s: recorded %d changes", op, len(changes))
+	return nil
+}
+
+func (s *Storage) Changes(ctx context.Context, personID int) ([]model.EnrichmentChange, error) {
+	const op = "storage.pg.changes"
+
+	query := `SELECT id, person_id, attribute, old_value, new_value, provider, changed_at
+		FROM enrichment_changes WHERE person_id = $1 ORDER BY id`
+	rows, err := s.q.QueryContext(ctx, query, personID)
+	if err != nil {
+		logger.Error("%s: select failed: %v", op, err)
+		return nil, fmt.Errorf("%s: %w", op, withCtx(ctx, err))
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.Error("%s: rows close failed: %v", op, err)
+		}
+	}()
+
+	changes := make([]model.EnrichmentChange, 0)
+	for rows.Next() {
+		var (
+			c                  model.EnrichmentChange
+			oldValue, newValue sql.NullString
+			provider           sql.NullString
+		)
+		if err := rows.Scan(&c.ID, &c.PersonID, &c.Attribute, &oldValue, &newValue, &provider, &c.ChangedAt); err != nil {
+			logger.Error("%s: scan failed: %v", op, err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		c.OldValue = null.SqlNullStringValid(oldValue)
+		c.NewValue = null.SqlNullStringValid(newValue)
+		c.Provider = provider.String
+		changes = append(changes, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Error("%s: rows error: %v", op, err)
+		return nil, fmt.Errorf("%s: %w", op, withCtx(ctx, err))
+	}
+
+	return changes, nil
+}
+
+func (s *Storage) TouchEnrichedAt(ctx context.Context, id, version int, enrichedAt time.Time, enrichment *model.Enrichment) error {
+	const op = "storage.pg.touchEnrichedAt"
+
+	if enrichment == nil {
+		enrichment = &model.Enrichment{}
+	}
+
+	query := `UPDATE people SET enriched_at = $2, enrichment = COALESCE(enrichment, '{}'::jsonb) || $3
+		WHERE id = $1 AND version = $4`
+	res, err := s.q.ExecContext(ctx, query, id, enrichedAt, jsonb{enrichment}, version)
+	if err != nil {
+		logger.Error("%s: update failed: %v", op, err)
+		return fmt.Errorf("%s: %w", op, withCtx(ctx, err))
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		logger.Error("%s: rows affected failed: %v", op, err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return s.missing(ctx, op, id, version)
+	}
+
+	logger.Debug("%s: enrichment of user with ID %d touched at version %d", op, id, version)
+	return nil
+}
@@ -0,0 +1,83 @@
+package pg
+
+import (
+	"Effective_Mobile/internal/logger"
+	"Effective_Mobile/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	pq "github.com/lib/pq"
+)
+
+var _ storage.Transactor = (*Storage)(nil)
+
+// querier — общее подмножество *sql.DB и *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+var isolationLevels = map[string]sql.IsolationLevel{
+	"read_committed":  sql.LevelReadCommitted,
+	"repeatable_read": sql.LevelRepeatableRead,
+	"serializable":    sql.LevelSerializable,
+}
+
+func (s *Storage) WithTx(ctx context.Context, fn func(tx storage.Repo) error) error {
+	return s.inTx(ctx, func(tx *Storage) error { return fn(tx) })
+}
+
+// inTx выполняет fn в транзакции и откатывает её, если fn вернула ошибку.
+// При конфликте сериализации или взаимной блокировке транзакция повторяется
+// до retries раз. Вложенный вызов выполняется в уже открытой транзакции.
+func (s *Storage) inTx(ctx context.Context, fn func(tx *Storage) error) error {
+	const op = "storage.pg.withTx"
+
+	if s.inside {
+		return fn(s)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := s.runTx(ctx, fn)
+		if err == nil || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		if attempt >= s.retries {
+			logger.Error("%s: giving up after %d attempts: %v", op, attempt+1, err)
+			return fmt.Errorf("%s: %w: %w", op, storage.ErrTxConflict, err)
+		}
+		logger.Info("%s: serialization conflict, retrying (attempt %d): %v", op, attempt+1, err)
+	}
+}
+
+func (s *Storage) runTx(ctx context.Context, fn func(tx *Storage) error) error {
+	const op = "storage.pg.runTx"
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: s.isolation})
+	if err != nil {
+		logger.Error("%s: begin failed: %v", op, err)
+		return fmt.Errorf("%s: %w", op, withCtx(ctx, err))
+	}
+
+	if err = fn(&Storage{db: s.db, q: tx, isolation: s.isolation, retries: s.retries, inside: true}); err != nil {
+		if rerr := tx.Rollback(); rerr != nil && !errors.Is(rerr, sql.ErrTxDone) {
+			logger.Error("%s: rollback failed: %v", op, rerr)
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.Error("%s: commit failed: %v", op, err)
+		return fmt.Errorf("%s: %w", op, withCtx(ctx, err))
+	}
+	return nil
+}
+
+// retryable — ошибки, после которых транзакцию можно повторить целиком:
+// serialization_failure и deadlock_detected.
+func retryable(err error) bool {
+	var pgErr *pq.Error
+	return errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01")
+}
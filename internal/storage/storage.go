@@ -1,9 +1,96 @@
 package storage
 
-import "errors"
+import (
+	"Effective_Mobile/internal/model"
+	"context"
+	"errors"
+	"time"
+)
 
 var (
 	ErrUserNotFound  = errors.New("user not found")
 	ErrUserExists    = errors.New("user exists")
 	ErrNothingUpdate = errors.New("nothing to update")
+	ErrCacheMiss     = errors.New("cache miss")
+	ErrTxConflict    = errors.New("transaction conflict")
+	// ErrVersionConflict — запись изменилась после того, как клиент её прочитал.
+	ErrVersionConflict = errors.New("version conflict")
 )
+
+// Repo — операции с людьми, доступные в том числе внутри транзакции.
+type Repo interface {
+	Add(ctx context.Context, user model.User) (int, error)
+	List(ctx context.Context, params *ListParam) ([]*model.User, error)
+	// Update после успешной записи сохраняет новую версию в user.Version.
+	Update(ctx context.Context, id int, user *model.User) error
+	// Delete удаляет человека; ненулевая version должна совпасть с текущей.
+	Delete(ctx context.Context, id, version int) error
+	// Enqueue ставит фоновое обогащение человека в очередь. Внутри транзакции
+	// задача появляется только вместе с остальными её изменениями.
+	Enqueue(ctx context.Context, personID int) error
+}
+
+// Repository описывает хранилище людей независимо от конкретного бэкенда.
+type Repository interface {
+	Repo
+	Close() error
+}
+
+// Transactor выполняет fn в транзакции: если fn вернула ошибку, изменения
+// откатываются, а при конфликте сериализации fn вызывается заново, поэтому
+// она должна быть идемпотентной. Если повторы исчерпаны — ErrTxConflict.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(tx Repo) error) error
+}
+
+// KeyStore — пересчёт ключей имён после смены правил нормализации (команда
+// rekey). Ключи не видны клиентам, поэтому версия записи не меняется.
+type KeyStore interface {
+	// NameKeys возвращает id, имя, фамилию и текущие ключи всех людей по
+	// возрастанию id; незаполненный ключ — пустая строка.
+	NameKeys(ctx context.Context) ([]*model.User, error)
+	SetNameKeys(ctx context.Context, id int, nameKey, surnameKey string) error
+}
+
+// JobQueue — очередь задач фонового обогащения.
+type JobQueue interface {
+	Enqueue(ctx context.Context, personID int) error
+	// Claim захватывает до limit готовых к выполнению задач. Задачи в статусе
+	// running, не завершённые за lease, считаются потерянными и захватываются снова.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*model.EnrichmentJob, error)
+	Complete(ctx context.Context, jobID int64) error
+	Retry(ctx context.Context, jobID int64, runAt time.Time, reason string) error
+	Fail(ctx context.Context, jobID int64, reason string) error
+	Jobs(ctx context.Context, personID int) ([]*model.EnrichmentJob, error)
+}
+
+// RefreshStore — выборка людей для повторного обогащения и журнал изменений.
+type RefreshStore interface {
+	// Stale возвращает людей с id больше AfterID, у которых есть незаблокированный
+	// атрибут без значения, с низкой достоверностью или устаревший.
+	Stale(ctx context.Context, params StaleParam) ([]*model.User, error)
+	RecordChanges(ctx context.Context, changes []model.EnrichmentChange) error
+	Changes(ctx context.Context, personID int) ([]model.EnrichmentChange, error)
+	// TouchEnrichedAt сохраняет время попытки и происхождение атрибутов, если
+	// повторное обогащение не изменило значений. Версия записи не меняется, и
+	// ETag остаётся прежним; version должна совпасть с текущей.
+	TouchEnrichedAt(ctx context.Context, id, version int, enrichedAt time.Time, enrichment *model.Enrichment) error
+}
+
+// StaleParam задаёт критерии выборки для повторного обогащения.
+type StaleParam struct {
+	AfterID int
+	Limit   int
+	// StaleBefore — атрибуты, полученные раньше, считаются устаревшими.
+	StaleBefore time.Time
+	// MissingBefore — пустые атрибуты и атрибуты с низкой достоверностью
+	// запрашиваются повторно не чаще, чем с этого момента. Для атрибутов без
+	// атрибуции отсчёт идёт от последней попытки обогащения (User.EnrichedAt).
+	MissingBefore time.Time
+}
+
+type ListParam struct {
+	User   model.User
+	Limit  int
+	Offset int
+}
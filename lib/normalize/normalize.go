@@ -0,0 +1,76 @@
+package normalize
+
+import (
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Transliterate включает перевод кириллицы в латиницу в ключах поиска и в
+// именах, отправляемых провайдерам обогащения. Задаётся при старте из конфигурации.
+var Transliterate = true
+
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// Clean убирает пробелы по краям, схлопывает внутренние и приводит строку к NFC.
+func Clean(s string) string {
+	return norm.NFC.String(strings.Join(strings.Fields(s), " "))
+}
+
+// Display возвращает имя для показа: Clean и заглавная буква в начале каждой
+// части ("анна-мария" → "Анна-Мария"). Остальные буквы не меняются, поэтому
+// "McDonald" остаётся как есть. Хранится имя в том виде, как его ввели.
+func Display(s string) string {
+	return cases.Title(language.Und, cases.NoLower).String(Clean(s))
+}
+
+// Fold возвращает Clean с case folding и заменой ё на е, без транслитерации.
+func Fold(s string) string {
+	return strings.ReplaceAll(cases.Fold().String(Clean(s)), "ё", "е")
+}
+
+// Key возвращает ключ для сравнения и поиска: Fold и, если включено,
+// транслитерация в латиницу. "dmitriy", " Dmitriy " и "Дмитрий" дают один ключ.
+func Key(s string) string {
+	key := Fold(s)
+	if Transliterate {
+		key = Latin(key)
+	}
+	return key
+}
+
+// Latin транслитерирует кириллицу в латиницу, сохраняя регистр первой буквы
+// каждого фрагмента. Остальные символы не меняются.
+func Latin(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		lower := []rune(strings.ToLower(string(r)))[0]
+		latin, ok := cyrillicToLatin[lower]
+		if !ok {
+			sb.WriteRune(r)
+			continue
+		}
+		if lower != r && latin != "" {
+			latin = strings.ToUpper(latin[:1]) + latin[1:]
+		}
+		sb.WriteString(latin)
+	}
+	return sb.String()
+}
+
+// QueryName возвращает имя для запроса к провайдерам обогащения.
+func QueryName(s string) string {
+	s = Clean(s)
+	if Transliterate {
+		s = Latin(s)
+	}
+	return s
+}
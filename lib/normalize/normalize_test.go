@@ -0,0 +1,108 @@
+package normalize
+
+import "testing"
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"  Ivan  ", "Ivan"},
+		{"Anna \t Maria\n", "Anna Maria"},
+		{"\u0415\u0308", "\u0401"}, // Ё из NFD в NFC
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Clean(tt.in); got != tt.want {
+			t.Errorf("Clean(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{" DMITRIY ", "dmitriy"},
+		{"Пётр", "петр"},
+		{"ПЁТР", "петр"},
+		{"Straße", "strasse"},
+		{"Анна  Мария", "анна мария"},
+	}
+	for _, tt := range tests {
+		if got := Fold(tt.in); got != tt.want {
+			t.Errorf("Fold(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLatin(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Дмитрий", "Dmitriy"},
+		{"щука", "shchuka"},
+		{"Щука", "Shchuka"},
+		{"Юлия-Жанна", "Yuliya-Zhanna"},
+		{"Подъячев", "Podyachev"},
+		{"Ivan", "Ivan"},
+		{"Ёж", "Ezh"},
+	}
+	for _, tt := range tests {
+		if got := Latin(tt.in); got != tt.want {
+			t.Errorf("Latin(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		in            string
+		transliterate bool
+		want          string
+	}{
+		{" Dmitriy ", true, "dmitriy"},
+		{"Дмитрий", true, "dmitriy"},
+		{"Дмитрий", false, "дмитрий"},
+		{"Пётр", true, "petr"},
+		{"ПЕТР", false, "петр"},
+		{"Пётр", false, "петр"},
+		{"Анна   Мария", true, "anna mariya"},
+	}
+	defer func(v bool) { Transliterate = v }(Transliterate)
+	for _, tt := range tests {
+		Transliterate = tt.transliterate
+		if got := Key(tt.in); got != tt.want {
+			t.Errorf("Key(%q) with Transliterate=%t = %q, want %q", tt.in, tt.transliterate, got, tt.want)
+		}
+	}
+}
+
+func TestQueryName(t *testing.T) {
+	defer func(v bool) { Transliterate = v }(Transliterate)
+
+	Transliterate = true
+	if got := QueryName(" Дмитрий "); got != "Dmitriy" {
+		t.Errorf("got %q, want Dmitriy", got)
+	}
+	Transliterate = false
+	if got := QueryName(" Дмитрий "); got != "Дмитрий" {
+		t.Errorf("got %q without transliteration, want Дмитрий", got)
+	}
+}
+
+func TestDisplay(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"анна-мария", "Анна-Мария"},
+		{"  ivan  petrov ", "Ivan Petrov"},
+		{"McDonald", "McDonald"},
+		{"d'Artagnan", "D'Artagnan"},
+		{"ёлкин", "Ёлкин"},
+	}
+	for _, tt := range tests {
+		if got := Display(tt.in); got != tt.want {
+			t.Errorf("Display(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -25,3 +25,11 @@ func SqlNullInt64Valid(param sql.NullInt64) *int {
 	logger.Debug("SqlNullInt64Valid: int64 is NULL")
 	return nil
 }
+
+// Value разыменовывает указатель для логирования: nil выводится как "null".
+func Value[T any](p *T) any {
+	if p == nil {
+		return "null"
+	}
+	return *p
+}
@@ -0,0 +1,252 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Struct проверяет поля структуры v по тегам validate и возвращает ошибки в
+// порядке объявления полей. Имя поля в ошибке берётся из тега json. Теги
+// разбираются один раз для каждого типа (см. Register); ошибка в тегах
+// возвращается вторым значением.
+//
+// Правила (через запятую):
+//
+//	required  — поле обязательно; в режиме partial не проверяется
+//	nonempty  — если поле передано, оно не может быть пустым
+//	min=N     — для чисел: не меньше N
+//	max=N     — для строк: не длиннее N символов, для чисел: не больше N
+//	name      — буквы любого алфавита, пробелы, дефисы и апострофы, начинается с буквы
+//	country   — код страны ISO 3166-1 alpha-2
+//	oneof=a b — одно из перечисленных значений
+//
+// Незаданные указатели и пустые строки проверяются только правилами required
+// и nonempty. В режиме partial (PUT, PATCH) пустое поле означает «не менять».
+func Struct(v any, partial bool) ([]FieldError, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	fields, err := rulesFor(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []FieldError
+	for _, f := range fields {
+		if msg := field(rv.Field(f.index), f.rules, partial); msg != "" {
+			errs = append(errs, FieldError{Field: f.name, Message: msg})
+		}
+	}
+	return errs, nil
+}
+
+// Register разбирает теги типа v заранее, чтобы ошибка в них обнаружилась
+// при старте, а не на первом запросе.
+func Register(v any) error {
+	_, err := rulesFor(reflect.TypeOf(v))
+	return err
+}
+
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+type rule struct {
+	name    string
+	arg     string
+	n       int64
+	allowed []string
+}
+
+type fieldRules struct {
+	index int
+	name  string
+	rules []rule
+}
+
+type compiled struct {
+	fields []fieldRules
+	err    error
+}
+
+// cache хранит разобранные правила по типу: reflect.Type → compiled.
+var cache sync.Map
+
+func rulesFor(t reflect.Type) ([]fieldRules, error) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return nil, errors.New("validate: nil value")
+	}
+	if c, ok := cache.Load(t); ok {
+		return c.(compiled).fields, c.(compiled).err
+	}
+
+	fields, err := compile(t)
+	cache.Store(t, compiled{fields: fields, err: err})
+	return fields, err
+}
+
+func compile(t reflect.Type) ([]fieldRules, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("validate: %s is not a struct", t)
+	}
+
+	var fields []fieldRules
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+
+		f := fieldRules{index: i, name: jsonName(t.Field(i))}
+		for _, raw := range strings.Split(tag, ",") {
+			r, err := parseRule(raw)
+			if err != nil {
+				return nil, fmt.Errorf("validate: %s.%s: %w", t.Name(), t.Field(i).Name, err)
+			}
+			f.rules = append(f.rules, r)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func parseRule(raw string) (rule, error) {
+	name, arg, hasArg := strings.Cut(strings.TrimSpace(raw), "=")
+	r := rule{name: name, arg: arg}
+
+	switch name {
+	case "required", "nonempty", "name", "country":
+		if hasArg {
+			return rule{}, fmt.Errorf("rule %s takes no argument", name)
+		}
+	case "min", "max":
+		n, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return rule{}, fmt.Errorf("rule %s: bad argument %q", name, arg)
+		}
+		r.n = n
+	case "oneof":
+		r.allowed = strings.Fields(arg)
+		if len(r.allowed) == 0 {
+			return rule{}, errors.New("rule oneof needs at least one value")
+		}
+	default:
+		return rule{}, fmt.Errorf("unknown rule %q", name)
+	}
+	return r, nil
+}
+
+// field возвращает описание первого нарушенного правила или пустую строку.
+func field(v reflect.Value, rules []rule, partial bool) string {
+	present := true
+	if v.Kind() == reflect.Pointer {
+		present = !v.IsNil()
+		v = reflect.Indirect(v)
+	}
+
+	blank := !present || (v.Kind() == reflect.String && strings.TrimSpace(v.String()) == "")
+	for _, r := range rules {
+		switch {
+		case r.name == "required" && blank && !partial:
+			return "is required"
+		case r.name == "nonempty" && present && blank:
+			return "must not be empty"
+		}
+	}
+	if blank {
+		return ""
+	}
+
+	for _, r := range rules {
+		if msg := check(v, r); msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+func check(v reflect.Value, r rule) string {
+	switch r.name {
+	case "min":
+		if v.CanInt() && v.Int() < r.n {
+			return "must be at least " + r.arg
+		}
+	case "max":
+		if v.Kind() == reflect.String && int64(utf8.RuneCountInString(strings.TrimSpace(v.String()))) > r.n {
+			return fmt.Sprintf("must be at most %s characters long", r.arg)
+		}
+		if v.CanInt() && v.Int() > r.n {
+			return "must be at most " + r.arg
+		}
+	case "name":
+		if !isName(v.String()) {
+			return "must contain only letters, spaces, hyphens and apostrophes"
+		}
+	case "country":
+		if !isCountry(v.String()) {
+			return "must be an ISO 3166-1 alpha-2 country code"
+		}
+	case "oneof":
+		for _, a := range r.allowed {
+			if v.String() == a {
+				return ""
+			}
+		}
+		return "must be one of: " + strings.Join(r.allowed, ", ")
+	}
+	return ""
+}
+
+// isName допускает буквы (вместе с диакритикой), а между частями имени —
+// пробел, дефис или апостроф: "Анна-Мария", "O'Neil", "de la Cruz".
+func isName(s string) bool {
+	s = strings.TrimSpace(s)
+	prev := ' '
+	for i, r := range s {
+		switch {
+		case unicode.IsLetter(r):
+		case unicode.Is(unicode.Mn, r) && i > 0:
+		case r == ' ' || r == '-' || r == '\'' || r == '’':
+			if i == 0 || !unicode.IsLetter(prev) && !unicode.Is(unicode.Mn, prev) && prev != ' ' {
+				return false
+			}
+		default:
+			return false
+		}
+		prev = r
+	}
+	return unicode.IsLetter(prev) || unicode.Is(unicode.Mn, prev)
+}
+
+func isCountry(s string) bool {
+	s = strings.TrimSpace(s)
+	if len(s) != 2 {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
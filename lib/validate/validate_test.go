@@ -0,0 +1,94 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+)
+
+type person struct {
+	Name    string  `json:"name" validate:"required,max=5,name"`
+	Age     *int    `json:"age,omitempty" validate:"min=0,max=150"`
+	Gender  *string `json:"gender" validate:"nonempty,oneof=male female"`
+	Country string  `validate:"country"`
+}
+
+func TestStruct(t *testing.T) {
+	age, gender := 200, ""
+	errs, err := Struct(&person{Name: "Анна-Мария", Age: &age, Gender: &gender, Country: "RUS"}, false)
+	if err != nil {
+		t.Fatalf("Struct: %v", err)
+	}
+
+	want := []string{
+		"name must be at most 5 characters long",
+		"age must be at most 150",
+		"gender must not be empty",
+		"Country must be an ISO 3166-1 alpha-2 country code",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %v, want %v", errs, want)
+	}
+	for i, e := range errs {
+		if e.Error() != want[i] {
+			t.Errorf("error %d: got %q, want %q", i, e.Error(), want[i])
+		}
+	}
+}
+
+func TestStructPartial(t *testing.T) {
+	errs, err := Struct(person{}, true)
+	if err != nil {
+		t.Fatalf("Struct: %v", err)
+	}
+	if len(errs) != 0 {
+		t.Fatalf("partial check of an empty request: got %v", errs)
+	}
+
+	if errs, _ = Struct(person{}, false); len(errs) != 1 || errs[0].Field != "name" {
+		t.Fatalf("full check of an empty request: got %v, want name is required", errs)
+	}
+}
+
+func TestBadTagsReturnError(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"unknown rule", struct {
+			A string `validate:"email"`
+		}{}, `unknown rule "email"`},
+		{"bad argument", struct {
+			A int `validate:"max=ten"`
+		}{}, `bad argument "ten"`},
+		{"empty oneof", struct {
+			A string `validate:"oneof="`
+		}{}, "needs at least one value"},
+		{"not a struct", 42, "is not a struct"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Register(tt.v)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Register: got %v, want an error containing %q", err, tt.want)
+			}
+			// ошибка запоминается вместе с типом и возвращается из Struct без паники
+			if _, err = Struct(tt.v, false); err == nil {
+				t.Fatal("Struct returned no error for bad tags")
+			}
+		})
+	}
+}
+
+func TestIsName(t *testing.T) {
+	for _, s := range []string{"Ivan", "Анна-Мария", "O'Neil", "de la Cruz", "José"} {
+		if !isName(s) {
+			t.Errorf("isName(%q) = false, want true", s)
+		}
+	}
+	for _, s := range []string{"", "-Ivan", "Ivan-", "Iv--an", "R2D2", "Ivan!"} {
+		if isName(s) {
+			t.Errorf("isName(%q) = true, want false", s)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS enrichment_cache (
+        key TEXT PRIMARY KEY,
+        value JSONB NOT NULL,
+        expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_enrichment_cache_expires_at ON enrichment_cache(expires_at);
@@ -0,0 +1 @@
+ALTER TABLE people DROP COLUMN IF EXISTS pending_enrichment;
\ No newline at end of file
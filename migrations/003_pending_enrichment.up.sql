@@ -0,0 +1 @@
+ALTER TABLE people ADD COLUMN IF NOT EXISTS pending_enrichment TEXT[];
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_enrichment_jobs_person;
+DROP INDEX IF EXISTS idx_enrichment_jobs_ready;
+DROP TABLE IF EXISTS enrichment_jobs;
+ALTER TABLE people DROP COLUMN IF EXISTS enrichment_status;
\ No newline at end of file
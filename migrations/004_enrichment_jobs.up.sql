@@ -0,0 +1,15 @@
+ALTER TABLE people ADD COLUMN IF NOT EXISTS enrichment_status TEXT NOT NULL DEFAULT 'complete';
+
+CREATE TABLE IF NOT EXISTS enrichment_jobs (
+        id BIGSERIAL PRIMARY KEY,
+        person_id INT NOT NULL REFERENCES people(id) ON DELETE CASCADE,
+        status TEXT NOT NULL DEFAULT 'queued',
+        attempts INT NOT NULL DEFAULT 0,
+        last_error TEXT,
+        run_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+        created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_enrichment_jobs_ready ON enrichment_jobs(status, run_at);
+CREATE INDEX idx_enrichment_jobs_person ON enrichment_jobs(person_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_people_enrichment;
+ALTER TABLE people DROP COLUMN IF EXISTS enrichment;
\ No newline at end of file
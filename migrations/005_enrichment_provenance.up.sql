@@ -0,0 +1,3 @@
+ALTER TABLE people ADD COLUMN IF NOT EXISTS enrichment JSONB;
+
+CREATE INDEX idx_people_enrichment ON people USING GIN (enrichment jsonb_path_ops);
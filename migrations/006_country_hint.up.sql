@@ -0,0 +1 @@
+ALTER TABLE people ADD COLUMN IF NOT EXISTS country_hint VARCHAR(2);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_people_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_people_name ON people(name, surname);
+
+ALTER TABLE people DROP COLUMN IF EXISTS surname_key;
+ALTER TABLE people DROP COLUMN IF EXISTS name_key;
@@ -0,0 +1,6 @@
+ALTER TABLE people ADD COLUMN IF NOT EXISTS name_key TEXT;
+ALTER TABLE people ADD COLUMN IF NOT EXISTS surname_key TEXT;
+
+-- Ключи существующих записей заполняет приложение (server rekey): в SQL нет
+-- замены ё → е и транслитерации из lib/normalize. Прежний индекс по name и
+-- surname остаётся до миграции 013, которая создаёт уникальный индекс по ключам.
@@ -0,0 +1 @@
+ALTER TABLE people ADD COLUMN IF NOT EXISTS locked_attributes TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_enrichment_changes_person;
+DROP TABLE IF EXISTS enrichment_changes;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS enrichment_changes (
+        id BIGSERIAL PRIMARY KEY,
+        person_id INT NOT NULL REFERENCES people(id) ON DELETE CASCADE,
+        attribute TEXT NOT NULL,
+        old_value TEXT,
+        new_value TEXT,
+        provider TEXT,
+        changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_enrichment_changes_person ON enrichment_changes(person_id);
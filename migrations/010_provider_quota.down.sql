@@ -0,0 +1 @@
+DROP TABLE IF EXISTS provider_quota;
\ No newline at end of file
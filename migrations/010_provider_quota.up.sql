@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS provider_quota (
+        provider TEXT NOT NULL,
+        day DATE NOT NULL,
+        used INT NOT NULL DEFAULT 0,
+        PRIMARY KEY (provider, day)
+);
@@ -0,0 +1,3 @@
+-- Время последней попытки обогащения, в том числе неудачной: по нему
+-- повторный запрос атрибутов без атрибуции ждёт REFRESH_RETRY_MISSING.
+ALTER TABLE people ADD COLUMN IF NOT EXISTS enriched_at TIMESTAMPTZ;
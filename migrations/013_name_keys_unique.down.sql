@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_people_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_people_name ON people(name, surname);
+
+ALTER TABLE people ALTER COLUMN surname_key DROP NOT NULL;
+ALTER TABLE people ALTER COLUMN name_key DROP NOT NULL;
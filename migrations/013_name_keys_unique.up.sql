@@ -0,0 +1,18 @@
+-- Перед этой миграцией нужно выполнить server rekey: он заполняет ключи и
+-- сообщает о людях с одинаковыми ключами. Миграция не запускается, пока такие
+-- люди есть, чтобы не упасть посреди создания индекса.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM people WHERE name_key IS NULL OR surname_key IS NULL) THEN
+        RAISE EXCEPTION 'people without name keys: run "server rekey" before migration 013';
+    END IF;
+    IF EXISTS (SELECT 1 FROM people GROUP BY name_key, surname_key HAVING count(*) > 1) THEN
+        RAISE EXCEPTION 'people with duplicate name keys: resolve the conflicts reported by "server rekey"';
+    END IF;
+END $$;
+
+ALTER TABLE people ALTER COLUMN name_key SET NOT NULL;
+ALTER TABLE people ALTER COLUMN surname_key SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_people_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_people_name_key ON people(name_key, surname_key);